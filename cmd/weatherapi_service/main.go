@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"weather-app/internal/api"
@@ -28,10 +33,54 @@ func main() {
 		appBaseURL = fmt.Sprintf("http://localhost:%s", port) // For local development
 	}
 
+	// Weather backends, tried in this order. Supported: weatherapi, openmeteo, openweathermap, fixtures
+	weatherProviders := os.Getenv("WEATHER_PROVIDERS")
+	if weatherProviders == "" {
+		weatherProviders = "weatherapi,openmeteo"
+	}
 	weatherAPIKey := os.Getenv("WEATHERAPI_COM_KEY")
-	if weatherAPIKey == "" {
-		log.Fatal("Error: WEATHERAPI_COM_KEY environment variable not set.")
+	openWeatherMapKey := os.Getenv("OPENWEATHERMAP_API_KEY")
+	weatherFixturesDir := os.Getenv("WEATHER_FIXTURES_DIR")
+	if weatherFixturesDir == "" {
+		weatherFixturesDir = "./fixtures/weather"
+	}
+	// When set, every answer from the backends is saved there as a fixture
+	weatherRecordDir := os.Getenv("WEATHER_RECORD_DIR")
+
+	weatherCacheTTL := 5 * time.Minute
+	if v := os.Getenv("WEATHER_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Error: invalid WEATHER_CACHE_TTL %q: %v", v, err)
+		}
+		weatherCacheTTL = ttl
 	}
+	cityNotFoundCacheTTL := time.Hour
+
+	// weatherapi.com call budget, 0 means unlimited
+	weatherAPICallsPerMinute, err := envInt("WEATHERAPI_CALLS_PER_MINUTE", 0)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	weatherAPICallsPerMonth, err := envInt("WEATHERAPI_CALLS_PER_MONTH", 1000000)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Confirmation links expire after CONFIRMATION_TOKEN_TTL; unconfirmed emails are purged after UNCONFIRMED_RETENTION_DAYS, 0 keeps them
+	confirmationPolicy := service.DefaultConfirmationPolicy()
+	if v := os.Getenv("CONFIRMATION_TOKEN_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			log.Fatalf("Error: invalid CONFIRMATION_TOKEN_TTL %q: must be a positive duration", v)
+		}
+		confirmationPolicy.TokenTTL = ttl
+	}
+	unconfirmedRetentionDays, err := envInt("UNCONFIRMED_RETENTION_DAYS", 7)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	confirmationPolicy.UnconfirmedRetention = time.Duration(unconfirmedRetentionDays) * 24 * time.Hour
 
 	// Database Configuration
 	dbCfg := database.DBConfig{
@@ -83,16 +132,42 @@ func main() {
 
 	// Dependencies
 	// Weather Provider
-	weatherClient := weatherprovider.NewClient(weatherAPIKey)
+	weatherAPIQuota := weatherprovider.NewQuotaLimiter("weatherapi", weatherAPICallsPerMinute, weatherAPICallsPerMonth, database.NewPGQuotaStore(db))
+	backends, err := buildWeatherBackends(weatherProviders, weatherBackendConfig{
+		weatherAPIKey:     weatherAPIKey,
+		weatherAPIQuota:   weatherAPIQuota,
+		openWeatherMapKey: openWeatherMapKey,
+		fixturesDir:       weatherFixturesDir,
+	})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	breakerFailureThreshold := 3
+	breakerCooldown := 30 * time.Second
+	failover := weatherprovider.NewFailoverProvider(breakerFailureThreshold, breakerCooldown, backends...)
+	var upstream weatherprovider.WeatherProvider = failover
+	if weatherRecordDir != "" {
+		log.Printf("Recording weather provider responses to %s", weatherRecordDir)
+		upstream = weatherprovider.NewRecordingProvider(upstream, weatherRecordDir)
+	}
 
 	// Repositories
 	subRepo := database.NewPGSubscriptionRepository(db)
+	subscriberRepo := database.NewPGSubscriberRepository(db)
+	alertRepo := database.NewPGAlertRepository(db)
+	ruleRepo := database.NewPGRuleRepository(db)
+	observationRepo := database.NewPGObservationRepository(db)
+
+	// observations are recorded below the cache, so only fresh readings are stored
+	upstream = weatherprovider.NewObservingProvider(upstream, observationRepo)
+	weatherCache := weatherprovider.NewCachingProvider(upstream, weatherCacheTTL, cityNotFoundCacheTTL)
+	weatherClient := weatherCache
 
 	// Email Service (Placeholder)
 	emailService := email.NewLogEmailService()
 
 	// Business Logic Services
-	subscriptionSvc := service.NewSubscriptionService(subRepo, emailService, weatherClient, appBaseURL)
+	subscriptionSvc := service.NewSubscriptionService(subRepo, subscriberRepo, alertRepo, ruleRepo, emailService, weatherClient, appBaseURL, confirmationPolicy)
 
 	// Subscription service schjeduler
 	schedulerService := scheduler.NewScheduler(subscriptionSvc)
@@ -101,9 +176,34 @@ func main() {
 	if err := schedulerService.SetupAndStartDefaultJobs(weatherUpdateCronSpec); err != nil {
 		log.Fatalf("Could not setup and start scheduler jobs: %v", err)
 	}
+	if err := schedulerService.AddJob("LogWeatherCacheStats", "0 * * * *", func(ctx context.Context) {
+		stats := weatherCache.Stats()
+		log.Printf("Weather cache: %d hits, %d negative hits, %d misses, %d coalesced, %d entries",
+			stats.Hits, stats.NegativeHits, stats.Misses, stats.Coalesced, stats.Entries)
+	}); err != nil {
+		log.Fatalf("Could not add cache stats job: %v", err)
+	}
+	if err := schedulerService.AddJob("LogWeatherBackendHealth", "*/5 * * * *", func(ctx context.Context) {
+		for _, h := range failover.Health() {
+			if h.LastFailureAt == nil {
+				log.Printf("Weather backend %s: %s", h.Name, h.State)
+				continue
+			}
+			log.Printf("Weather backend %s: %s, %d consecutive failures, last at %s: %s",
+				h.Name, h.State, h.ConsecutiveFailures, h.LastFailureAt.Format(time.RFC3339), h.LastError)
+		}
+	}); err != nil {
+		log.Fatalf("Could not add backend health job: %v", err)
+	}
+	if err := schedulerService.AddJob("LogWeatherQuotaUsage", "0 * * * *", func(ctx context.Context) {
+		usage := weatherAPIQuota.Usage()
+		log.Printf("Weather API quota (%s): %d/%d calls this month, %d rejected", usage.Name, usage.MonthCalls, usage.MonthLimit, usage.RejectedCalls)
+	}); err != nil {
+		log.Fatalf("Could not add quota usage job: %v", err)
+	}
 
 	// API Handlers
-	weatherHandler := api.NewWeatherHandler(weatherClient)
+	weatherHandler := api.NewWeatherHandler(weatherClient, observationRepo)
 	subscriptionHandler := api.NewSubscriptionHandler(subscriptionSvc)
 
 	// Router
@@ -118,8 +218,86 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
-	log.Printf("Starting server on port %s", port)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Could not listen on %s: %v\n", port, err)
+	go func() {
+		log.Printf("Starting server on port %s", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Could not listen on %s: %v\n", port, err)
+		}
+	}()
+
+	// Graceful shutdown: cancel running jobs, then drain in-flight requests
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	select {
+	case <-schedulerService.Stop().Done():
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for scheduler jobs to finish")
+	}
+	log.Println("Server stopped")
+}
+
+type weatherBackendConfig struct {
+	weatherAPIKey     string
+	weatherAPIQuota   *weatherprovider.QuotaLimiter // meters the weatherapi backend
+	openWeatherMapKey string
+	fixturesDir       string
+}
+
+// buildWeatherBackends turns a comma-separated list of backend names into the failover chain.
+func buildWeatherBackends(names string, cfg weatherBackendConfig) ([]weatherprovider.Backend, error) {
+	var backends []weatherprovider.Backend
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		switch name {
+		case "":
+			continue
+		case "weatherapi":
+			if cfg.weatherAPIKey == "" {
+				return nil, fmt.Errorf("WEATHERAPI_COM_KEY environment variable not set (use WEATHER_PROVIDERS=fixtures to run offline)")
+			}
+			client := weatherprovider.NewClient(cfg.weatherAPIKey, cfg.weatherAPIQuota)
+			backends = append(backends, weatherprovider.Backend{Name: name, Provider: client})
+		case "openmeteo":
+			backends = append(backends, weatherprovider.Backend{Name: name, Provider: weatherprovider.NewOpenMeteoClient()})
+		case "openweathermap":
+			if cfg.openWeatherMapKey == "" {
+				return nil, fmt.Errorf("OPENWEATHERMAP_API_KEY environment variable not set")
+			}
+			backends = append(backends, weatherprovider.Backend{Name: name, Provider: weatherprovider.NewOpenWeatherMapClient(cfg.openWeatherMapKey)})
+		case "fixtures":
+			if _, err := os.Stat(cfg.fixturesDir); err != nil {
+				return nil, fmt.Errorf("weather fixtures directory: %w", err)
+			}
+			log.Printf("Serving weather from fixtures in %s", cfg.fixturesDir)
+			backends = append(backends, weatherprovider.Backend{Name: name, Provider: weatherprovider.NewFixtureProvider(cfg.fixturesDir)})
+		default:
+			return nil, fmt.Errorf("unknown weather provider %q in WEATHER_PROVIDERS", name)
+		}
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("WEATHER_PROVIDERS must name at least one weather backend")
+	}
+	return backends, nil
+}
+
+// envInt reads a non-negative integer from the environment, falling back to def when unset.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", name, v)
 	}
+	return n, nil
 }
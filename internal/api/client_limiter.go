@@ -0,0 +1,65 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// maxLimitedClients triggers a sweep of finished windows once a clientLimiter
+// tracks more clients than this.
+const maxLimitedClients = 10000
+
+type clientWindow struct {
+	start time.Time
+	count int
+}
+
+// clientLimiter allows each client, usually an IP address, limit requests per
+// window. Counts live in memory, so a restart resets them.
+type clientLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]clientWindow
+}
+
+func newClientLimiter(limit int, window time.Duration) *clientLimiter {
+	return &clientLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		clients: make(map[string]clientWindow),
+	}
+}
+
+// Allow counts a request from client. When client has used up its window it
+// reports false and how long until the next window starts.
+func (l *clientLimiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.clients[client]
+	if !ok || !now.Before(w.start.Add(l.window)) {
+		if !ok && len(l.clients) >= maxLimitedClients {
+			l.sweepLocked(now)
+		}
+		w = clientWindow{start: now}
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	l.clients[client] = w
+	return true, 0
+}
+
+func (l *clientLimiter) sweepLocked(now time.Time) {
+	for client, w := range l.clients {
+		if !now.Before(w.start.Add(l.window)) {
+			delete(l.clients, client)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientLimiter(t *testing.T) {
+	now := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+	limiter := newClientLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		allowed, _ := limiter.Allow("203.0.113.7")
+		assert.True(t, allowed, "request %d", i)
+	}
+
+	now = now.Add(20 * time.Second)
+	allowed, retryAfter := limiter.Allow("203.0.113.7")
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	allowed, _ = limiter.Allow("198.51.100.2")
+	assert.True(t, allowed, "clients are limited separately")
+
+	// a new window starts once the old one is over
+	now = now.Add(40 * time.Second)
+	allowed, _ = limiter.Allow("203.0.113.7")
+	assert.True(t, allowed)
+}
+
+func TestClientLimiter_SweepsFinishedWindows(t *testing.T) {
+	now := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+	limiter := newClientLimiter(1, time.Minute)
+	limiter.now = func() time.Time { return now }
+	for i := 0; i < maxLimitedClients; i++ {
+		limiter.Allow(time.Duration(i).String())
+	}
+
+	now = now.Add(time.Minute)
+	limiter.Allow("203.0.113.7")
+
+	assert.Len(t, limiter.clients, 1)
+}
@@ -1,153 +1,863 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"weather-app/internal/core"
+	"weather-app/internal/i18n"
+	"weather-app/internal/platform/database"
 	"weather-app/internal/platform/weatherprovider"
 	"weather-app/internal/service"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// requestTimeout bounds upstream and database work for a single API request;
+// it stays below the server's WriteTimeout so errors can still be written.
+const requestTimeout = 8 * time.Second
+
+// weatherResponse is core.Weather converted to the requested units, plus the
+// units and description language used.
+type weatherResponse struct {
+	core.Weather
+	Units core.UnitSystem `json:"units"`
+	Lang  string          `json:"lang"`
+}
+
+type forecastResponse struct {
+	core.Forecast
+	Units core.UnitSystem `json:"units"`
+	Lang  string          `json:"lang"`
+}
+
+type historyResponse struct {
+	core.WeatherHistory
+	Units core.UnitSystem `json:"units"`
+}
+
 type WeatherHandler struct {
-	provider weatherprovider.WeatherProvider
+	provider     weatherprovider.WeatherProvider
+	observations database.ObservationRepository
 }
 
-func NewWeatherHandler(p weatherprovider.WeatherProvider) *WeatherHandler {
-	return &WeatherHandler{provider: p}
+func NewWeatherHandler(p weatherprovider.WeatherProvider, observations database.ObservationRepository) *WeatherHandler {
+	return &WeatherHandler{provider: p, observations: observations}
 }
 
 // GetWeather handles GET /api/weather
 func (h *WeatherHandler) GetWeather(w http.ResponseWriter, r *http.Request) {
-	city := r.URL.Query().Get("city")
-	if city == "" {
-		http.Error(w, `{"error": "city query parameter is required"}`, http.StatusBadRequest)
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseLocationQuery(r)
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, locationQueryErrorMessage(err))
 		return
 	}
+	units, err := core.ParseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUnits)
+		return
+	}
+	includeAirQuality := false
+	if aqiParam := r.URL.Query().Get("aqi"); aqiParam != "" {
+		includeAirQuality, err = strconv.ParseBool(aqiParam)
+		if err != nil {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidAQIParam)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
 
-	weatherData, err := h.provider.FetchWeather(city)
+	weatherData, err := h.provider.FetchWeather(ctx, query.WithLang(lang))
 	if err != nil {
 		if errors.Is(err, weatherprovider.ErrCityNotFound) {
-			http.Error(w, `{"error": "City not found"}`, http.StatusNotFound)
+			writeError(w, lang, http.StatusNotFound, i18n.MsgCityNotFound)
+		} else if errors.Is(err, weatherprovider.ErrNotSupported) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgLookupNotSupported)
+		} else if errors.Is(err, weatherprovider.ErrRateLimited) {
+			writeRateLimited(w, lang, err)
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, lang, http.StatusGatewayTimeout, i18n.MsgProviderTimeout)
+		} else if errors.Is(err, context.Canceled) {
+			log.Printf("GetWeather for %s cancelled by client", query)
 		} else if errors.Is(err, weatherprovider.ErrAPIRequest) {
-			http.Error(w, `{"error": "Failed to fetch weather data from provider"}`, http.StatusInternalServerError)
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgWeatherFetchFailed)
 		} else {
-			http.Error(w, `{"error": "An unexpected error occurred"}`, http.StatusInternalServerError)
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgUnexpectedError)
 		}
 		return
 	}
 
+	resp := weatherResponse{Weather: weatherData.ConvertTo(units), Units: units, Lang: lang}
+	if includeAirQuality {
+		// air quality is an extra; a failure here shouldn't cost the caller the weather
+		airQuality, err := h.provider.FetchAirQuality(ctx, query)
+		if err != nil {
+			log.Printf("Error fetching air quality for %s: %v", query, err)
+		} else {
+			resp.AirQuality = airQuality
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(weatherData); err != nil {
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		log.Printf("Error encoding weather data to JSON: %v", err)
 	}
 }
 
+// requestLanguage is the language for the response: the lang query parameter,
+// else the best match for Accept-Language. An unsupported lang is answered
+// with 400 and reported as not ok.
+func requestLanguage(w http.ResponseWriter, r *http.Request) (string, bool) {
+	fallback := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	tag := r.URL.Query().Get("lang")
+	if tag == "" {
+		return fallback, true
+	}
+	lang, err := i18n.Parse(tag)
+	if err != nil {
+		writeInvalidLanguage(w, fallback)
+		return "", false
+	}
+	return lang, true
+}
+
+func writeInvalidLanguage(w http.ResponseWriter, lang string) {
+	writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidLanguage, strings.Join(i18n.Supported(), ", "))
+}
+
+// writeError writes msg in lang as a JSON error body.
+func writeError(w http.ResponseWriter, lang string, code int, msg i18n.Message, args ...interface{}) {
+	body, err := json.Marshal(map[string]string{"error": i18n.T(lang, msg, args...)})
+	if err != nil {
+		log.Printf("Error encoding error message %s: %v", msg, err)
+	}
+	http.Error(w, string(body), code)
+}
+
+// writeMessage writes msg in lang as a 200 JSON message body.
+func writeMessage(w http.ResponseWriter, lang string, msg i18n.Message) {
+	writeMessageStatus(w, lang, http.StatusOK, msg)
+}
+
+// writeMessageStatus writes msg in lang as a JSON message body with code.
+func writeMessageStatus(w http.ResponseWriter, lang string, code int, msg i18n.Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"message": i18n.T(lang, msg)})
+}
+
+// writeRateLimited answers 429 with Retry-After taken from the exhausted quota.
+func writeRateLimited(w http.ResponseWriter, lang string, err error) {
+	var rateLimitErr *weatherprovider.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		setRetryAfter(w, rateLimitErr.RetryAfter)
+	}
+	writeError(w, lang, http.StatusTooManyRequests, i18n.MsgRateLimited)
+}
+
+// setRetryAfter sets Retry-After to d in whole seconds, at least one.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int(math.Ceil(d.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+}
+
+const (
+	maxHourlyHistorySpan = 31 * 24 * time.Hour
+	maxDailyHistorySpan  = 366 * 24 * time.Hour
+)
+
+// parseHistoryTime accepts RFC 3339 timestamps and plain dates (midnight UTC).
+func parseHistoryTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// GetWeatherHistory handles GET /api/weather/history
+func (h *WeatherHandler) GetWeatherHistory(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseLocationQuery(r)
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, locationQueryErrorMessage(err))
+		return
+	}
+	params := r.URL.Query()
+	interval, err := core.ParseHistoryInterval(params.Get("interval"))
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidInterval)
+		return
+	}
+	units, err := core.ParseUnits(params.Get("units"))
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUnits)
+		return
+	}
+
+	to := time.Now().UTC()
+	if toParam := params.Get("to"); toParam != "" {
+		if to, err = parseHistoryTime(toParam); err != nil {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidTo)
+			return
+		}
+	}
+	maxSpan := maxHourlyHistorySpan
+	from := to.Add(-24 * time.Hour)
+	if interval == core.HistoryByDay {
+		maxSpan = maxDailyHistorySpan
+		from = to.AddDate(0, 0, -30)
+	}
+	if fromParam := params.Get("from"); fromParam != "" {
+		if from, err = parseHistoryTime(fromParam); err != nil {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidFrom)
+			return
+		}
+	}
+	if !from.Before(to) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgFromNotBeforeTo)
+		return
+	}
+	if to.Sub(from) > maxSpan {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgHistorySpanTooLong, interval, int(maxSpan.Hours()/24))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	points, err := h.observations.Aggregate(ctx, query, from, to, interval)
+	if err != nil {
+		log.Printf("Error aggregating weather history for %s: %v", query, err)
+		writeError(w, lang, http.StatusInternalServerError, i18n.MsgHistoryFailed)
+		return
+	}
+
+	history := core.WeatherHistory{City: query.City, Location: query.String(), Interval: interval, From: from, To: to, Points: points}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	resp := historyResponse{WeatherHistory: history.ConvertTo(units), Units: units}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding weather history to JSON: %v", err)
+	}
+}
+
+// defaultForecastDays is used when GET /api/forecast is called without days.
+const defaultForecastDays = 3
+
+// GetForecast handles GET /api/forecast
+func (h *WeatherHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseLocationQuery(r)
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, locationQueryErrorMessage(err))
+		return
+	}
+
+	days := defaultForecastDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed < 1 || parsed > weatherprovider.MaxForecastDays {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidDays, weatherprovider.MaxForecastDays)
+			return
+		}
+		days = parsed
+	}
+	units, err := core.ParseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUnits)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	forecast, err := h.provider.FetchForecast(ctx, query.WithLang(lang), days)
+	if err != nil {
+		if errors.Is(err, weatherprovider.ErrCityNotFound) {
+			writeError(w, lang, http.StatusNotFound, i18n.MsgCityNotFound)
+		} else if errors.Is(err, weatherprovider.ErrNotSupported) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgLookupNotSupported)
+		} else if errors.Is(err, weatherprovider.ErrRateLimited) {
+			writeRateLimited(w, lang, err)
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, lang, http.StatusGatewayTimeout, i18n.MsgProviderTimeout)
+		} else if errors.Is(err, context.Canceled) {
+			log.Printf("GetForecast for %s cancelled by client", query)
+		} else if errors.Is(err, weatherprovider.ErrAPIRequest) {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgForecastFetchFailed)
+		} else {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgUnexpectedError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	resp := forecastResponse{Forecast: forecast.ConvertTo(units), Units: units, Lang: lang}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding forecast data to JSON: %v", err)
+	}
+}
+
+// GetAstronomy handles GET /api/astronomy
+func (h *WeatherHandler) GetAstronomy(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseLocationQuery(r)
+	if err != nil {
+		writeError(w, lang, http.StatusBadRequest, locationQueryErrorMessage(err))
+		return
+	}
+	date := time.Now().UTC().Format(core.DateLayout)
+	if dateParam := r.URL.Query().Get("date"); dateParam != "" {
+		if _, err := time.Parse(core.DateLayout, dateParam); err != nil {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidDate)
+			return
+		}
+		date = dateParam
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	astronomy, err := h.provider.FetchAstronomy(ctx, query, date)
+	if err != nil {
+		if errors.Is(err, weatherprovider.ErrCityNotFound) {
+			writeError(w, lang, http.StatusNotFound, i18n.MsgCityNotFound)
+		} else if errors.Is(err, weatherprovider.ErrInvalidDate) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidDate)
+		} else if errors.Is(err, weatherprovider.ErrNotSupported) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgLookupNotSupported)
+		} else if errors.Is(err, weatherprovider.ErrRateLimited) {
+			writeRateLimited(w, lang, err)
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, lang, http.StatusGatewayTimeout, i18n.MsgProviderTimeout)
+		} else if errors.Is(err, context.Canceled) {
+			log.Printf("GetAstronomy for %s cancelled by client", query)
+		} else if errors.Is(err, weatherprovider.ErrAPIRequest) {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgAstronomyFetchFailed)
+		} else {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgUnexpectedError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(astronomy); err != nil {
+		log.Printf("Error encoding astronomy data to JSON: %v", err)
+	}
+}
+
+const (
+	minCitySearchLength   = 2
+	maxCitySearchLength   = 100
+	defaultCitySearchSize = 5
+	maxCitySearchSize     = 10
+	// results only change when the provider's location database does
+	citySearchCacheControl = "public, max-age=3600"
+)
+
+// SearchCities handles GET /api/cities/search
+func (h *WeatherHandler) SearchCities(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(q)) < minCitySearchLength || len([]rune(q)) > maxCitySearchLength {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidSearchQuery, minCitySearchLength, maxCitySearchLength)
+		return
+	}
+
+	limit := defaultCitySearchSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > maxCitySearchSize {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidLimit, maxCitySearchSize)
+			return
+		}
+		limit = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	locations, err := h.provider.SearchLocations(ctx, q)
+	if err != nil {
+		if errors.Is(err, weatherprovider.ErrRateLimited) {
+			writeRateLimited(w, lang, err)
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, lang, http.StatusGatewayTimeout, i18n.MsgProviderTimeout)
+		} else if errors.Is(err, context.Canceled) {
+			log.Printf("SearchCities for %q cancelled by client", q)
+		} else if errors.Is(err, weatherprovider.ErrAPIRequest) {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgCitySearchFailed)
+		} else {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgUnexpectedError)
+		}
+		return
+	}
+	if len(locations) > limit {
+		locations = locations[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", citySearchCacheControl)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(locations); err != nil {
+		log.Printf("Error encoding city search results to JSON: %v", err)
+	}
+}
+
+// Each client may ask for resendRequestsPerClient confirmation emails per
+// resendClientWindow, whatever addresses it asks for.
+const (
+	resendRequestsPerClient = 5
+	resendClientWindow      = 15 * time.Minute
+)
+
 type SubscriptionHandler struct {
-	subService *service.SubscriptionService
+	subService    *service.SubscriptionService
+	resendLimiter *clientLimiter
 }
 
 func NewSubscriptionHandler(ss *service.SubscriptionService) *SubscriptionHandler {
-	return &SubscriptionHandler{subService: ss}
+	return &SubscriptionHandler{
+		subService:    ss,
+		resendLimiter: newClientLimiter(resendRequestsPerClient, resendClientWindow),
+	}
 }
 
 // Subscribe handles POST /api/subscribe
 func (h *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
 	if err := r.ParseForm(); err != nil {
 		log.Printf("Error parsing form: %v", err)
-		http.Error(w, `{"error": "Failed to parse form data"}`, http.StatusBadRequest)
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgFormParseFailed)
 		return
 	}
+	// the subscription's emails are in the language the form was sent in
+	if tag := r.FormValue("lang"); tag != "" {
+		parsed, err := i18n.Parse(tag)
+		if err != nil {
+			writeInvalidLanguage(w, lang)
+			return
+		}
+		lang = parsed
+	}
 
 	req := core.SubscriptionRequest{
-		Email:     r.FormValue("email"),
-		City:      r.FormValue("city"),
-		Frequency: r.FormValue("frequency"),
+		Email:        r.FormValue("email"),
+		City:         r.FormValue("city"),
+		Frequency:    r.FormValue("frequency"),
+		Weekday:      r.FormValue("weekday"),
+		Units:        r.FormValue("units"),
+		Language:     lang,
+		DeliveryTime: r.FormValue("delivery_time"),
+		Timezone:     r.FormValue("timezone"),
+	}
+	if threshold := r.FormValue("aqi_threshold"); threshold != "" {
+		parsed, err := strconv.Atoi(threshold)
+		if err != nil || parsed < 1 || parsed > 6 {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidAQIThreshold)
+			return
+		}
+		req.AQIThreshold = &parsed
+	}
+	if astronomy := r.FormValue("astronomy"); astronomy != "" {
+		parsed, err := strconv.ParseBool(astronomy)
+		if err != nil {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidAstronomyParam)
+			return
+		}
+		req.IncludeAstronomy = parsed
 	}
 
 	if req.Email == "" || req.City == "" || req.Frequency == "" {
-		http.Error(w, `{"error": "email, city, and frequency are required"}`, http.StatusBadRequest)
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgSubscribeFieldsMissing)
 		return
 	}
-	if req.Frequency != "hourly" && req.Frequency != "daily" {
-		http.Error(w, `{"error": "frequency must be 'hourly' or 'daily'"}`, http.StatusBadRequest)
+	if _, err := core.ParseUnits(req.Units); err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUnits)
 		return
 	}
 
-	err := h.subService.CreateSubscription(req)
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	sub, err := h.subService.CreateSubscription(ctx, req)
 	if err != nil {
 		log.Printf("Subscribe handler error: %v", err)
 		if errors.Is(err, service.ErrSubscriptionAlreadyExists) {
-			http.Error(w, `{"error": "Email already subscribed for this city"}`, http.StatusConflict)
-		} else if err.Error() == "invalid frequency" {
-			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusBadRequest)
+			writeError(w, lang, http.StatusConflict, i18n.MsgAlreadySubscribed)
+		} else if errors.Is(err, service.ErrSubscriberSettingsConflict) {
+			writeSubscriberSettingsConflict(w, lang, err)
+		} else if errors.Is(err, service.ErrConfirmationRateLimited) {
+			writeConfirmationTooSoon(w, lang, err)
+		} else if errors.Is(err, service.ErrCityNotFound) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgCityNotFound)
+		} else if errors.Is(err, service.ErrLocationUnavailable) {
+			writeError(w, lang, http.StatusServiceUnavailable, i18n.MsgCityUnverifiable)
+		} else if errors.Is(err, service.ErrInvalidAQIThreshold) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidAQIThreshold)
+		} else if errors.Is(err, core.ErrInvalidUnits) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUnits)
+		} else if errors.Is(err, i18n.ErrUnsupportedLanguage) {
+			writeInvalidLanguage(w, lang)
+		} else if errors.Is(err, core.ErrInvalidDeliveryTime) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidDeliveryTime)
+		} else if errors.Is(err, core.ErrInvalidTimezone) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidTimezone)
+		} else if errors.Is(err, core.ErrInvalidFrequency) {
+			writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidFrequency)
 		} else {
-			http.Error(w, `{"error": "Failed to create subscription"}`, http.StatusInternalServerError)
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgSubscribeFailed)
 		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Subscription successful. Confirmation email sent."})
+	if sub.IsConfirmed {
+		writeMessage(w, lang, i18n.MsgSubscriptionAdded)
+		return
+	}
+	writeMessage(w, lang, i18n.MsgSubscribed)
+}
+
+// writeSubscriberSettingsConflict answers 409 naming the units and language the
+// email already uses.
+func writeSubscriberSettingsConflict(w http.ResponseWriter, lang string, err error) {
+	var conflictErr *service.SubscriberSettingsConflictError
+	if !errors.As(err, &conflictErr) {
+		writeError(w, lang, http.StatusInternalServerError, i18n.MsgSubscribeFailed)
+		return
+	}
+	writeError(w, lang, http.StatusConflict, i18n.MsgSubscriberSettings, conflictErr.Units, conflictErr.Language)
 }
 
 func (h *SubscriptionHandler) ConfirmSubscription(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
 	token := chi.URLParam(r, "token")
 	if token == "" {
-		http.Error(w, `{"error": "Token is required"}`, http.StatusBadRequest)
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgTokenRequired)
 		return
 	}
 
-	err := h.subService.ConfirmSubscription(token)
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	err := h.subService.ConfirmSubscription(ctx, token)
 	if err != nil {
 		log.Printf("ConfirmSubscription handler error for token %s: %v", token, err)
 		if errors.Is(err, service.ErrSubscriptionNotFound) || errors.Is(err, service.ErrInvalidToken) {
-			http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusNotFound)
+			writeError(w, lang, http.StatusNotFound, i18n.MsgInvalidOrExpiredToken)
+		} else if errors.Is(err, service.ErrConfirmationExpired) {
+			writeError(w, lang, http.StatusGone, i18n.MsgConfirmationExpired)
 		} else if errors.Is(err, service.ErrAlreadyConfirmed) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Subscription already confirmed."})
+			writeMessage(w, lang, i18n.MsgAlreadyConfirmed)
 		} else {
-			http.Error(w, `{"error": "Failed to confirm subscription"}`, http.StatusInternalServerError)
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgConfirmFailed)
 		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Subscription confirmed successfully"})
+	writeMessage(w, lang, i18n.MsgConfirmed)
+}
+
+// ResendConfirmation handles POST /api/confirm/resend with an email form field.
+// It answers the same whether or not the address is subscribed, confirmed or
+// was just sent a link, so it can't be used to find out which addresses are.
+func (h *SubscriptionHandler) ResendConfirmation(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+	if allowed, retryAfter := h.resendLimiter.Allow(clientIP(r)); !allowed {
+		setRetryAfter(w, retryAfter)
+		writeError(w, lang, http.StatusTooManyRequests, i18n.MsgResendLimited)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgFormParseFailed)
+		return
+	}
+	email := r.FormValue("email")
+	if email == "" {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgEmailRequired)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	if err := h.subService.ResendConfirmation(ctx, email); err != nil {
+		log.Printf("ResendConfirmation handler error for %s: %v", email, err)
+		writeError(w, lang, http.StatusInternalServerError, i18n.MsgConfirmFailed)
+		return
+	}
+
+	writeMessageStatus(w, lang, http.StatusAccepted, i18n.MsgConfirmationResent)
+}
+
+// writeConfirmationTooSoon answers 429 with Retry-After set to when the next
+// confirmation email may go out.
+func writeConfirmationTooSoon(w http.ResponseWriter, lang string, err error) {
+	var rateLimitErr *service.ConfirmationRateLimitError
+	if errors.As(err, &rateLimitErr) {
+		setRetryAfter(w, rateLimitErr.RetryAfter)
+	}
+	writeError(w, lang, http.StatusTooManyRequests, i18n.MsgConfirmationTooSoon)
 }
 
 // Unsubscribe handles GET /api/unsubscribe/{token}
 func (h *SubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
 	token := chi.URLParam(r, "token")
 	if token == "" {
-		http.Error(w, `{"error": "Token is required"}`, http.StatusBadRequest)
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgTokenRequired)
 		return
 	}
 
-	err := h.subService.Unsubscribe(token)
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	err := h.subService.Unsubscribe(ctx, token)
 	if err != nil {
 		log.Printf("Unsubscribe handler error for token %s: %v", token, err)
 		if errors.Is(err, service.ErrSubscriptionNotFound) || errors.Is(err, service.ErrInvalidToken) {
 			if errors.Is(err, service.ErrInvalidToken) {
-				http.Error(w, `{"error": "Invalid token format"}`, http.StatusBadRequest) // 400
+				writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidTokenFormat) // 400
 			} else {
-				http.Error(w, `{"error": "Token not found"}`, http.StatusNotFound) // 404
+				writeError(w, lang, http.StatusNotFound, i18n.MsgTokenNotFound) // 404
 			}
 		} else {
-			http.Error(w, `{"error": "Failed to process unsubscription"}`, http.StatusInternalServerError)
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgUnsubscribeFailed)
 		}
 		return
 	}
 
+	writeMessage(w, lang, i18n.MsgUnsubscribed)
+}
+
+// writeManageError maps errors from reading, changing and deleting a
+// subscription by its manage token.
+func writeManageError(w http.ResponseWriter, lang string, err error) {
+	if errors.Is(err, service.ErrInvalidToken) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidTokenFormat)
+	} else if errors.Is(err, service.ErrSubscriptionNotFound) {
+		writeError(w, lang, http.StatusNotFound, i18n.MsgTokenNotFound)
+	} else if errors.Is(err, service.ErrSubscriptionAlreadyExists) {
+		writeError(w, lang, http.StatusConflict, i18n.MsgAlreadySubscribed)
+	} else if errors.Is(err, service.ErrCityNotFound) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgCityNotFound)
+	} else if errors.Is(err, service.ErrLocationUnavailable) {
+		writeError(w, lang, http.StatusServiceUnavailable, i18n.MsgCityUnverifiable)
+	} else if errors.Is(err, core.ErrInvalidUnits) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUnits)
+	} else if errors.Is(err, core.ErrInvalidDeliveryTime) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidDeliveryTime)
+	} else if errors.Is(err, core.ErrInvalidFrequency) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidFrequency)
+	} else {
+		writeError(w, lang, http.StatusInternalServerError, i18n.MsgUpdateFailed)
+	}
+}
+
+func writeSubscription(w http.ResponseWriter, sub *core.Subscription) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Unsubscribed successfully"})
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		log.Printf("Error encoding subscription to JSON: %v", err)
+	}
+}
+
+// GetSubscription handles GET /api/subscriptions/{token}
+func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	sub, err := h.subService.GetSubscription(ctx, chi.URLParam(r, "token"))
+	if err != nil {
+		log.Printf("GetSubscription handler error: %v", err)
+		writeManageError(w, lang, err)
+		return
+	}
+	writeSubscription(w, sub)
+}
+
+// UpdateSubscription handles PATCH /api/subscriptions/{token} with a JSON
+// core.SubscriptionUpdate body.
+func (h *SubscriptionHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	var req core.SubscriptionUpdate
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidUpdateBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	sub, err := h.subService.UpdateSubscription(ctx, chi.URLParam(r, "token"), req)
+	if err != nil {
+		log.Printf("UpdateSubscription handler error: %v", err)
+		writeManageError(w, lang, err)
+		return
+	}
+	writeSubscription(w, sub)
+}
+
+// DeleteSubscription handles DELETE /api/subscriptions/{token}
+func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	if err := h.subService.DeleteSubscription(ctx, chi.URLParam(r, "token")); err != nil {
+		log.Printf("DeleteSubscription handler error: %v", err)
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrSubscriptionNotFound) {
+			writeManageError(w, lang, err)
+		} else {
+			writeError(w, lang, http.StatusInternalServerError, i18n.MsgUnsubscribeFailed)
+		}
+		return
+	}
+
+	writeMessage(w, lang, i18n.MsgUnsubscribed)
+}
+
+// writeRuleError maps errors from the notification rule operations.
+func writeRuleError(w http.ResponseWriter, lang string, err error) {
+	if errors.Is(err, service.ErrInvalidToken) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidTokenFormat)
+	} else if errors.Is(err, service.ErrSubscriptionNotFound) {
+		writeError(w, lang, http.StatusNotFound, i18n.MsgTokenNotFound)
+	} else if errors.Is(err, service.ErrRuleNotFound) {
+		writeError(w, lang, http.StatusNotFound, i18n.MsgRuleNotFound)
+	} else if errors.Is(err, service.ErrTooManyRules) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgTooManyRules, service.MaxRulesPerSubscription)
+	} else if errors.Is(err, core.ErrInvalidRule) {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidRule, strings.TrimPrefix(err.Error(), core.ErrInvalidRule.Error()+": "))
+	} else {
+		writeError(w, lang, http.StatusInternalServerError, i18n.MsgRulesFailed)
+	}
+}
+
+// ListRules handles GET /api/subscriptions/{token}/rules
+func (h *SubscriptionHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	rules, err := h.subService.ListRules(ctx, chi.URLParam(r, "token"))
+	if err != nil {
+		log.Printf("ListRules handler error: %v", err)
+		writeRuleError(w, lang, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string][]core.NotificationRule{"rules": rules}); err != nil {
+		log.Printf("Error encoding notification rules to JSON: %v", err)
+	}
+}
+
+// AddRule handles POST /api/subscriptions/{token}/rules with a JSON
+// core.RuleRequest body.
+func (h *SubscriptionHandler) AddRule(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	var req core.RuleRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, lang, http.StatusBadRequest, i18n.MsgInvalidRuleBody)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	rule, err := h.subService.AddRule(ctx, chi.URLParam(r, "token"), req)
+	if err != nil {
+		log.Printf("AddRule handler error: %v", err)
+		writeRuleError(w, lang, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		log.Printf("Error encoding notification rule to JSON: %v", err)
+	}
+}
+
+// DeleteRule handles DELETE /api/subscriptions/{token}/rules/{id}
+func (h *SubscriptionHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	lang, ok := requestLanguage(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	if err := h.subService.DeleteRule(ctx, chi.URLParam(r, "token"), chi.URLParam(r, "id")); err != nil {
+		log.Printf("DeleteRule handler error: %v", err)
+		writeRuleError(w, lang, err)
+		return
+	}
+
+	writeMessage(w, lang, i18n.MsgRuleDeleted)
 }
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -8,33 +10,80 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 	"weather-app/internal/core"
+	"weather-app/internal/mocks"
 	"weather-app/internal/platform/weatherprovider"
+	"weather-app/internal/service"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-type MockWeatherProvider struct {
+type MockWeatherProvider = mocks.WeatherProvider
+
+type MockObservationRepository struct {
 	mock.Mock
 }
 
-func (m *MockWeatherProvider) FetchWeather(city string) (*core.Weather, error) {
-	args := m.Called(city)
+func (m *MockObservationRepository) Record(ctx context.Context, query core.LocationQuery, weather *core.Weather) error {
+	return m.Called(ctx, query, weather).Error(0)
+}
+
+func (m *MockObservationRepository) Aggregate(ctx context.Context, query core.LocationQuery, from, to time.Time, interval core.HistoryInterval) ([]core.HistoryPoint, error) {
+	args := m.Called(ctx, query, from, to, interval)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*core.Weather), args.Error(1)
+	return args.Get(0).([]core.HistoryPoint), args.Error(1)
 }
 
 type MockSubscriptionService struct {
 	mock.Mock
 }
 
+// testManageToken is the manage token of the subscription the subscription
+// handler tests work on.
+const testManageToken = "0b5e1c42-7d3a-4f0e-8c6a-2a9f4d1e7b30"
+
+// subscriptionMocks are the dependencies of the SubscriptionService behind a
+// test router.
+type subscriptionMocks struct {
+	subs        *mocks.SubscriptionRepository
+	subscribers *mocks.SubscriberRepository
+	rules       *mocks.RuleRepository
+	emailer     *mocks.EmailService
+	provider    *mocks.WeatherProvider
+}
+
+func (m *subscriptionMocks) assertExpectations(t *testing.T) {
+	m.subs.AssertExpectations(t)
+	m.subscribers.AssertExpectations(t)
+	m.rules.AssertExpectations(t)
+	m.emailer.AssertExpectations(t)
+	m.provider.AssertExpectations(t)
+}
+
+// newSubscriptionRouter serves the API with a real SubscriptionService over mocks.
+func newSubscriptionRouter() (http.Handler, *subscriptionMocks) {
+	m := &subscriptionMocks{
+		subs:        new(mocks.SubscriptionRepository),
+		subscribers: new(mocks.SubscriberRepository),
+		rules:       new(mocks.RuleRepository),
+		emailer:     new(mocks.EmailService),
+		provider:    new(mocks.WeatherProvider),
+	}
+	ss := service.NewSubscriptionService(m.subs, m.subscribers, new(mocks.AlertRepository), m.rules,
+		m.emailer, m.provider, "http://localhost:8080", service.DefaultConfirmationPolicy())
+	return NewRouter(NewWeatherHandler(m.provider, new(MockObservationRepository)), NewSubscriptionHandler(ss)), m
+}
+
 func TestWeatherHandler_GetWeather(t *testing.T) {
 	tests := []struct {
 		name                string
 		cityQueryParam      string
+		unitsParam          string
+		skipProvider        bool
 		mockProviderWeather *core.Weather
 		mockProviderError   error
 		expectedStatusCode  int
@@ -44,20 +93,79 @@ func TestWeatherHandler_GetWeather(t *testing.T) {
 			name:           "success",
 			cityQueryParam: "London",
 			mockProviderWeather: &core.Weather{
-				Temperature: 15.5,
-				Humidity:    60.0,
-				Description: "Cloudy",
+				Temperature:   15.5,
+				Humidity:      60.0,
+				Description:   "Cloudy",
+				Condition:     core.ConditionCloudy,
+				IsDay:         true,
+				Icon:          "cloudy",
+				FeelsLike:     14.2,
+				WindSpeed:     18.4,
+				WindGust:      27.0,
+				WindDegree:    250,
+				WindDirection: "WSW",
+				Pressure:      1012,
+				Precipitation: 0.1,
+				CloudCover:    75,
+				UVIndex:       3,
+				Visibility:    10,
+				ObservedAt:    time.Date(2025, 5, 20, 8, 45, 0, 0, time.UTC),
+				Location: core.Location{
+					Name:     "London",
+					Region:   "City of London, Greater London",
+					Country:  "United Kingdom",
+					Lat:      51.52,
+					Lon:      -0.11,
+					Timezone: "Europe/London",
+				},
 			},
 			mockProviderError:  nil,
 			expectedStatusCode: http.StatusOK,
-			expectedBody:       `{"temperature":15.5,"humidity":60,"description":"Cloudy"}`,
+			expectedBody: `{"temperature":15.5,"humidity":60,"description":"Cloudy","condition":"cloudy","is_day":true,"icon":"cloudy","feels_like":14.2,
+				"wind_speed":18.4,"wind_gust":27,"wind_degree":250,"wind_direction":"WSW","pressure":1012,
+				"precipitation":0.1,"cloud_cover":75,"uv_index":3,"visibility":10,"observed_at":"2025-05-20T08:45:00Z",
+				"location":{"name":"London","region":"City of London, Greater London","country":"United Kingdom",
+				"lat":51.52,"lon":-0.11,"timezone":"Europe/London"},
+				"lang":"en","units":{"temperature":"C","speed":"kph","pressure":"hPa","distance":"km","precipitation":"mm"}}`,
+		},
+		{
+			name:           "imperial units",
+			cityQueryParam: "New York",
+			unitsParam:     "imperial",
+			mockProviderWeather: &core.Weather{
+				Temperature: 20,
+				Humidity:    40,
+				Description: "Sunny",
+				Condition:   core.ConditionClear,
+				IsDay:       true,
+				Icon:        "clear-day",
+				WindSpeed:   16.09344,
+				Pressure:    1000,
+				Visibility:  16.09344,
+				ObservedAt:  time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC),
+				Location:    core.Location{Name: "New York", Lat: 40.71, Lon: -74.01},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"temperature":68,"humidity":40,"description":"Sunny","condition":"clear","is_day":true,"icon":"clear-day","feels_like":32,
+				"wind_speed":10,"wind_gust":0,"wind_degree":0,"wind_direction":"","pressure":29.53,
+				"precipitation":0,"cloud_cover":0,"uv_index":0,"visibility":10,"observed_at":"2025-05-20T12:00:00Z",
+				"location":{"name":"New York","lat":40.71,"lon":-74.01},
+				"lang":"en","units":{"temperature":"F","speed":"mph","pressure":"inHg","distance":"mi","precipitation":"in"}}`,
+		},
+		{
+			name:               "invalid units",
+			cityQueryParam:     "London",
+			unitsParam:         "kelvin",
+			skipProvider:       true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "units must be 'metric', 'imperial' or a list like 'temperature=F,speed=ms'"}`,
 		},
 		{
 			name:               "missing city query parameter",
 			cityQueryParam:     "",
 			mockProviderError:  nil,
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       `{"error": "city query parameter is required"}`,
+			expectedBody:       `{"error": "one of city, lat and lon, postcode, airport or ip query parameters is required"}`,
 		},
 		{
 			name:                "city not found error from provider",
@@ -75,6 +183,14 @@ func TestWeatherHandler_GetWeather(t *testing.T) {
 			expectedStatusCode:  http.StatusInternalServerError,
 			expectedBody:        `{"error": "Failed to fetch weather data from provider"}`,
 		},
+		{
+			name:                "provider timed out",
+			cityQueryParam:      "SlowCity",
+			mockProviderWeather: nil,
+			mockProviderError:   fmt.Errorf("%w: %w", weatherprovider.ErrAPIRequest, context.DeadlineExceeded),
+			expectedStatusCode:  http.StatusGatewayTimeout,
+			expectedBody:        `{"error": "Weather provider timed out"}`,
+		},
 		{
 			name:                "generic error from provider",
 			cityQueryParam:      "ValidCityButGenericError",
@@ -88,17 +204,20 @@ func TestWeatherHandler_GetWeather(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockProvider := new(MockWeatherProvider)
-			weatherHandler := NewWeatherHandler(mockProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
 
-			reqPath := "/weather"
+			query := url.Values{}
 			if tc.cityQueryParam != "" {
-				reqPath = fmt.Sprintf("/weather?city=%s", url.QueryEscape(tc.cityQueryParam))
+				query.Set("city", tc.cityQueryParam)
 			}
-			req := httptest.NewRequest(http.MethodGet, reqPath, nil)
+			if tc.unitsParam != "" {
+				query.Set("units", tc.unitsParam)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/weather?"+query.Encode(), nil)
 			rr := httptest.NewRecorder()
 
-			if tc.cityQueryParam != "" {
-				mockProvider.On("FetchWeather", tc.cityQueryParam).Return(tc.mockProviderWeather, tc.mockProviderError).Once()
+			if tc.cityQueryParam != "" && !tc.skipProvider {
+				mockProvider.On("FetchWeather", mock.Anything, core.CityQuery(tc.cityQueryParam)).Return(tc.mockProviderWeather, tc.mockProviderError).Once()
 			}
 
 			http.HandlerFunc(weatherHandler.GetWeather).ServeHTTP(rr, req)
@@ -112,3 +231,1221 @@ func TestWeatherHandler_GetWeather(t *testing.T) {
 		})
 	}
 }
+
+func TestWeatherHandler_GetForecast(t *testing.T) {
+	tests := []struct {
+		name                 string
+		query                string
+		expectedCity         string
+		expectedDays         int
+		mockProviderForecast *core.Forecast
+		mockProviderError    error
+		expectedStatusCode   int
+		expectedBody         string
+	}{
+		{
+			name:         "success with default days",
+			query:        "city=London",
+			expectedCity: "London",
+			expectedDays: defaultForecastDays,
+			mockProviderForecast: &core.Forecast{
+				City: "London",
+				Days: []core.ForecastDay{{Date: "2025-05-20", MaxTemperature: 18, MinTemperature: 9, ChanceOfRain: 70, Description: "Patchy rain", Condition: core.ConditionRain, Icon: "rain"}},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"city":"London","days":[{"date":"2025-05-20","max_temperature":18,"min_temperature":9,
+				"avg_humidity":0,"total_precip":0,"max_wind_speed":0,"chance_of_rain":70,"chance_of_snow":0,"description":"Patchy rain","condition":"rain","icon":"rain"}],
+				"lang":"en","units":{"temperature":"C","speed":"kph","pressure":"hPa","distance":"km","precipitation":"mm"}}`,
+		},
+		{
+			name:                 "explicit days",
+			query:                "city=Kyiv&days=1",
+			expectedCity:         "Kyiv",
+			expectedDays:         1,
+			mockProviderForecast: &core.Forecast{City: "Kyiv", Days: []core.ForecastDay{}},
+			expectedStatusCode:   http.StatusOK,
+			expectedBody:         `{"city":"Kyiv","days":[],"lang":"en","units":{"temperature":"C","speed":"kph","pressure":"hPa","distance":"km","precipitation":"mm"}}`,
+		},
+		{
+			name:               "missing city query parameter",
+			query:              "days=2",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "one of city, lat and lon, postcode, airport or ip query parameters is required"}`,
+		},
+		{
+			name:               "days out of range",
+			query:              "city=London&days=15",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "days must be an integer between 1 and 14"}`,
+		},
+		{
+			name:               "days not a number",
+			query:              "city=London&days=abc",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "days must be an integer between 1 and 14"}`,
+		},
+		{
+			name:               "city not found error from provider",
+			query:              "city=UnknownCity",
+			expectedCity:       "UnknownCity",
+			expectedDays:       defaultForecastDays,
+			mockProviderError:  weatherprovider.ErrCityNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "City not found"}`,
+		},
+		{
+			name:               "api request error from provider",
+			query:              "city=London",
+			expectedCity:       "London",
+			expectedDays:       defaultForecastDays,
+			mockProviderError:  weatherprovider.ErrAPIRequest,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"error": "Failed to fetch forecast data from provider"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+
+			req := httptest.NewRequest(http.MethodGet, "/forecast?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			if tc.expectedCity != "" {
+				mockProvider.On("FetchForecast", mock.Anything, core.CityQuery(tc.expectedCity), tc.expectedDays).Return(tc.mockProviderForecast, tc.mockProviderError).Once()
+			}
+
+			http.HandlerFunc(weatherHandler.GetForecast).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+
+			responseBody := strings.TrimSpace(rr.Body.String())
+			assert.JSONEq(t, tc.expectedBody, responseBody, "response body mismatch")
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWeatherHandler_GetAstronomy(t *testing.T) {
+	sunrise := time.Date(2025, 6, 21, 1, 47, 0, 0, time.UTC)
+	sunset := time.Date(2025, 6, 21, 18, 13, 0, 0, time.UTC)
+	tests := []struct {
+		name               string
+		query              string
+		expectedQuery      *core.LocationQuery
+		expectedDate       interface{}
+		mockAstronomy      *core.Astronomy
+		mockProviderError  error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:          "success",
+			query:         "city=Kyiv&date=2025-06-21",
+			expectedQuery: &core.LocationQuery{Kind: core.QueryByCity, City: "Kyiv"},
+			expectedDate:  "2025-06-21",
+			mockAstronomy: &core.Astronomy{
+				Date: "2025-06-21", Location: core.Location{Name: "Kyiv"}, Sunrise: &sunrise, Sunset: &sunset,
+				DayLengthMinutes: 986, MoonPhase: core.WaningCrescent, MoonIllumination: 18,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"date":"2025-06-21","location":{"name":"Kyiv","lat":0,"lon":0},
+				"sunrise":"2025-06-21T01:47:00Z","sunset":"2025-06-21T18:13:00Z","day_length_minutes":986,
+				"moon_phase":"waning_crescent","moon_illumination":18}`,
+		},
+		{
+			name:          "polar night computed for today",
+			query:         "lat=69.65&lon=18.96",
+			expectedQuery: &core.LocationQuery{Kind: core.QueryByCoordinates, Lat: 69.65, Lon: 18.96},
+			expectedDate:  mock.Anything,
+			mockAstronomy: &core.Astronomy{
+				Date: "2025-12-21", Location: core.Location{Name: "69.6500,18.9600", Lat: 69.65, Lon: 18.96},
+				MoonPhase: core.WaxingCrescent, MoonIllumination: 2, Computed: true,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"date":"2025-12-21","location":{"name":"69.6500,18.9600","lat":69.65,"lon":18.96},
+				"sunrise":null,"sunset":null,"day_length_minutes":0,"moon_phase":"waxing_crescent","moon_illumination":2,
+				"computed":true}`,
+		},
+		{
+			name:               "invalid date",
+			query:              "city=Kyiv&date=21.06.2025",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "date must be a YYYY-MM-DD date"}`,
+		},
+		{
+			name:               "missing location",
+			query:              "date=2025-06-21",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "one of city, lat and lon, postcode, airport or ip query parameters is required"}`,
+		},
+		{
+			name:               "city not found error from provider",
+			query:              "city=Atlantis&date=2025-06-21",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByCity, City: "Atlantis"},
+			expectedDate:       "2025-06-21",
+			mockProviderError:  weatherprovider.ErrCityNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "City not found"}`,
+		},
+		{
+			name:               "date out of the provider's range",
+			query:              "city=Kyiv&date=1600-01-01",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByCity, City: "Kyiv"},
+			expectedDate:       "1600-01-01",
+			mockProviderError:  fmt.Errorf("%w: 1600-01-01", weatherprovider.ErrInvalidDate),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "date must be a YYYY-MM-DD date"}`,
+		},
+		{
+			name:               "api request error from provider",
+			query:              "city=Kyiv&date=2025-06-21",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByCity, City: "Kyiv"},
+			expectedDate:       "2025-06-21",
+			mockProviderError:  weatherprovider.ErrAPIRequest,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"error": "Failed to fetch astronomy data from provider"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+
+			req := httptest.NewRequest(http.MethodGet, "/astronomy?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			if tc.expectedQuery != nil {
+				mockProvider.On("FetchAstronomy", mock.Anything, *tc.expectedQuery, tc.expectedDate).Return(tc.mockAstronomy, tc.mockProviderError).Once()
+			}
+
+			http.HandlerFunc(weatherHandler.GetAstronomy).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			assert.JSONEq(t, tc.expectedBody, strings.TrimSpace(rr.Body.String()), "response body mismatch")
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWeatherHandler_SearchCities(t *testing.T) {
+	kyiv := core.Location{ID: "weatherapi:2801268", Name: "Kyiv", Region: "Kyyivs'ka Oblast'", Country: "Ukraine", Lat: 50.43, Lon: 30.52}
+	kyivJSON := `{"id":"weatherapi:2801268","name":"Kyiv","region":"Kyyivs'ka Oblast'","country":"Ukraine","lat":50.43,"lon":30.52}`
+	many := make([]core.Location, 8)
+	for i := range many {
+		many[i] = kyiv
+	}
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedSearch     string
+		mockLocations      []core.Location
+		mockError          error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "success",
+			query:              "q=kiev",
+			expectedSearch:     "kiev",
+			mockLocations:      []core.Location{kyiv},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "[" + kyivJSON + "]",
+		},
+		{
+			name:               "results capped at default limit",
+			query:              "q=ky",
+			expectedSearch:     "ky",
+			mockLocations:      many,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "[" + strings.Repeat(kyivJSON+",", defaultCitySearchSize-1) + kyivJSON + "]",
+		},
+		{
+			name:               "explicit limit",
+			query:              "q=ky&limit=1",
+			expectedSearch:     "ky",
+			mockLocations:      many,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "[" + kyivJSON + "]",
+		},
+		{
+			name:               "no matches",
+			query:              "q=zzzz",
+			expectedSearch:     "zzzz",
+			mockLocations:      []core.Location{},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `[]`,
+		},
+		{
+			name:               "query too short",
+			query:              "q=k",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "q must be between 2 and 100 characters"}`,
+		},
+		{
+			name:               "limit out of range",
+			query:              "q=kyiv&limit=50",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "limit must be an integer between 1 and 10"}`,
+		},
+		{
+			name:               "provider error",
+			query:              "q=kyiv",
+			expectedSearch:     "kyiv",
+			mockError:          weatherprovider.ErrAPIRequest,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"error": "Failed to search cities with provider"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+
+			req := httptest.NewRequest(http.MethodGet, "/cities/search?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			if tc.expectedSearch != "" {
+				var locations interface{}
+				if tc.mockLocations != nil {
+					locations = tc.mockLocations
+				}
+				mockProvider.On("SearchLocations", mock.Anything, tc.expectedSearch).Return(locations, tc.mockError).Once()
+			}
+
+			http.HandlerFunc(weatherHandler.SearchCities).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			assert.JSONEq(t, tc.expectedBody, strings.TrimSpace(rr.Body.String()), "response body mismatch")
+			if tc.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, citySearchCacheControl, rr.Header().Get("Cache-Control"))
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWeatherHandler_GetWeather_LocationQueries(t *testing.T) {
+	weather := &core.Weather{Temperature: 10, Humidity: 50, Description: "Clear"}
+
+	tests := []struct {
+		name               string
+		query              string
+		remoteAddr         string
+		expectedQuery      *core.LocationQuery
+		mockError          error
+		expectedStatusCode int
+		expectedError      string
+	}{
+		{
+			name:               "coordinates",
+			query:              "lat=50.45&lon=30.52",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByCoordinates, Lat: 50.45, Lon: 30.52},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "postcode",
+			query:              "postcode=sw1a+1aa",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByPostcode, Postcode: "SW1A 1AA"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "airport",
+			query:              "airport=kbp",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByAirport, Airport: "KBP"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "auto ip uses the client address",
+			query:              "ip=auto:ip",
+			remoteAddr:         "203.0.113.7:51234",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByIP, IP: "203.0.113.7"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "explicit ip",
+			query:              "ip=198.51.100.1",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByIP, IP: "198.51.100.1"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "lookup not supported by provider",
+			query:              "airport=lhr",
+			expectedQuery:      &core.LocationQuery{Kind: core.QueryByAirport, Airport: "LHR"},
+			mockError:          weatherprovider.ErrNotSupported,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "This kind of location lookup is not supported by the weather provider",
+		},
+		{
+			name:               "conflicting parameters",
+			query:              "city=Kyiv&lat=50.45&lon=30.52",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "only one of city, lat and lon, postcode, airport or ip may be given",
+		},
+		{
+			name:               "lat without lon",
+			query:              "lat=50.45",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "lat and lon must be given together",
+		},
+		{
+			name:               "coordinates out of range",
+			query:              "lat=95&lon=30",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "lat must be within [-90, 90] and lon within [-180, 180]",
+		},
+		{
+			name:               "coordinates not numeric",
+			query:              "lat=north&lon=30",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "lat and lon must be numbers",
+		},
+		{
+			name:               "invalid airport code",
+			query:              "airport=KBPX",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "airport must be a 3-letter IATA code",
+		},
+		{
+			name:               "invalid ip",
+			query:              "ip=localhost",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "ip must be an IPv4 or IPv6 address",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+
+			req := httptest.NewRequest(http.MethodGet, "/weather?"+tc.query, nil)
+			if tc.remoteAddr != "" {
+				req.RemoteAddr = tc.remoteAddr
+			}
+			rr := httptest.NewRecorder()
+
+			if tc.expectedQuery != nil {
+				var result interface{}
+				if tc.mockError == nil {
+					result = weather
+				}
+				mockProvider.On("FetchWeather", mock.Anything, *tc.expectedQuery).Return(result, tc.mockError).Once()
+			}
+
+			http.HandlerFunc(weatherHandler.GetWeather).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			if tc.expectedError != "" {
+				assert.JSONEq(t, fmt.Sprintf(`{"error": %q}`, tc.expectedError), strings.TrimSpace(rr.Body.String()))
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWeatherHandler_GetWeather_AirQuality(t *testing.T) {
+	weather := &core.Weather{Temperature: 10, Humidity: 50, Description: "Clear"}
+	airQuality := &core.AirQuality{PM25: 12.3, PM10: 20.1, O3: 50, NO2: 8.5, CO: 230, SO2: 1.2, USEPAIndex: 1, DEFRAIndex: 2}
+
+	tests := []struct {
+		name               string
+		aqiParam           string
+		expectAirQuality   bool
+		mockAirQuality     *core.AirQuality
+		mockAirQualityErr  error
+		expectedStatusCode int
+		expectedAirQuality string
+	}{
+		{
+			name:               "included when requested",
+			aqiParam:           "true",
+			expectAirQuality:   true,
+			mockAirQuality:     airQuality,
+			expectedStatusCode: http.StatusOK,
+			expectedAirQuality: `{"pm2_5":12.3,"pm10":20.1,"o3":50,"no2":8.5,"co":230,"so2":1.2,"us_epa_index":1,"defra_index":2}`,
+		},
+		{
+			name:               "not fetched by default",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "provider failure still returns weather",
+			aqiParam:           "1",
+			expectAirQuality:   true,
+			mockAirQualityErr:  weatherprovider.ErrAPIRequest,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid aqi parameter",
+			aqiParam:           "maybe",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+
+			query := url.Values{"city": {"London"}}
+			if tc.aqiParam != "" {
+				query.Set("aqi", tc.aqiParam)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/weather?"+query.Encode(), nil)
+			rr := httptest.NewRecorder()
+
+			if tc.expectedStatusCode == http.StatusOK {
+				mockProvider.On("FetchWeather", mock.Anything, core.CityQuery("London")).Return(weather, nil).Once()
+			}
+			if tc.expectAirQuality {
+				var result interface{}
+				if tc.mockAirQuality != nil {
+					result = tc.mockAirQuality
+				}
+				mockProvider.On("FetchAirQuality", mock.Anything, core.CityQuery("London")).Return(result, tc.mockAirQualityErr).Once()
+			}
+
+			http.HandlerFunc(weatherHandler.GetWeather).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			if tc.expectedStatusCode == http.StatusOK {
+				var body map[string]json.RawMessage
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				if tc.expectedAirQuality != "" {
+					assert.JSONEq(t, tc.expectedAirQuality, string(body["air_quality"]))
+				} else {
+					assert.NotContains(t, body, "air_quality")
+				}
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWeatherHandler_GetWeatherHistory(t *testing.T) {
+	from := time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 5, 22, 0, 0, 0, 0, time.UTC)
+	points := []core.HistoryPoint{{
+		Time:         from,
+		Observations: 4,
+		Temperature:  core.Stats{Min: 10, Max: 20, Avg: 15},
+		Humidity:     core.Stats{Min: 40, Max: 60, Avg: 50},
+	}}
+
+	tests := []struct {
+		name               string
+		query              string
+		expectAggregate    bool
+		expectedFrom       time.Time
+		expectedTo         time.Time
+		expectedInterval   core.HistoryInterval
+		mockError          error
+		expectedStatusCode int
+		expectedError      string
+	}{
+		{
+			name:               "daily buckets",
+			query:              "city=Kyiv&from=2025-05-20&to=2025-05-22&interval=day&units=imperial",
+			expectAggregate:    true,
+			expectedFrom:       from,
+			expectedTo:         to,
+			expectedInterval:   core.HistoryByDay,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "rfc3339 timestamps default to hourly",
+			query:              "city=Kyiv&from=2025-05-20T00:00:00Z&to=2025-05-22T00:00:00%2B00:00",
+			expectAggregate:    true,
+			expectedFrom:       from,
+			expectedTo:         to,
+			expectedInterval:   core.HistoryByHour,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing location",
+			query:              "from=2025-05-20",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "one of city, lat and lon, postcode, airport or ip query parameters is required",
+		},
+		{
+			name:               "invalid interval",
+			query:              "city=Kyiv&interval=week",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "interval must be 'hour' or 'day'",
+		},
+		{
+			name:               "invalid from",
+			query:              "city=Kyiv&from=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "from must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		},
+		{
+			name:               "from after to",
+			query:              "city=Kyiv&from=2025-05-22&to=2025-05-20",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "from must be before to",
+		},
+		{
+			name:               "hourly range too long",
+			query:              "city=Kyiv&from=2025-01-01&to=2025-03-01",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "hour history can cover at most 31 days",
+		},
+		{
+			name:               "repository error",
+			query:              "city=Kyiv&from=2025-05-20&to=2025-05-22",
+			expectAggregate:    true,
+			expectedFrom:       from,
+			expectedTo:         to,
+			expectedInterval:   core.HistoryByHour,
+			mockError:          errors.New("db down"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedError:      "Failed to load weather history",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			observations := new(MockObservationRepository)
+			weatherHandler := NewWeatherHandler(mockProvider, observations)
+
+			if tc.expectAggregate {
+				var result interface{}
+				if tc.mockError == nil {
+					result = points
+				}
+				observations.On("Aggregate", mock.Anything, core.CityQuery("Kyiv"), tc.expectedFrom, tc.expectedTo, tc.expectedInterval).
+					Return(result, tc.mockError).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/weather/history?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(weatherHandler.GetWeatherHistory).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			if tc.expectedError != "" {
+				assert.JSONEq(t, fmt.Sprintf(`{"error": %q}`, tc.expectedError), strings.TrimSpace(rr.Body.String()))
+			}
+
+			observations.AssertExpectations(t)
+		})
+	}
+
+	t.Run("looks history up by the query without the provider", func(t *testing.T) {
+		tests := []struct {
+			name             string
+			query            string
+			expectedQuery    core.LocationQuery
+			expectedLocation string
+		}{
+			{name: "city", query: "city=kiev", expectedQuery: core.CityQuery("kiev"), expectedLocation: "city:kiev"},
+			{name: "coordinates", query: "lat=50.45&lon=30.5233", expectedQuery: core.CoordinatesQuery(50.45, 30.5233), expectedLocation: "coordinates:50.4500,30.5233"},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				// no provider expectations: any call to it fails the test
+				mockProvider := new(MockWeatherProvider)
+				observations := new(MockObservationRepository)
+				weatherHandler := NewWeatherHandler(mockProvider, observations)
+				observations.On("Aggregate", mock.Anything, tc.expectedQuery, from, to, core.HistoryByDay).Return(points, nil).Once()
+
+				req := httptest.NewRequest(http.MethodGet, "/weather/history?"+tc.query+"&from=2025-05-20&to=2025-05-22&interval=day", nil)
+				rr := httptest.NewRecorder()
+				http.HandlerFunc(weatherHandler.GetWeatherHistory).ServeHTTP(rr, req)
+
+				assert.Equal(t, http.StatusOK, rr.Code)
+				var resp struct {
+					Location string `json:"location"`
+				}
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.Equal(t, tc.expectedLocation, resp.Location)
+				mockProvider.AssertExpectations(t)
+				observations.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("converts units", func(t *testing.T) {
+		mockProvider := new(MockWeatherProvider)
+		observations := new(MockObservationRepository)
+		weatherHandler := NewWeatherHandler(mockProvider, observations)
+		observations.On("Aggregate", mock.Anything, core.CityQuery("Kyiv"), from, to, core.HistoryByDay).Return(points, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/weather/history?city=Kyiv&from=2025-05-20&to=2025-05-22&interval=day&units=imperial", nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(weatherHandler.GetWeatherHistory).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp struct {
+			Interval string              `json:"interval"`
+			Points   []core.HistoryPoint `json:"points"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "day", resp.Interval)
+		if assert.Len(t, resp.Points, 1) {
+			assert.Equal(t, core.Stats{Min: 50, Max: 68, Avg: 59}, resp.Points[0].Temperature)
+			assert.Equal(t, 4, resp.Points[0].Observations)
+		}
+	})
+}
+
+func TestWeatherHandler_GetWeather_RateLimited(t *testing.T) {
+	mockProvider := new(MockWeatherProvider)
+	weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+
+	rateLimitErr := &weatherprovider.RateLimitError{Reason: "weatherapi per-minute quota exhausted", RetryAfter: 41500 * time.Millisecond}
+	mockProvider.On("FetchWeather", mock.Anything, core.CityQuery("London")).
+		Return(nil, fmt.Errorf("%w: %w: all weather backends failed", weatherprovider.ErrAPIRequest, rateLimitErr)).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/weather?city=London", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(weatherHandler.GetWeather).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "42", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error": "Weather API quota exhausted, please try again later"}`, strings.TrimSpace(rr.Body.String()))
+	mockProvider.AssertExpectations(t)
+}
+
+func TestWeatherHandler_GetWeather_Language(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		expectedQuery  *core.LocationQuery
+		mockError      error
+		expectedStatus int
+		expectedLang   string
+		expectedError  string
+	}{
+		{
+			name:           "lang parameter is passed to the provider",
+			url:            "/weather?city=Kyiv&lang=uk",
+			expectedQuery:  &core.LocationQuery{Kind: core.QueryByCity, City: "Kyiv", Lang: "uk"},
+			expectedStatus: http.StatusOK,
+			expectedLang:   "uk",
+		},
+		{
+			name:           "Accept-Language is used without lang",
+			url:            "/weather?city=Kyiv",
+			acceptLanguage: "uk-UA,uk;q=0.9,en;q=0.8",
+			expectedQuery:  &core.LocationQuery{Kind: core.QueryByCity, City: "Kyiv", Lang: "uk"},
+			expectedStatus: http.StatusOK,
+			expectedLang:   "uk",
+		},
+		{
+			name:           "lang parameter wins over Accept-Language",
+			url:            "/weather?city=Kyiv&lang=en",
+			acceptLanguage: "uk",
+			expectedQuery:  &core.LocationQuery{Kind: core.QueryByCity, City: "Kyiv"},
+			expectedStatus: http.StatusOK,
+			expectedLang:   "en",
+		},
+		{
+			name:           "errors are localized",
+			url:            "/weather?city=Atlantis&lang=uk",
+			expectedQuery:  &core.LocationQuery{Kind: core.QueryByCity, City: "Atlantis", Lang: "uk"},
+			mockError:      weatherprovider.ErrCityNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Місто не знайдено",
+		},
+		{
+			name:           "validation errors are localized",
+			url:            "/weather?lang=uk",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "потрібен один із параметрів запиту city, lat і lon, postcode, airport або ip",
+		},
+		{
+			name:           "unsupported lang",
+			url:            "/weather?city=Kyiv&lang=fr",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "lang must be one of en, uk",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProvider := new(MockWeatherProvider)
+			weatherHandler := NewWeatherHandler(mockProvider, new(MockObservationRepository))
+			if tc.expectedQuery != nil {
+				var result *core.Weather
+				if tc.mockError == nil {
+					result = &core.Weather{Temperature: 18, Description: "Ясно", Location: core.Location{Name: "Kyiv"}}
+				}
+				mockProvider.On("FetchWeather", mock.Anything, *tc.expectedQuery).Return(result, tc.mockError).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(weatherHandler.GetWeather).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedError != "" {
+				assert.JSONEq(t, fmt.Sprintf(`{"error": %q}`, tc.expectedError), strings.TrimSpace(rr.Body.String()))
+			} else {
+				var resp struct {
+					Lang        string `json:"lang"`
+					Description string `json:"description"`
+				}
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.Equal(t, tc.expectedLang, resp.Lang)
+				assert.Equal(t, "Ясно", resp.Description)
+			}
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSubscriptionHandler_AddRule(t *testing.T) {
+	sub := &core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv", Units: "metric", Language: "en"}
+
+	tests := []struct {
+		name               string
+		body               string
+		setupMocks         func(m *subscriptionMocks)
+		expectedStatusCode int
+		expectedError      string
+	}{
+		{
+			name: "success",
+			body: `{"name":"Frost","period":"tomorrow","expression":{"field":"temperature","op":"lt","value":0}}`,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+				m.rules.On("ListBySubscription", mock.Anything, sub.ID).Return([]core.NotificationRule{}, nil).Once()
+				m.rules.On("Create", mock.Anything, mock.MatchedBy(func(r *core.NotificationRule) bool {
+					return r.SubscriptionID == sub.ID && r.Name == "Frost" && r.Units == "metric" && r.CooldownHours == core.DefaultRuleCooldownHours
+				})).Return(nil).Once()
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "invalid expression",
+			body:               `{"period":"now","expression":{"field":"temperature","op":"is","value":0}}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "Invalid notification rule: temperature needs one of lt, lte, gt or gte",
+		},
+		{
+			name:               "invalid period",
+			body:               `{"period":"next week","expression":{"field":"temperature","op":"lt","value":0}}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "Invalid notification rule: period must be now, today or tomorrow",
+		},
+		{
+			name:               "cooldown out of range",
+			body:               `{"cooldown_hours":0,"expression":{"field":"condition","op":"is","value":"rain"}}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      "Invalid notification rule: cooldown_hours must be between 1 and 168",
+		},
+		{
+			name: "unknown subscription",
+			body: `{"expression":{"field":"condition","op":"is","value":"rain"}}`,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(nil, nil).Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, m := newSubscriptionRouter()
+			if tc.setupMocks != nil {
+				tc.setupMocks(m)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/subscriptions/"+testManageToken+"/rules", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			if tc.expectedError != "" {
+				assert.JSONEq(t, fmt.Sprintf(`{"error": %q}`, tc.expectedError), strings.TrimSpace(rr.Body.String()))
+			}
+			m.assertExpectations(t)
+		})
+	}
+}
+
+func TestSubscriptionHandler_DeleteRule(t *testing.T) {
+	sub := &core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv"}
+	ruleID := "6f1c2a9e-3b1d-4c1e-9a51-0d2f1f1b7c11"
+
+	tests := []struct {
+		name               string
+		ruleID             string
+		setupMocks         func(m *subscriptionMocks)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:   "success",
+			ruleID: ruleID,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+				m.rules.On("Delete", mock.Anything, sub.ID, ruleID).Return(true, nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"message": "Notification rule deleted"}`,
+		},
+		{
+			name:   "missing rule",
+			ruleID: ruleID,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+				m.rules.On("Delete", mock.Anything, sub.ID, ruleID).Return(false, nil).Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "Notification rule not found"}`,
+		},
+		{
+			name:               "malformed rule id",
+			ruleID:             "not-a-uuid",
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "Notification rule not found"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, m := newSubscriptionRouter()
+			if tc.setupMocks != nil {
+				tc.setupMocks(m)
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/subscriptions/"+testManageToken+"/rules/"+tc.ruleID, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			assert.JSONEq(t, tc.expectedBody, strings.TrimSpace(rr.Body.String()))
+			m.assertExpectations(t)
+		})
+	}
+}
+
+func TestSubscriptionHandler_Subscribe_SettingsConflict(t *testing.T) {
+	router, m := newSubscriptionRouter()
+	subscriber := &core.Subscriber{ID: "subscriber-1", Email: "test@example.com", Units: "imperial", Language: "en", IsConfirmed: true}
+	m.provider.On("SearchLocations", mock.Anything, "Tokyo").
+		Return([]core.Location{{ID: "test:tokyo", Name: "Tokyo", Timezone: "Asia/Tokyo"}}, nil).Once()
+	m.subscribers.On("FindByEmail", mock.Anything, subscriber.Email).Return(subscriber, nil).Once()
+
+	form := url.Values{"email": {subscriber.Email}, "city": {"Tokyo"}, "frequency": {"daily"}, "units": {"metric"}, "lang": {"en"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"error": "This email's subscriptions use imperial units and language en; subscribe with the same units and language"}`,
+		strings.TrimSpace(rr.Body.String()))
+	m.assertExpectations(t)
+}
+
+func TestSubscriptionHandler_ManageSubscription(t *testing.T) {
+	nextDueAt := time.Date(2025, 5, 21, 7, 0, 0, 0, time.UTC)
+	newSub := func() *core.Subscription {
+		return &core.Subscription{
+			ID: "sub-1", SubscriberID: "subscriber-1", Email: "test@example.com", City: "London", LocationID: "test:london",
+			Timezone: "Europe/London", Frequency: "daily", Units: "metric", Language: "en", DeliveryTime: "08:00",
+			DeliveryTimezone: "Europe/London", NextDueAt: &nextDueAt, IsConfirmed: true, ManageToken: testManageToken,
+		}
+	}
+	unknownToken := "9d7f2b1e-5a4c-4e3b-8f6d-1c2b3a4d5e6f"
+
+	tests := []struct {
+		name               string
+		method             string
+		token              string
+		body               string
+		setupMocks         func(m *subscriptionMocks)
+		expectedStatusCode int
+		expectedBody       string
+		check              func(t *testing.T, sub core.Subscription)
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			token:  testManageToken,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(newSub(), nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			check: func(t *testing.T, sub core.Subscription) {
+				assert.Equal(t, "London", sub.City)
+				assert.Equal(t, "daily", sub.Frequency)
+			},
+		},
+		{
+			name:   "get unknown token",
+			method: http.MethodGet,
+			token:  unknownToken,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, unknownToken).Return(nil, nil).Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "Token not found"}`,
+		},
+		{
+			name:               "get malformed token",
+			method:             http.MethodGet,
+			token:              "not-a-uuid",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "Invalid token format"}`,
+		},
+		{
+			name:   "update",
+			method: http.MethodPatch,
+			token:  testManageToken,
+			body:   `{"delivery_time":"07:30","paused":false}`,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(newSub(), nil).Once()
+				m.subs.On("UpdateSettings", mock.Anything, mock.MatchedBy(func(sub *core.Subscription) bool {
+					return sub.DeliveryTime == "07:30" && sub.NextDueAt != nil && !sub.NextDueAt.Equal(nextDueAt)
+				}), true).Return(nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			check: func(t *testing.T, sub core.Subscription) {
+				assert.Equal(t, "07:30", sub.DeliveryTime)
+				assert.False(t, sub.IsPaused)
+			},
+		},
+		{
+			name:   "update unknown token",
+			method: http.MethodPatch,
+			token:  unknownToken,
+			body:   `{"paused":true}`,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, unknownToken).Return(nil, nil).Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "Token not found"}`,
+		},
+		{
+			name:   "update with invalid frequency",
+			method: http.MethodPatch,
+			token:  testManageToken,
+			body:   `{"frequency":"monthly"}`,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(newSub(), nil).Once()
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "frequency must be hourly, every_Nh (N is 2, 3, 4, 6, 8 or 12), twice_daily, daily, weekdays, weekly with a weekday, or alerts"}`,
+		},
+		{
+			name:               "update with unknown field",
+			method:             http.MethodPatch,
+			token:              testManageToken,
+			body:               `{"email":"other@example.com"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "Invalid subscription update: expected a JSON object with city, frequency, weekday, delivery_time, units or paused"}`,
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			token:  testManageToken,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(newSub(), nil).Once()
+				m.subs.On("Delete", mock.Anything, "sub-1").Return(nil).Once()
+				m.subscribers.On("DeleteIfUnused", mock.Anything, "subscriber-1").Return(nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"message": "Unsubscribed successfully"}`,
+		},
+		{
+			name:   "delete unknown token",
+			method: http.MethodDelete,
+			token:  unknownToken,
+			setupMocks: func(m *subscriptionMocks) {
+				m.subs.On("FindByManageToken", mock.Anything, unknownToken).Return(nil, nil).Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error": "Token not found"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, m := newSubscriptionRouter()
+			if tc.setupMocks != nil {
+				tc.setupMocks(m)
+			}
+
+			req := httptest.NewRequest(tc.method, "/api/subscriptions/"+tc.token, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, strings.TrimSpace(rr.Body.String()))
+			}
+			if tc.check != nil {
+				var sub core.Subscription
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sub))
+				tc.check(t, sub)
+			}
+			m.assertExpectations(t)
+		})
+	}
+}
+
+func TestSubscriptionHandler_ConfirmSubscription_Expired(t *testing.T) {
+	router, m := newSubscriptionRouter()
+	token := "e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"
+	expiredAt := time.Now().Add(-time.Minute)
+	m.subscribers.On("FindByConfirmationToken", mock.Anything, token).
+		Return(&core.Subscriber{ID: "subscriber-1", ConfirmationToken: &token, ConfirmationExpiresAt: &expiredAt}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/confirm/"+token, nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.JSONEq(t, `{"error": "Confirmation link has expired. Request a new one with POST /api/confirm/resend or by subscribing again"}`,
+		strings.TrimSpace(rr.Body.String()))
+	m.assertExpectations(t)
+	m.subscribers.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything)
+}
+
+func TestSubscriptionHandler_ResendConfirmation(t *testing.T) {
+	email := "test@example.com"
+	sub := core.Subscription{ID: "sub-1", SubscriberID: "subscriber-1", Email: email, City: "Kyiv", ManageToken: testManageToken}
+	unconfirmed := &core.Subscriber{ID: "subscriber-1", Email: email, Language: "en"}
+	accepted := `{"message": "If this email has subscriptions waiting for confirmation, a new confirmation link is on its way. Earlier links no longer work."}`
+
+	tests := []struct {
+		name               string
+		form               url.Values
+		setupMocks         func(m *subscriptionMocks)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name: "unknown email",
+			form: url.Values{"email": {email}},
+			setupMocks: func(m *subscriptionMocks) {
+				m.subscribers.On("FindByEmail", mock.Anything, email).Return(nil, nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+			expectedBody:       accepted,
+		},
+		{
+			name: "confirmed email",
+			form: url.Values{"email": {email}},
+			setupMocks: func(m *subscriptionMocks) {
+				m.subscribers.On("FindByEmail", mock.Anything, email).
+					Return(&core.Subscriber{ID: "subscriber-1", Email: email, IsConfirmed: true}, nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+			expectedBody:       accepted,
+		},
+		{
+			name: "sent too recently",
+			form: url.Values{"email": {email}},
+			setupMocks: func(m *subscriptionMocks) {
+				m.subscribers.On("FindByEmail", mock.Anything, email).Return(unconfirmed, nil).Once()
+				m.subs.On("ListBySubscriber", mock.Anything, unconfirmed.ID).Return([]core.Subscription{sub}, nil).Once()
+				m.subscribers.On("ReissueConfirmation", mock.Anything, unconfirmed.ID, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(false, nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+			expectedBody:       accepted,
+		},
+		{
+			name: "sent",
+			form: url.Values{"email": {email}},
+			setupMocks: func(m *subscriptionMocks) {
+				m.subscribers.On("FindByEmail", mock.Anything, email).Return(unconfirmed, nil).Once()
+				m.subs.On("ListBySubscriber", mock.Anything, unconfirmed.ID).Return([]core.Subscription{sub}, nil).Once()
+				m.subscribers.On("ReissueConfirmation", mock.Anything, unconfirmed.ID, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(true, nil).Once()
+				m.emailer.On("SendConfirmationEmail", mock.Anything, email, "en", "Kyiv", mock.Anything, mock.Anything).Return(nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+			expectedBody:       accepted,
+		},
+		{
+			name: "database error",
+			form: url.Values{"email": {email}},
+			setupMocks: func(m *subscriptionMocks) {
+				m.subscribers.On("FindByEmail", mock.Anything, email).Return(nil, errors.New("db down")).Once()
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"error": "Failed to confirm subscription"}`,
+		},
+		{
+			name:               "missing email",
+			form:               url.Values{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error": "email is required"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router, m := newSubscriptionRouter()
+			if tc.setupMocks != nil {
+				tc.setupMocks(m)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/confirm/resend", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code, "status code mismatch")
+			assert.JSONEq(t, tc.expectedBody, strings.TrimSpace(rr.Body.String()))
+			m.assertExpectations(t)
+		})
+	}
+}
+
+func TestSubscriptionHandler_ResendConfirmation_ClientLimit(t *testing.T) {
+	router, m := newSubscriptionRouter()
+	m.subscribers.On("FindByEmail", mock.Anything, mock.Anything).Return(nil, nil).Times(resendRequestsPerClient + 1)
+
+	resend := func(remoteAddr, email string) *httptest.ResponseRecorder {
+		form := url.Values{"email": {email}}
+		req := httptest.NewRequest(http.MethodPost, "/api/confirm/resend", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// the limit is per client, whichever addresses it asks about
+	for i := 0; i < resendRequestsPerClient; i++ {
+		rr := resend("203.0.113.7:5000", fmt.Sprintf("user%d@example.com", i))
+		assert.Equal(t, http.StatusAccepted, rr.Code, "request %d", i)
+	}
+
+	rr := resend("203.0.113.7:5001", "another@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "900", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error": "Too many confirmation requests, please try again later"}`, strings.TrimSpace(rr.Body.String()))
+
+	// other clients are not affected
+	rr = resend("198.51.100.2:5000", "another@example.com")
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	m.assertExpectations(t)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"weather-app/internal/core"
+	"weather-app/internal/i18n"
+)
+
+// autoIP asks for the weather at the caller's own IP address, as seen after middleware.RealIP.
+const autoIP = "auto:ip"
+
+var (
+	errNoLocation        = errors.New("one of city, lat and lon, postcode, airport or ip query parameters is required")
+	errConflictingParams = errors.New("only one of city, lat and lon, postcode, airport or ip may be given")
+	errIncompleteCoords  = errors.New("lat and lon must be given together")
+	errInvalidCoords     = errors.New("lat and lon must be numbers")
+)
+
+// parseLocationQuery reads the location parameters shared by the weather
+// endpoints and makes sure exactly one way of locating the place was used.
+func parseLocationQuery(r *http.Request) (core.LocationQuery, error) {
+	params := r.URL.Query()
+	city := strings.TrimSpace(params.Get("city"))
+	lat, lon := params.Get("lat"), params.Get("lon")
+	postcode := params.Get("postcode")
+	airport := params.Get("airport")
+	ip := params.Get("ip")
+
+	given := 0
+	for _, v := range []string{city, lat + lon, postcode, airport, ip} {
+		if v != "" {
+			given++
+		}
+	}
+	if given == 0 {
+		return core.LocationQuery{}, errNoLocation
+	}
+	if given > 1 {
+		return core.LocationQuery{}, errConflictingParams
+	}
+
+	var query core.LocationQuery
+	switch {
+	case city != "":
+		query = core.CityQuery(city)
+	case lat != "" || lon != "":
+		if lat == "" || lon == "" {
+			return core.LocationQuery{}, errIncompleteCoords
+		}
+		latValue, errLat := strconv.ParseFloat(lat, 64)
+		lonValue, errLon := strconv.ParseFloat(lon, 64)
+		if errLat != nil || errLon != nil {
+			return core.LocationQuery{}, errInvalidCoords
+		}
+		query = core.CoordinatesQuery(latValue, lonValue)
+	case postcode != "":
+		query = core.PostcodeQuery(postcode)
+	case airport != "":
+		query = core.AirportQuery(airport)
+	default:
+		if ip == autoIP || ip == "auto" {
+			ip = clientIP(r)
+		}
+		query = core.IPQuery(ip)
+	}
+
+	if err := query.Validate(); err != nil {
+		return core.LocationQuery{}, err
+	}
+	return query, nil
+}
+
+// clientIP returns the request's IP. middleware.RealIP has already replaced
+// RemoteAddr with the X-Forwarded-For / X-Real-IP address when present.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// locationQueryErrors maps parse errors to the text for the JSON error body.
+var locationQueryErrors = []struct {
+	err error
+	msg i18n.Message
+}{
+	{errNoLocation, i18n.MsgNoLocation},
+	{errConflictingParams, i18n.MsgConflictingLocation},
+	{errIncompleteCoords, i18n.MsgIncompleteCoordinates},
+	{errInvalidCoords, i18n.MsgInvalidCoordinates},
+	{core.ErrEmptyCity, i18n.MsgEmptyCity},
+	{core.ErrCoordinatesOutOfRange, i18n.MsgCoordinatesOutOfRange},
+	{core.ErrInvalidPostcode, i18n.MsgInvalidPostcode},
+	{core.ErrInvalidAirport, i18n.MsgInvalidAirport},
+	{core.ErrInvalidIP, i18n.MsgInvalidIP},
+}
+
+func locationQueryErrorMessage(err error) i18n.Message {
+	for _, e := range locationQueryErrors {
+		if errors.Is(err, e.err) {
+			return e.msg
+		}
+	}
+	return i18n.MsgUnexpectedError
+}
@@ -21,9 +21,20 @@ func NewRouter(wh *WeatherHandler, sh *SubscriptionHandler) *chi.Mux {
 
 	r.Route("/api", func(r chi.Router) {
 		r.Get("/weather", wh.GetWeather)
+		r.Get("/weather/history", wh.GetWeatherHistory)
+		r.Get("/forecast", wh.GetForecast)
+		r.Get("/astronomy", wh.GetAstronomy)
+		r.Get("/cities/search", wh.SearchCities)
 		r.Post("/subscribe", sh.Subscribe)
+		r.Post("/confirm/resend", sh.ResendConfirmation)
 		r.Get("/confirm/{token}", sh.ConfirmSubscription)
 		r.Get("/unsubscribe/{token}", sh.Unsubscribe)
+		r.Get("/subscriptions/{token}", sh.GetSubscription)
+		r.Patch("/subscriptions/{token}", sh.UpdateSubscription)
+		r.Delete("/subscriptions/{token}", sh.DeleteSubscription)
+		r.Get("/subscriptions/{token}/rules", sh.ListRules)
+		r.Post("/subscriptions/{token}/rules", sh.AddRule)
+		r.Delete("/subscriptions/{token}/rules/{id}", sh.DeleteRule)
 	})
 
 	// for serving static html file
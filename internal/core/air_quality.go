@@ -0,0 +1,43 @@
+package core
+
+// AirQuality holds pollutant concentrations in μg/m³ and the two standard indices.
+type AirQuality struct {
+	PM25       float64 `json:"pm2_5"`
+	PM10       float64 `json:"pm10"`
+	O3         float64 `json:"o3"`
+	NO2        float64 `json:"no2"`
+	CO         float64 `json:"co"`
+	SO2        float64 `json:"so2"`
+	USEPAIndex int     `json:"us_epa_index"` // 1 (good) to 6 (hazardous)
+	DEFRAIndex int     `json:"defra_index"`  // 1 (low) to 10 (very high)
+}
+
+// USEPAIndexFromPM25 derives the US EPA category from PM2.5 (2024 breakpoints),
+// for providers that only report concentrations.
+func USEPAIndexFromPM25(pm25 float64) int {
+	switch {
+	case pm25 <= 9.0:
+		return 1
+	case pm25 <= 35.4:
+		return 2
+	case pm25 <= 55.4:
+		return 3
+	case pm25 <= 125.4:
+		return 4
+	case pm25 <= 225.4:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// DEFRAIndexFromPM25 derives the UK DEFRA Daily Air Quality Index from PM2.5.
+func DEFRAIndexFromPM25(pm25 float64) int {
+	upperBounds := []float64{11, 23, 35, 41, 47, 53, 58, 64, 70}
+	for i, bound := range upperBounds {
+		if pm25 <= bound {
+			return i + 1
+		}
+	}
+	return 10
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of calendar dates such as ForecastDay.Date.
+const DateLayout = "2006-01-02"
+
+type MoonPhase string
+
+const (
+	NewMoon        MoonPhase = "new_moon"
+	WaxingCrescent MoonPhase = "waxing_crescent"
+	FirstQuarter   MoonPhase = "first_quarter"
+	WaxingGibbous  MoonPhase = "waxing_gibbous"
+	FullMoon       MoonPhase = "full_moon"
+	WaningGibbous  MoonPhase = "waning_gibbous"
+	LastQuarter    MoonPhase = "last_quarter"
+	WaningCrescent MoonPhase = "waning_crescent"
+)
+
+// moonPhases are in order through the synodic month, starting at new moon.
+var moonPhases = []MoonPhase{NewMoon, WaxingCrescent, FirstQuarter, WaxingGibbous, FullMoon, WaningGibbous, LastQuarter, WaningCrescent}
+
+// ParseMoonPhase reads a provider's phase name such as "Waxing Crescent". It
+// returns "" for names it doesn't know.
+func ParseMoonPhase(name string) MoonPhase {
+	phase := MoonPhase(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_"))
+	if phase == "third_quarter" {
+		return LastQuarter
+	}
+	for _, p := range moonPhases {
+		if p == phase {
+			return p
+		}
+	}
+	return ""
+}
+
+// Astronomy is the sun and moon for one calendar day at a location.
+type Astronomy struct {
+	Date     string   `json:"date"` // YYYY-MM-DD, local to the location
+	Location Location `json:"location"`
+	// Sunrise and Sunset are nil when the sun doesn't rise or set that day
+	Sunrise          *time.Time `json:"sunrise"`
+	Sunset           *time.Time `json:"sunset"`
+	DayLengthMinutes int        `json:"day_length_minutes"`
+	MoonPhase        MoonPhase  `json:"moon_phase"`
+	MoonIllumination int        `json:"moon_illumination"` // % of the disc lit
+	// Computed is set when the times were calculated locally rather than
+	// reported by a weather provider.
+	Computed bool `json:"computed,omitempty"`
+}
+
+// synodicMonth is the mean time from one new moon to the next, in days.
+const synodicMonth = 29.530588853
+
+// referenceNewMoon is the new moon of 6 January 2000.
+var referenceNewMoon = time.Date(2000, 1, 6, 18, 14, 0, 0, time.UTC)
+
+// MoonAt estimates the moon's phase and illumination (%) at t from the mean
+// lunar cycle; it can be off by up to a day from the true phase.
+func MoonAt(t time.Time) (MoonPhase, int) {
+	age := math.Mod(t.Sub(referenceNewMoon).Hours()/24, synodicMonth)
+	if age < 0 {
+		age += synodicMonth
+	}
+	illumination := (1 - math.Cos(2*math.Pi*age/synodicMonth)) / 2
+	// each phase is centred on its point in the cycle, so new moon spans the wrap-around
+	idx := int(math.Floor(age/synodicMonth*8+0.5)) % len(moonPhases)
+	return moonPhases[idx], int(math.Round(illumination * 100))
+}
+
+// ComputeAstronomy calculates sunrise, sunset and the moon for date
+// (YYYY-MM-DD) at loc with the NOAA sunrise equation, which is accurate to a
+// minute or two. Times are in loc's timezone, or UTC if it has none.
+func ComputeAstronomy(loc Location, date string) (*Astronomy, error) {
+	tz := time.UTC
+	if loc.Timezone != "" {
+		if l, err := time.LoadLocation(loc.Timezone); err == nil {
+			tz = l
+		}
+	}
+	day, err := time.ParseInLocation(DateLayout, date, tz)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Astronomy{Date: date, Location: loc, Computed: true}
+	noon := day.Add(12 * time.Hour)
+	a.MoonPhase, a.MoonIllumination = MoonAt(noon)
+
+	sunrise, sunset, cosHourAngle := sunTimes(loc.Lat, loc.Lon, noon)
+	switch {
+	case cosHourAngle > 1: // polar night
+		a.DayLengthMinutes = 0
+	case cosHourAngle < -1: // midnight sun
+		a.DayLengthMinutes = 24 * 60
+	default:
+		rise, set := sunrise.In(tz), sunset.In(tz)
+		a.Sunrise, a.Sunset = &rise, &set
+		a.DayLengthMinutes = int(math.Round(set.Sub(rise).Minutes()))
+	}
+	return a, nil
+}
+
+// sunTimes returns sunrise and sunset around the solar noon nearest to t, and
+// the cosine of the sunrise hour angle, which is outside [-1, 1] when the sun
+// stays up or down all day.
+func sunTimes(lat, lon float64, t time.Time) (sunrise, sunset time.Time, cosHourAngle float64) {
+	const j2000 = 2451545.0
+	rad := math.Pi / 180
+
+	julianDay := float64(t.Unix())/86400 + 2440587.5
+	// longitudes are east-positive, so solar noon comes earlier in UTC east of Greenwich
+	n := math.Round(julianDay - j2000 - 0.0009 + lon/360)
+	meanNoon := n + 0.0009 - lon/360
+
+	m := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*math.Sin(m*rad) + 0.0200*math.Sin(2*m*rad) + 0.0003*math.Sin(3*m*rad)
+	lambda := math.Mod(m+center+180+102.9372, 360)
+	transit := j2000 + meanNoon + 0.0053*math.Sin(m*rad) - 0.0069*math.Sin(2*lambda*rad)
+
+	sinDecl := math.Sin(lambda*rad) * math.Sin(23.4397*rad)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHourAngle = (math.Sin(-0.833*rad) - math.Sin(lat*rad)*sinDecl) / (math.Cos(lat*rad) * cosDecl)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, cosHourAngle
+	}
+	hourAngle := math.Acos(cosHourAngle) / rad
+
+	fromJulian := func(j float64) time.Time {
+		return time.Unix(int64(math.Round((j-2440587.5)*86400)), 0).UTC()
+	}
+	return fromJulian(transit - hourAngle/360), fromJulian(transit + hourAngle/360), cosHourAngle
+}
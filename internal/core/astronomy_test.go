@@ -0,0 +1,116 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeAstronomy(t *testing.T) {
+	tests := []struct {
+		name             string
+		loc              Location
+		date             string
+		sunrise, sunset  string // local HH:MM, empty when the sun doesn't rise or set
+		dayLengthMinutes int
+	}{
+		{
+			name:    "Kyiv midsummer",
+			loc:     Location{Lat: 50.45, Lon: 30.52, Timezone: "Europe/Kyiv"},
+			date:    "2025-06-21",
+			sunrise: "04:47", sunset: "21:14",
+		},
+		{
+			name:    "London midwinter",
+			loc:     Location{Lat: 51.5074, Lon: -0.1278, Timezone: "Europe/London"},
+			date:    "2025-12-21",
+			sunrise: "08:04", sunset: "15:53",
+		},
+		{
+			name:    "New York equinox",
+			loc:     Location{Lat: 40.71, Lon: -74.01, Timezone: "America/New_York"},
+			date:    "2025-03-20",
+			sunrise: "07:00", sunset: "19:09",
+		},
+		{
+			name:    "Sydney southern summer",
+			loc:     Location{Lat: -33.87, Lon: 151.21, Timezone: "Australia/Sydney"},
+			date:    "2025-01-01",
+			sunrise: "05:48", sunset: "20:10",
+		},
+		{
+			name:             "Tromsø midnight sun",
+			loc:              Location{Lat: 69.65, Lon: 18.96, Timezone: "Europe/Oslo"},
+			date:             "2025-06-21",
+			dayLengthMinutes: 24 * 60,
+		},
+		{
+			name:             "Tromsø polar night",
+			loc:              Location{Lat: 69.65, Lon: 18.96, Timezone: "Europe/Oslo"},
+			date:             "2025-12-21",
+			dayLengthMinutes: 0,
+		},
+	}
+
+	// the sunrise equation is good to a couple of minutes
+	const tolerance = 2 * time.Minute
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := ComputeAstronomy(tc.loc, tc.date)
+			require.NoError(t, err)
+			assert.True(t, a.Computed)
+			assert.Equal(t, tc.date, a.Date)
+
+			if tc.sunrise == "" {
+				assert.Nil(t, a.Sunrise)
+				assert.Nil(t, a.Sunset)
+				assert.Equal(t, tc.dayLengthMinutes, a.DayLengthMinutes)
+				return
+			}
+			tz, err := time.LoadLocation(tc.loc.Timezone)
+			require.NoError(t, err)
+			wantRise, _ := time.ParseInLocation("2006-01-02 15:04", tc.date+" "+tc.sunrise, tz)
+			wantSet, _ := time.ParseInLocation("2006-01-02 15:04", tc.date+" "+tc.sunset, tz)
+			require.NotNil(t, a.Sunrise)
+			assert.WithinDuration(t, wantRise, *a.Sunrise, tolerance)
+			assert.WithinDuration(t, wantSet, *a.Sunset, tolerance)
+			assert.Equal(t, tz.String(), a.Sunrise.Location().String())
+			assert.InDelta(t, wantSet.Sub(wantRise).Minutes(), a.DayLengthMinutes, 2*tolerance.Minutes())
+		})
+	}
+
+	_, err := ComputeAstronomy(Location{}, "21/06/2025")
+	assert.Error(t, err)
+}
+
+func TestMoonAt(t *testing.T) {
+	tests := []struct {
+		at    time.Time
+		phase MoonPhase
+	}{
+		{at: time.Date(2025, 1, 29, 12, 36, 0, 0, time.UTC), phase: NewMoon},
+		{at: time.Date(2025, 2, 5, 8, 2, 0, 0, time.UTC), phase: FirstQuarter},
+		{at: time.Date(2025, 2, 12, 13, 53, 0, 0, time.UTC), phase: FullMoon},
+		{at: time.Date(2025, 2, 20, 17, 32, 0, 0, time.UTC), phase: LastQuarter},
+		{at: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC), phase: WaxingCrescent},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.at.Format(time.RFC3339), func(t *testing.T) {
+			phase, _ := MoonAt(tc.at)
+			assert.Equal(t, tc.phase, phase)
+		})
+	}
+
+	_, illumination := MoonAt(time.Date(2025, 2, 12, 13, 53, 0, 0, time.UTC))
+	assert.GreaterOrEqual(t, illumination, 98)
+}
+
+func TestParseMoonPhase(t *testing.T) {
+	assert.Equal(t, WaxingCrescent, ParseMoonPhase("Waxing Crescent"))
+	assert.Equal(t, LastQuarter, ParseMoonPhase("Third Quarter"))
+	assert.Equal(t, FullMoon, ParseMoonPhase(" full moon "))
+	assert.Equal(t, MoonPhase(""), ParseMoonPhase("Blue moon"))
+}
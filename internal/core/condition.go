@@ -0,0 +1,87 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+// Condition is a weather condition normalized across providers, so rules and
+// emails don't depend on any provider's wording or language.
+type Condition string
+
+const (
+	ConditionClear        Condition = "clear"
+	ConditionPartlyCloudy Condition = "partly_cloudy"
+	ConditionCloudy       Condition = "cloudy"
+	ConditionOvercast     Condition = "overcast"
+	ConditionFog          Condition = "fog"
+	ConditionDrizzle      Condition = "drizzle"
+	ConditionRain         Condition = "rain"
+	ConditionHeavyRain    Condition = "heavy_rain"
+	ConditionFreezingRain Condition = "freezing_rain"
+	ConditionSleet        Condition = "sleet"
+	ConditionSnow         Condition = "snow"
+	ConditionHeavySnow    Condition = "heavy_snow"
+	ConditionThunder      Condition = "thunder"
+	// ConditionUnknown is used for provider codes that aren't mapped.
+	ConditionUnknown Condition = "unknown"
+)
+
+var ErrInvalidCondition = errors.New("invalid condition")
+
+// conditions are the known conditions, roughly from fair to severe.
+var conditions = []Condition{
+	ConditionClear, ConditionPartlyCloudy, ConditionCloudy, ConditionOvercast, ConditionFog,
+	ConditionDrizzle, ConditionRain, ConditionHeavyRain, ConditionFreezingRain, ConditionSleet,
+	ConditionSnow, ConditionHeavySnow, ConditionThunder,
+}
+
+// Conditions lists the conditions a provider can report, without
+// ConditionUnknown.
+func Conditions() []Condition {
+	return append([]Condition(nil), conditions...)
+}
+
+// ParseCondition reads a condition name such as "partly_cloudy"; spaces and
+// dashes are accepted in place of underscores.
+func ParseCondition(s string) (Condition, error) {
+	name := strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(s)))
+	for _, c := range conditions {
+		if Condition(name) == c {
+			return c, nil
+		}
+	}
+	return "", ErrInvalidCondition
+}
+
+// HasDayNight reports whether c is drawn differently by day and by night.
+func (c Condition) HasDayNight() bool {
+	return c == ConditionClear || c == ConditionPartlyCloudy
+}
+
+// Icon is the icon identifier for c, e.g. "clear-night" or "heavy-rain".
+// Only conditions with a visible sky have day and night variants.
+func (c Condition) Icon(isDay bool) string {
+	if c == "" {
+		c = ConditionUnknown
+	}
+	icon := strings.ReplaceAll(string(c), "_", "-")
+	if !c.HasDayNight() {
+		return icon
+	}
+	if isDay {
+		return icon + "-day"
+	}
+	return icon + "-night"
+}
+
+// IsPrecipitation reports whether something is falling: rain, snow, sleet or
+// a thunderstorm.
+func (c Condition) IsPrecipitation() bool {
+	switch c {
+	case ConditionDrizzle, ConditionRain, ConditionHeavyRain, ConditionFreezingRain,
+		ConditionSleet, ConditionSnow, ConditionHeavySnow, ConditionThunder:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Condition
+		wantErr  bool
+	}{
+		{input: "clear", expected: ConditionClear},
+		{input: "Partly Cloudy", expected: ConditionPartlyCloudy},
+		{input: "heavy-rain", expected: ConditionHeavyRain},
+		{input: " thunder ", expected: ConditionThunder},
+		{input: "unknown", wantErr: true},
+		{input: "hail", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			c, err := ParseCondition(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCondition)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, c)
+		})
+	}
+}
+
+func TestCondition_Icon(t *testing.T) {
+	tests := []struct {
+		condition Condition
+		isDay     bool
+		expected  string
+	}{
+		{condition: ConditionClear, isDay: true, expected: "clear-day"},
+		{condition: ConditionClear, isDay: false, expected: "clear-night"},
+		{condition: ConditionPartlyCloudy, isDay: false, expected: "partly-cloudy-night"},
+		{condition: ConditionHeavySnow, isDay: true, expected: "heavy-snow"},
+		{condition: ConditionHeavySnow, isDay: false, expected: "heavy-snow"},
+		{condition: "", isDay: true, expected: "unknown"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.condition.Icon(tc.isDay))
+		})
+	}
+}
+
+func TestCondition_IsPrecipitation(t *testing.T) {
+	assert.True(t, ConditionDrizzle.IsPrecipitation())
+	assert.True(t, ConditionThunder.IsPrecipitation())
+	assert.False(t, ConditionFog.IsPrecipitation())
+	assert.False(t, ConditionUnknown.IsPrecipitation())
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDeliveryTime = errors.New("invalid delivery time")
+	ErrInvalidTimezone     = errors.New("invalid timezone")
+)
+
+// DefaultDeliveryTime is when daily updates go out unless the subscriber
+// picks another time.
+const DefaultDeliveryTime = "08:00"
+
+// ClockTime is a local time of day with minute precision.
+type ClockTime struct {
+	Hour   int
+	Minute int
+}
+
+// ParseClockTime reads a 24-hour "HH:MM" time such as "07:30".
+func ParseClockTime(s string) (ClockTime, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return ClockTime{}, fmt.Errorf("%w: %q is not a HH:MM time", ErrInvalidDeliveryTime, s)
+	}
+	return ClockTime{Hour: t.Hour(), Minute: t.Minute()}, nil
+}
+
+func (c ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+// On returns the instant the clock in loc shows c on the given day. When
+// daylight saving time skips c that day, it is the moment the clocks jump;
+// when c happens twice as the clocks fall back, it is the first of the two.
+func (c ClockTime) On(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, c.Hour, c.Minute, 0, 0, loc)
+	start, end := t.ZoneBounds()
+
+	if t.Hour() != c.Hour || t.Minute() != c.Minute {
+		// c is in a spring-forward gap, which time.Date resolves with either offset
+		if t.Hour()*60+t.Minute() > c.Hour*60+c.Minute {
+			return start
+		}
+		return end
+	}
+
+	if !start.IsZero() {
+		// if the clocks were further ahead before this zone began, c may have happened then already
+		_, offset := t.Zone()
+		_, prevOffset := start.Add(-time.Second).Zone()
+		if earlier := t.Add(-time.Duration(prevOffset-offset) * time.Second); prevOffset > offset && earlier.Before(start) {
+			return earlier
+		}
+	}
+	return t
+}
+
+// LoadTimezone loads an IANA timezone such as "Europe/Kyiv".
+func LoadTimezone(name string) (*time.Location, error) {
+	// time.LoadLocation treats "" as UTC and "Local" as the server's zone, neither of which a subscriber means
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// DeliveryLocation is the timezone the subscription's delivery time is in,
+// falling back to the city's timezone and then UTC.
+func (s Subscription) DeliveryLocation() *time.Location {
+	for _, name := range []string{s.DeliveryTimezone, s.Timezone} {
+		if loc, err := LoadTimezone(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// DailyDeliveryAt is when the subscription's daily update is due on the local
+// calendar day that t falls on.
+func (s Subscription) DailyDeliveryAt(t time.Time) time.Time {
+	loc := s.DeliveryLocation()
+	year, month, day := t.In(loc).Date()
+	return s.deliveryClock().On(year, month, day, loc)
+}
+
+// deliveryClock is the subscription's delivery time, or the default for rows
+// that don't have a valid one.
+func (s Subscription) deliveryClock() ClockTime {
+	clock, err := ParseClockTime(s.DeliveryTime)
+	if err != nil {
+		clock, _ = ParseClockTime(DefaultDeliveryTime)
+	}
+	return clock
+}
+
+// NextDeliveryAfter is when the first routine update after t is due: the top
+// of the next UTC hour for hourly subscriptions, otherwise the next local time
+// the subscription's frequency delivers at. It is nil for subscriptions
+// without routine updates.
+func (s Subscription) NextDeliveryAfter(t time.Time) *time.Time {
+	frequency, err := ParseFrequency(s.Frequency)
+	if err != nil || !frequency.HasRoutineUpdates() {
+		return nil
+	}
+	if frequency.Kind == FrequencyHourly {
+		next := t.Truncate(time.Hour).Add(time.Hour).UTC()
+		return &next
+	}
+
+	loc := s.DeliveryLocation()
+	clocks := frequency.clocks(s.deliveryClock())
+	year, month, day := t.In(loc).Date()
+	// every schedule delivers at least once a week
+	for days := 0; days <= 7; days++ {
+		// noon is never skipped by daylight saving changes, unlike midnight in some zones
+		if !frequency.deliversOn(time.Date(year, month, day+days, 12, 0, 0, 0, loc).Weekday()) {
+			continue
+		}
+		for _, clock := range clocks {
+			if next := clock.On(year, month, day+days, loc); next.After(t) {
+				next = next.UTC()
+				return &next
+			}
+		}
+	}
+	return nil
+}
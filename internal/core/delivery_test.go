@@ -0,0 +1,216 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClockTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "08:00", expected: "08:00"},
+		{input: "7:30", expected: "07:30"},
+		{input: " 23:59 ", expected: "23:59"},
+		{input: "24:00", wantErr: true},
+		{input: "08:60", wantErr: true},
+		{input: "8am", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			c, err := ParseClockTime(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDeliveryTime)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, c.String())
+		})
+	}
+}
+
+func TestClockTime_On(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		clock    ClockTime
+		date     time.Time // only the calendar day is used
+		loc      *time.Location
+		expected time.Time
+	}{
+		{
+			name:     "summer time",
+			clock:    ClockTime{Hour: 8},
+			date:     time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			loc:      newYork,
+			expected: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "winter time",
+			clock:    ClockTime{Hour: 8},
+			date:     time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+			loc:      newYork,
+			expected: time.Date(2025, 12, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day the clocks go forward",
+			clock:    ClockTime{Hour: 8},
+			date:     time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC),
+			loc:      kyiv,
+			expected: time.Date(2025, 3, 30, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped by the clocks going forward",
+			clock:    ClockTime{Hour: 3, Minute: 30},
+			date:     time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC),
+			loc:      kyiv,
+			expected: time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC), // 04:00 EEST
+		},
+		{
+			name:     "skipped in New York",
+			clock:    ClockTime{Hour: 2, Minute: 30},
+			date:     time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC),
+			loc:      newYork,
+			expected: time.Date(2025, 3, 9, 7, 0, 0, 0, time.UTC), // 03:00 EDT
+		},
+		{
+			name:     "repeated by the clocks going back",
+			clock:    ClockTime{Hour: 3, Minute: 30},
+			date:     time.Date(2025, 10, 26, 0, 0, 0, 0, time.UTC),
+			loc:      kyiv,
+			expected: time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC), // the first, still EEST
+		},
+		{
+			name:     "repeated in New York",
+			clock:    ClockTime{Hour: 1, Minute: 30},
+			date:     time.Date(2025, 11, 2, 0, 0, 0, 0, time.UTC),
+			loc:      newYork,
+			expected: time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC), // the first, still EDT
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.clock.On(tc.date.Year(), tc.date.Month(), tc.date.Day(), tc.loc)
+			assert.True(t, tc.expected.Equal(got), "expected %s, got %s", tc.expected, got.UTC())
+		})
+	}
+}
+
+func TestSubscription_DailyDeliveryAt(t *testing.T) {
+	sub := Subscription{DeliveryTime: "07:30", DeliveryTimezone: "America/New_York", Timezone: "Europe/Kyiv"}
+	// 02:00 UTC is still the previous evening in New York
+	at := sub.DailyDeliveryAt(time.Date(2025, 6, 2, 2, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 6, 1, 11, 30, 0, 0, time.UTC), at.UTC())
+
+	// rows from before delivery times existed fall back to the city's timezone, then UTC
+	sub = Subscription{Timezone: "Europe/Kyiv"}
+	assert.Equal(t, time.Date(2025, 6, 2, 5, 0, 0, 0, time.UTC), sub.DailyDeliveryAt(time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)).UTC())
+	sub = Subscription{DeliveryTimezone: "Mars/Olympus_Mons"}
+	assert.Equal(t, time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC), sub.DailyDeliveryAt(time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)).UTC())
+}
+
+func TestSubscription_NextDeliveryAfter(t *testing.T) {
+	kyiv := Subscription{Frequency: "daily", DeliveryTime: "08:00", DeliveryTimezone: "Europe/Kyiv"}
+
+	tests := []struct {
+		name     string
+		sub      Subscription
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "hourly goes out at the top of the next hour",
+			sub:      Subscription{Frequency: "hourly"},
+			after:    time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily before the delivery time is due the same day",
+			sub:      kyiv,
+			after:    time.Date(2025, 6, 2, 4, 59, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 2, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily at the delivery time is due the next day",
+			sub:      kyiv,
+			after:    time.Date(2025, 6, 2, 5, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 3, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily after days of downtime is due once, at the next delivery time",
+			sub:      kyiv,
+			after:    time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 10, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily across the switch to winter time keeps the local time",
+			sub:      kyiv,
+			after:    time.Date(2025, 10, 25, 6, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 10, 26, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 6 hours is spaced from the delivery time",
+			sub:      Subscription{Frequency: "every_6h", DeliveryTime: "07:30", DeliveryTimezone: "UTC"},
+			after:    time.Date(2025, 6, 2, 20, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 3, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "twice daily goes out again 12 hours later",
+			sub:      Subscription{Frequency: "twice_daily", DeliveryTime: "08:00", DeliveryTimezone: "Europe/Kyiv"},
+			after:    time.Date(2025, 6, 2, 5, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 2, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays skip the weekend",
+			sub:      Subscription{Frequency: "weekdays", DeliveryTime: "08:00", DeliveryTimezone: "Europe/Kyiv"},
+			after:    time.Date(2025, 6, 6, 6, 0, 0, 0, time.UTC), // Friday after delivery
+			expected: time.Date(2025, 6, 9, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly is due on the chosen local day",
+			sub:      Subscription{Frequency: "weekly_monday", DeliveryTime: "07:00", DeliveryTimezone: "America/New_York"},
+			after:    time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC), // Monday 08:00 in New York
+			expected: time.Date(2025, 6, 9, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly on the local day that is already the next day in UTC",
+			sub:      Subscription{Frequency: "weekly_sunday", DeliveryTime: "23:00", DeliveryTimezone: "America/New_York"},
+			after:    time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 6, 9, 3, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.sub.NextDeliveryAfter(tc.after)
+			require.NotNil(t, got)
+			assert.Equal(t, tc.expected, *got)
+		})
+	}
+
+	assert.Nil(t, Subscription{Frequency: "alerts"}.NextDeliveryAfter(time.Now()))
+	assert.Nil(t, Subscription{Frequency: "fortnightly"}.NextDeliveryAfter(time.Now()))
+}
+
+func TestLoadTimezone(t *testing.T) {
+	loc, err := LoadTimezone("Europe/Kyiv")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Kyiv", loc.String())
+
+	for _, name := range []string{"", "Local", "Europe/Atlantis"} {
+		_, err := LoadTimezone(name)
+		assert.ErrorIs(t, err, ErrInvalidTimezone, name)
+	}
+}
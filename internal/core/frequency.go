@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrequencyKind is the kind of schedule a subscription's updates follow.
+type FrequencyKind string
+
+const (
+	FrequencyHourly     FrequencyKind = "hourly"
+	FrequencyEveryHours FrequencyKind = "every_hours" // written as e.g. "every_3h"
+	FrequencyTwiceDaily FrequencyKind = "twice_daily"
+	FrequencyDaily      FrequencyKind = "daily"
+	FrequencyWeekdays   FrequencyKind = "weekdays"
+	FrequencyWeekly     FrequencyKind = "weekly" // written as e.g. "weekly_monday"
+	// FrequencyAlerts sends no routine updates, only severe weather alerts.
+	FrequencyAlerts FrequencyKind = "alerts"
+)
+
+var ErrInvalidFrequency = errors.New("invalid frequency")
+
+// EveryHoursIntervals are the intervals an "every N hours" subscription can
+// pick; each divides the day evenly, so updates fall at the same times daily.
+var EveryHoursIntervals = []int{2, 3, 4, 6, 8, 12}
+
+// Frequency is how often a subscription gets routine updates. Schedules other
+// than hourly are anchored to the subscription's local delivery time.
+type Frequency struct {
+	Kind    FrequencyKind
+	Hours   int          // for FrequencyEveryHours
+	Weekday time.Weekday // for FrequencyWeekly
+}
+
+// ParseFrequency reads a frequency as stored on a subscription: "hourly",
+// "every_3h", "twice_daily", "daily", "weekdays", "weekly_monday" or "alerts".
+// Weekly days may be abbreviated, e.g. "weekly_mon".
+func ParseFrequency(s string) (Frequency, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch kind := FrequencyKind(name); kind {
+	case FrequencyHourly, FrequencyTwiceDaily, FrequencyDaily, FrequencyWeekdays, FrequencyAlerts:
+		return Frequency{Kind: kind}, nil
+	}
+
+	if hours, ok := strings.CutPrefix(name, "every_"); ok {
+		n, err := strconv.Atoi(strings.TrimSuffix(hours, "h"))
+		if err == nil && strings.HasSuffix(hours, "h") && slices.Contains(EveryHoursIntervals, n) {
+			return Frequency{Kind: FrequencyEveryHours, Hours: n}, nil
+		}
+	}
+	if day, ok := strings.CutPrefix(name, "weekly_"); ok {
+		if weekday, ok := parseWeekday(day); ok {
+			return Frequency{Kind: FrequencyWeekly, Weekday: weekday}, nil
+		}
+	}
+	return Frequency{}, fmt.Errorf("%w: %q", ErrInvalidFrequency, s)
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func (f Frequency) String() string {
+	switch f.Kind {
+	case FrequencyEveryHours:
+		return fmt.Sprintf("every_%dh", f.Hours)
+	case FrequencyWeekly:
+		return "weekly_" + strings.ToLower(f.Weekday.String())
+	}
+	return string(f.Kind)
+}
+
+// HasRoutineUpdates reports whether the scheduler sends updates on this
+// schedule, which is everything but alerts-only subscriptions.
+func (f Frequency) HasRoutineUpdates() bool {
+	return f.Kind != FrequencyAlerts && f.Kind != ""
+}
+
+// SendsForecast reports whether updates are at most daily and so carry the
+// day's forecast rather than the current conditions.
+func (f Frequency) SendsForecast() bool {
+	return f.Kind == FrequencyDaily || f.Kind == FrequencyWeekdays || f.Kind == FrequencyWeekly
+}
+
+// deliversOn reports whether updates go out on the given local weekday.
+func (f Frequency) deliversOn(day time.Weekday) bool {
+	switch f.Kind {
+	case FrequencyWeekdays:
+		return day != time.Saturday && day != time.Sunday
+	case FrequencyWeekly:
+		return day == f.Weekday
+	}
+	return true
+}
+
+// clocks are the local times updates go out on a delivery day, in order,
+// for a subscription delivering at at.
+func (f Frequency) clocks(at ClockTime) []ClockTime {
+	every := 24
+	switch f.Kind {
+	case FrequencyEveryHours:
+		every = f.Hours
+	case FrequencyTwiceDaily:
+		every = 12
+	}
+
+	clocks := make([]ClockTime, 0, 24/every)
+	for h := at.Hour % every; h < 24; h += every {
+		clocks = append(clocks, ClockTime{Hour: h, Minute: at.Minute})
+	}
+	return clocks
+}
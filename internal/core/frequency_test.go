@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFrequency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Frequency
+		name     string
+		wantErr  bool
+	}{
+		{input: "hourly", expected: Frequency{Kind: FrequencyHourly}, name: "hourly"},
+		{input: " Daily ", expected: Frequency{Kind: FrequencyDaily}, name: "daily"},
+		{input: "twice_daily", expected: Frequency{Kind: FrequencyTwiceDaily}, name: "twice_daily"},
+		{input: "weekdays", expected: Frequency{Kind: FrequencyWeekdays}, name: "weekdays"},
+		{input: "alerts", expected: Frequency{Kind: FrequencyAlerts}, name: "alerts"},
+		{input: "every_6h", expected: Frequency{Kind: FrequencyEveryHours, Hours: 6}, name: "every_6h"},
+		{input: "weekly_friday", expected: Frequency{Kind: FrequencyWeekly, Weekday: time.Friday}, name: "weekly_friday"},
+		{input: "weekly_sun", expected: Frequency{Kind: FrequencyWeekly, Weekday: time.Sunday}, name: "weekly_sunday"},
+		{input: "every_5h", wantErr: true},
+		{input: "every_6", wantErr: true},
+		{input: "every_h", wantErr: true},
+		{input: "weekly", wantErr: true},
+		{input: "weekly_someday", wantErr: true},
+		{input: "every_hours", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			f, err := ParseFrequency(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidFrequency)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+			assert.Equal(t, tc.name, f.String())
+		})
+	}
+}
+
+func TestFrequency_Clocks(t *testing.T) {
+	at := ClockTime{Hour: 7, Minute: 30}
+	assert.Equal(t, []ClockTime{{7, 30}}, Frequency{Kind: FrequencyDaily}.clocks(at))
+	assert.Equal(t, []ClockTime{{7, 30}, {19, 30}}, Frequency{Kind: FrequencyTwiceDaily}.clocks(at))
+	assert.Equal(t, []ClockTime{{1, 30}, {7, 30}, {13, 30}, {19, 30}}, Frequency{Kind: FrequencyEveryHours, Hours: 6}.clocks(at))
+}
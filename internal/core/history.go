@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidInterval = errors.New("invalid history interval")
+
+// HistoryInterval is the bucket size observations are aggregated into.
+type HistoryInterval string
+
+const (
+	HistoryByHour HistoryInterval = "hour"
+	HistoryByDay  HistoryInterval = "day"
+)
+
+// ParseHistoryInterval accepts "hour" or "day"; an empty string means hour.
+func ParseHistoryInterval(s string) (HistoryInterval, error) {
+	switch HistoryInterval(s) {
+	case "", HistoryByHour:
+		return HistoryByHour, nil
+	case HistoryByDay:
+		return HistoryByDay, nil
+	}
+	return "", ErrInvalidInterval
+}
+
+// Duration is the length of one bucket.
+func (i HistoryInterval) Duration() time.Duration {
+	if i == HistoryByDay {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// Stats summarizes one measurement over a bucket.
+type Stats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+func (s Stats) convert(f func(float64) float64) Stats {
+	return Stats{Min: f(s.Min), Max: f(s.Max), Avg: f(s.Avg)}
+}
+
+// HistoryPoint aggregates the observations recorded in one bucket starting at Time (UTC).
+type HistoryPoint struct {
+	Time          time.Time `json:"time"`
+	Observations  int       `json:"observations"`
+	Temperature   Stats     `json:"temperature"`
+	Humidity      Stats     `json:"humidity"`
+	WindSpeed     Stats     `json:"wind_speed"`
+	Pressure      Stats     `json:"pressure"`
+	Precipitation Stats     `json:"precipitation"`
+}
+
+type WeatherHistory struct {
+	City     string          `json:"city,omitempty"`
+	Location string          `json:"location"` // the key the observations are stored under, e.g. "city:kyiv"
+	Interval HistoryInterval `json:"interval"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Points   []HistoryPoint  `json:"points"`
+}
+
+// ConvertTo returns a copy of h with every measurement expressed in u.
+func (h WeatherHistory) ConvertTo(u UnitSystem) WeatherHistory {
+	points := make([]HistoryPoint, len(h.Points))
+	for i, p := range h.Points {
+		p.Temperature = p.Temperature.convert(u.ConvertTemperature)
+		p.WindSpeed = p.WindSpeed.convert(u.ConvertSpeed)
+		p.Pressure = p.Pressure.convert(u.ConvertPressure)
+		p.Precipitation = p.Precipitation.convert(u.ConvertPrecipitation)
+		points[i] = p
+	}
+	h.Points = points
+	return h
+}
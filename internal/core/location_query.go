@@ -0,0 +1,126 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidLocationQuery = errors.New("invalid location query")
+
+var (
+	ErrEmptyCity             = fmt.Errorf("%w: city is empty", ErrInvalidLocationQuery)
+	ErrCoordinatesOutOfRange = fmt.Errorf("%w: lat must be within [-90, 90] and lon within [-180, 180]", ErrInvalidLocationQuery)
+	ErrInvalidPostcode       = fmt.Errorf("%w: postcode must be 2-10 letters, digits, spaces or dashes", ErrInvalidLocationQuery)
+	ErrInvalidAirport        = fmt.Errorf("%w: airport must be a 3-letter IATA code", ErrInvalidLocationQuery)
+	ErrInvalidIP             = fmt.Errorf("%w: ip must be an IPv4 or IPv6 address", ErrInvalidLocationQuery)
+)
+
+type LocationQueryKind string
+
+const (
+	QueryByCity        LocationQueryKind = "city"
+	QueryByCoordinates LocationQueryKind = "coordinates"
+	QueryByPostcode    LocationQueryKind = "postcode"
+	QueryByAirport     LocationQueryKind = "airport"
+	QueryByIP          LocationQueryKind = "ip"
+)
+
+var (
+	airportCodePattern = regexp.MustCompile(`^[A-Za-z]{3}$`)
+	postcodePattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+)
+
+// LocationQuery says how to find a place. Only the fields for Kind are set;
+// each provider adapter translates it into its own request parameters.
+type LocationQuery struct {
+	Kind     LocationQueryKind
+	City     string
+	Lat      float64
+	Lon      float64
+	Postcode string
+	Airport  string // IATA code, upper case
+	IP       string
+	// Lang asks for condition descriptions in this language, e.g. "uk".
+	// Empty means English, which every provider speaks by default.
+	Lang string
+}
+
+func CityQuery(city string) LocationQuery {
+	return LocationQuery{Kind: QueryByCity, City: strings.TrimSpace(city)}
+}
+
+func CoordinatesQuery(lat, lon float64) LocationQuery {
+	return LocationQuery{Kind: QueryByCoordinates, Lat: lat, Lon: lon}
+}
+
+func PostcodeQuery(postcode string) LocationQuery {
+	return LocationQuery{Kind: QueryByPostcode, Postcode: strings.ToUpper(strings.TrimSpace(postcode))}
+}
+
+func AirportQuery(iata string) LocationQuery {
+	return LocationQuery{Kind: QueryByAirport, Airport: strings.ToUpper(strings.TrimSpace(iata))}
+}
+
+func IPQuery(ip string) LocationQuery {
+	return LocationQuery{Kind: QueryByIP, IP: strings.TrimSpace(ip)}
+}
+
+// WithLang returns the query asking for descriptions in lang. English is left
+// empty so it shares cache entries with queries that don't ask for a language.
+func (q LocationQuery) WithLang(lang string) LocationQuery {
+	if lang == "en" {
+		lang = ""
+	}
+	q.Lang = lang
+	return q
+}
+
+func (q LocationQuery) Validate() error {
+	switch q.Kind {
+	case QueryByCity:
+		if q.City == "" {
+			return ErrEmptyCity
+		}
+	case QueryByCoordinates:
+		if q.Lat < -90 || q.Lat > 90 || q.Lon < -180 || q.Lon > 180 {
+			return ErrCoordinatesOutOfRange
+		}
+	case QueryByPostcode:
+		if !postcodePattern.MatchString(q.Postcode) {
+			return ErrInvalidPostcode
+		}
+	case QueryByAirport:
+		if !airportCodePattern.MatchString(q.Airport) {
+			return ErrInvalidAirport
+		}
+	case QueryByIP:
+		if net.ParseIP(q.IP) == nil {
+			return ErrInvalidIP
+		}
+	default:
+		return fmt.Errorf("%w: unknown query kind", ErrInvalidLocationQuery)
+	}
+	return nil
+}
+
+// String is a stable, human readable form used in logs and cache keys.
+func (q LocationQuery) String() string {
+	if q.Lang != "" {
+		return fmt.Sprintf("%s;lang=%s", q.WithLang("").String(), q.Lang)
+	}
+	switch q.Kind {
+	case QueryByCoordinates:
+		return fmt.Sprintf("%s:%.4f,%.4f", q.Kind, q.Lat, q.Lon)
+	case QueryByPostcode:
+		return fmt.Sprintf("%s:%s", q.Kind, q.Postcode)
+	case QueryByAirport:
+		return fmt.Sprintf("%s:%s", q.Kind, q.Airport)
+	case QueryByIP:
+		return fmt.Sprintf("%s:%s", q.Kind, q.IP)
+	default:
+		return fmt.Sprintf("%s:%s", QueryByCity, strings.ToLower(q.City))
+	}
+}
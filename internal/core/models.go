@@ -1,27 +1,173 @@
 package core
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Weather struct {
-	Temperature float64 `json:"temperature"`
-	Humidity    float64 `json:"humidity"`
-	Description string  `json:"description"`
+	Temperature   float64     `json:"temperature"` // °C
+	Humidity      float64     `json:"humidity"`    // %
+	Description   string      `json:"description"` // provider's wording, in the requested language
+	Condition     Condition   `json:"condition"`
+	IsDay         bool        `json:"is_day"`
+	Icon          string      `json:"icon"`           // Condition.Icon(IsDay)
+	FeelsLike     float64     `json:"feels_like"`     // °C
+	WindSpeed     float64     `json:"wind_speed"`     // km/h
+	WindGust      float64     `json:"wind_gust"`      // km/h
+	WindDegree    int         `json:"wind_degree"`    // direction the wind blows from
+	WindDirection string      `json:"wind_direction"` // 16-point compass, e.g. "NNE"
+	Pressure      float64     `json:"pressure"`       // hPa
+	Precipitation float64     `json:"precipitation"`  // mm
+	CloudCover    int         `json:"cloud_cover"`    // %
+	UVIndex       float64     `json:"uv_index"`
+	Visibility    float64     `json:"visibility"` // km
+	ObservedAt    time.Time   `json:"observed_at"`
+	Location      Location    `json:"location"`
+	AirQuality    *AirQuality `json:"air_quality,omitempty"`
 }
 
+// Location is a place as resolved by the weather provider.
+type Location struct {
+	ID       string  `json:"id,omitempty"` // provider-prefixed, e.g. "weatherapi:2801268"
+	Name     string  `json:"name"`
+	Region   string  `json:"region,omitempty"`
+	Country  string  `json:"country,omitempty"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Timezone string  `json:"timezone,omitempty"` // IANA name, e.g. "Europe/Kyiv"
+}
+
+// Coordinates returns "lat,lon" with four decimals (about 10 m).
+func (l Location) Coordinates() string {
+	return fmt.Sprintf("%.4f,%.4f", l.Lat, l.Lon)
+}
+
+type Forecast struct {
+	City string        `json:"city"`
+	Days []ForecastDay `json:"days"`
+}
+
+type ForecastDay struct {
+	Date           string         `json:"date"` // YYYY-MM-DD, local to the city
+	MaxTemperature float64        `json:"max_temperature"`
+	MinTemperature float64        `json:"min_temperature"`
+	AvgHumidity    float64        `json:"avg_humidity"`
+	TotalPrecip    float64        `json:"total_precip"`   // mm
+	MaxWindSpeed   float64        `json:"max_wind_speed"` // km/h
+	ChanceOfRain   int            `json:"chance_of_rain"`
+	ChanceOfSnow   int            `json:"chance_of_snow"`
+	Description    string         `json:"description"`
+	Condition      Condition      `json:"condition"`
+	Icon           string         `json:"icon"` // daytime variant
+	Hours          []ForecastHour `json:"hours,omitempty"`
+}
+
+type ForecastHour struct {
+	Time         time.Time `json:"time"`
+	Temperature  float64   `json:"temperature"`
+	Humidity     float64   `json:"humidity"`
+	ChanceOfRain int       `json:"chance_of_rain"`
+	Description  string    `json:"description"`
+	Condition    Condition `json:"condition"`
+	IsDay        bool      `json:"is_day"`
+	Icon         string    `json:"icon"`
+}
+
+// Subscriber is an email address. It owns any number of subscriptions, is
+// confirmed once for all of them, and holds the preferences they share.
+type Subscriber struct {
+	ID                    string     `db:"id" json:"id"` //UUID
+	Email                 string     `db:"email" json:"email"`
+	Units                 string     `db:"units" json:"units"`       // UnitSystem.String()
+	Language              string     `db:"language" json:"language"` // for emails and weather descriptions, e.g. "uk"
+	ConfirmationToken     *string    `db:"confirmation_token" json:"-"`
+	ConfirmationExpiresAt *time.Time `db:"confirmation_expires_at" json:"-"` // when ConfirmationToken stops working
+	ConfirmationSentAt    *time.Time `db:"confirmation_sent_at" json:"-"`    // when the last confirmation email went out
+	IsConfirmed           bool       `db:"is_confirmed" json:"confirmed"`
+	CreatedAt             time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt             time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// ConfirmationExpired reports whether the subscriber's confirmation link no
+// longer works at now. Links without an expiry never expire.
+func (s Subscriber) ConfirmationExpired(now time.Time) bool {
+	return s.ConfirmationExpiresAt != nil && !now.Before(*s.ConfirmationExpiresAt)
+}
+
+// Subscription is one subscriber's updates for one location. Email, Units,
+// Language and IsConfirmed are read from the subscriber.
 type Subscription struct {
-	ID                string    `db:"id" json:"id"` //UUID
-	Email             string    `db:"email" json:"email"`
-	City              string    `db:"city" json:"city"`
-	Frequency         string    `db:"frequency" json:"frequency"`
-	ConfirmationToken *string   `db:"confirmation_token" json:"-"`
-	IsConfirmed       bool      `db:"is_confirmed" json:"confirmed"`
-	UnsubscribeToken  string    `db:"unsubscribe_token" json:"-"`
-	CreatedAt         time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+	ID               string     `db:"id" json:"id"` //UUID
+	SubscriberID     string     `db:"subscriber_id" json:"subscriber_id"`
+	Email            string     `db:"email" json:"email"`
+	City             string     `db:"city" json:"city"` // canonical location name
+	LocationID       string     `db:"location_id" json:"location_id"`
+	Country          string     `db:"country" json:"country"`
+	Lat              float64    `db:"lat" json:"lat"`
+	Lon              float64    `db:"lon" json:"lon"`
+	Timezone         string     `db:"timezone" json:"timezone"`
+	Frequency        string     `db:"frequency" json:"frequency"`                   // Frequency.String(), e.g. "weekly_monday"
+	Units            string     `db:"units" json:"units"`                           // UnitSystem.String()
+	AQIThreshold     *int       `db:"aqi_threshold" json:"aqi_threshold,omitempty"` // US EPA index that triggers an alert
+	AQIAlertActive   bool       `db:"aqi_alert_active" json:"-"`
+	Language         string     `db:"language" json:"language"`                   // for emails and weather descriptions, e.g. "uk"
+	IncludeAstronomy bool       `db:"include_astronomy" json:"include_astronomy"` // sun and moon in daily updates
+	DeliveryTime     string     `db:"delivery_time" json:"delivery_time"`         // local "HH:MM" for daily updates
+	DeliveryTimezone string     `db:"delivery_timezone" json:"delivery_timezone"` // IANA name DeliveryTime is in
+	NextDueAt        *time.Time `db:"next_due_at" json:"next_due_at,omitempty"`   // nil without routine updates or until confirmed
+	LastSentAt       *time.Time `db:"last_sent_at" json:"last_sent_at,omitempty"`
+	IsConfirmed      bool       `db:"is_confirmed" json:"confirmed"`
+	IsPaused         bool       `db:"is_paused" json:"paused"`             // no updates, alerts or rule notifications while set
+	PendingToken     *string    `db:"pending_confirmation_token" json:"-"` // a city still to be confirmed on its own link, see migration 000014
+	UnsubscribeToken string     `db:"unsubscribe_token" json:"-"`
+	ManageToken      string     `db:"manage_token" json:"-"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
 }
 
+// WeatherQuery is what the scheduler asks the provider for. Subscriptions made
+// before locations were resolved only have the city name the user typed.
+func (s Subscription) WeatherQuery() LocationQuery {
+	if s.LocationID == "" {
+		return CityQuery(s.City).WithLang(s.Language)
+	}
+	return CoordinatesQuery(s.Lat, s.Lon).WithLang(s.Language)
+}
+
+// SubscriptionRequest subscribes an email to updates for a city. Units and
+// Language are the subscriber's preferences: they are set when the email
+// subscribes for the first time and must match them afterwards.
 type SubscriptionRequest struct {
 	Email     string `form:"email" json:"email"`
 	City      string `form:"city" json:"city"`
 	Frequency string `form:"frequency" json:"frequency"`
+	// Weekday picks the day for a plain "weekly" Frequency, e.g. "monday"
+	Weekday string `form:"weekday" json:"weekday"`
+	Units   string `form:"units" json:"units"`
+	// AQIThreshold opts into air quality alerts at this US EPA index (1-6)
+	AQIThreshold *int `form:"aqi_threshold" json:"aqi_threshold,omitempty"`
+	// Language for the subscription's emails, e.g. "uk"; English if empty
+	Language string `form:"lang" json:"lang"`
+	// IncludeAstronomy adds sunrise, sunset and the moon to daily updates
+	IncludeAstronomy bool `form:"astronomy" json:"astronomy"`
+	// DeliveryTime is when daily updates go out, "HH:MM" local to Timezone;
+	// DefaultDeliveryTime if empty
+	DeliveryTime string `form:"delivery_time" json:"delivery_time"`
+	// Timezone is an IANA name such as "America/New_York"; the city's
+	// timezone if empty
+	Timezone string `form:"timezone" json:"timezone"`
+}
+
+// SubscriptionUpdate changes a subscription from its manage page. Nil fields
+// are left as they are. Units is the subscriber's preference, so it changes
+// all of the email's subscriptions.
+type SubscriptionUpdate struct {
+	City      *string `json:"city,omitempty"`
+	Frequency *string `json:"frequency,omitempty"`
+	// Weekday picks the day when the frequency is weekly, e.g. "friday"
+	Weekday      *string `json:"weekday,omitempty"`
+	DeliveryTime *string `json:"delivery_time,omitempty"`
+	Units        *string `json:"units,omitempty"`
+	Paused       *bool   `json:"paused,omitempty"`
 }
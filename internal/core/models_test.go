@@ -0,0 +1,18 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriber_ConfirmationExpired(t *testing.T) {
+	expiresAt := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	subscriber := Subscriber{ConfirmationExpiresAt: &expiresAt}
+
+	assert.False(t, subscriber.ConfirmationExpired(expiresAt.Add(-time.Second)))
+	assert.True(t, subscriber.ConfirmationExpired(expiresAt))
+	assert.True(t, subscriber.ConfirmationExpired(expiresAt.Add(time.Hour)))
+	assert.False(t, Subscriber{}.ConfirmationExpired(expiresAt), "links without an expiry never expire")
+}
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid notification rule")
+
+// RuleField is the measurement a rule comparison looks at.
+type RuleField string
+
+const (
+	RuleTemperature   RuleField = "temperature"   // in the rule's units
+	RulePrecipitation RuleField = "precipitation" // in the rule's units
+	RuleWindSpeed     RuleField = "wind_speed"    // in the rule's units
+	RuleCondition     RuleField = "condition"     // a Condition name
+)
+
+// RuleOperator compares a field with a rule's value. lt, lte, gt and gte
+// apply to numeric fields, is and is_not to the condition.
+type RuleOperator string
+
+const (
+	RuleLess           RuleOperator = "lt"
+	RuleLessOrEqual    RuleOperator = "lte"
+	RuleGreater        RuleOperator = "gt"
+	RuleGreaterOrEqual RuleOperator = "gte"
+	RuleIs             RuleOperator = "is"
+	RuleIsNot          RuleOperator = "is_not"
+)
+
+// RulePeriod is the weather a rule is evaluated against.
+type RulePeriod string
+
+const (
+	RuleNow      RulePeriod = "now"      // current conditions
+	RuleToday    RulePeriod = "today"    // the forecast for the location's current day
+	RuleTomorrow RulePeriod = "tomorrow" // the forecast for the day after
+)
+
+const (
+	// MaxRuleComparisons and MaxRuleDepth keep rules small enough to evaluate
+	// on every scheduler run.
+	MaxRuleComparisons = 10
+	MaxRuleDepth       = 3
+
+	// a rule's cool-down is a whole number of hours in this range
+	DefaultRuleCooldownHours = 24
+	MinRuleCooldownHours     = 1
+	MaxRuleCooldownHours     = 7 * 24
+)
+
+// RuleExpr is a rule's expression: either a comparison of Field with Value,
+// or an AND (All) or OR (Any) of nested expressions.
+type RuleExpr struct {
+	All   []RuleExpr   `json:"all,omitempty"`
+	Any   []RuleExpr   `json:"any,omitempty"`
+	Field RuleField    `json:"field,omitempty"`
+	Op    RuleOperator `json:"op,omitempty"`
+	Value any          `json:"value,omitempty"` // a number, or a condition name for RuleCondition
+}
+
+// NotificationRule sends a notification when its expression matches the
+// weather for Period, and then not again for CooldownHours.
+type NotificationRule struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"-"`
+	Name           string     `json:"name,omitempty"`
+	Period         RulePeriod `json:"period"`
+	Units          string     `json:"units"` // UnitSystem.String() the values are in
+	Expression     RuleExpr   `json:"expression"`
+	CooldownHours  int        `json:"cooldown_hours"`
+	LastFiredAt    *time.Time `json:"last_fired_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// RuleRequest creates a notification rule. Values are in the subscription's
+// units; an empty Period means tomorrow and a nil CooldownHours the default.
+type RuleRequest struct {
+	Name          string   `json:"name"`
+	Period        string   `json:"period"`
+	Expression    RuleExpr `json:"expression"`
+	CooldownHours *int     `json:"cooldown_hours"`
+}
+
+// RuleInput is the weather a rule is evaluated against. A forecast day spans
+// a temperature range; current conditions have a single temperature.
+type RuleInput struct {
+	MinTemperature float64
+	MaxTemperature float64
+	Precipitation  float64
+	WindSpeed      float64
+	Condition      Condition
+}
+
+// RuleInputFromWeather describes current conditions in units u.
+func RuleInputFromWeather(w Weather, u UnitSystem) RuleInput {
+	w = w.ConvertTo(u)
+	return RuleInput{
+		MinTemperature: w.Temperature,
+		MaxTemperature: w.Temperature,
+		Precipitation:  w.Precipitation,
+		WindSpeed:      w.WindSpeed,
+		Condition:      w.Condition,
+	}
+}
+
+// RuleInputFromForecastDay describes a forecast day in units u.
+func RuleInputFromForecastDay(d ForecastDay, u UnitSystem) RuleInput {
+	d = Forecast{Days: []ForecastDay{d}}.ConvertTo(u).Days[0]
+	return RuleInput{
+		MinTemperature: d.MinTemperature,
+		MaxTemperature: d.MaxTemperature,
+		Precipitation:  d.TotalPrecip,
+		WindSpeed:      d.MaxWindSpeed,
+		Condition:      d.Condition,
+	}
+}
+
+// ParseRulePeriod reads "now", "today" or "tomorrow"; an empty string means
+// tomorrow.
+func ParseRulePeriod(s string) (RulePeriod, error) {
+	switch p := RulePeriod(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return RuleTomorrow, nil
+	case RuleNow, RuleToday, RuleTomorrow:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w: period must be now, today or tomorrow", ErrInvalidRule)
+}
+
+// Validate checks the expression's structure, fields, operators and values,
+// and normalizes condition names.
+func (e *RuleExpr) Validate() error {
+	comparisons := 0
+	return e.validate(1, &comparisons)
+}
+
+func (e *RuleExpr) validate(depth int, comparisons *int) error {
+	if depth > MaxRuleDepth {
+		return fmt.Errorf("%w: groups can be nested at most %d deep", ErrInvalidRule, MaxRuleDepth)
+	}
+
+	isGroup := len(e.All) > 0 || len(e.Any) > 0
+	if isGroup {
+		if (len(e.All) > 0 && len(e.Any) > 0) || e.Field != "" || e.Op != "" || e.Value != nil {
+			return fmt.Errorf("%w: an expression is either an all group, an any group or a comparison", ErrInvalidRule)
+		}
+		for i := range e.All {
+			if err := e.All[i].validate(depth+1, comparisons); err != nil {
+				return err
+			}
+		}
+		for i := range e.Any {
+			if err := e.Any[i].validate(depth+1, comparisons); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	*comparisons++
+	if *comparisons > MaxRuleComparisons {
+		return fmt.Errorf("%w: at most %d comparisons are allowed", ErrInvalidRule, MaxRuleComparisons)
+	}
+
+	switch e.Field {
+	case RuleTemperature, RulePrecipitation, RuleWindSpeed:
+		switch e.Op {
+		case RuleLess, RuleLessOrEqual, RuleGreater, RuleGreaterOrEqual:
+		default:
+			return fmt.Errorf("%w: %s needs one of lt, lte, gt or gte", ErrInvalidRule, e.Field)
+		}
+		if _, ok := e.Value.(float64); !ok {
+			return fmt.Errorf("%w: %s needs a number value", ErrInvalidRule, e.Field)
+		}
+	case RuleCondition:
+		if e.Op != RuleIs && e.Op != RuleIsNot {
+			return fmt.Errorf("%w: condition needs is or is_not", ErrInvalidRule)
+		}
+		name, _ := e.Value.(string)
+		condition, err := ParseCondition(name)
+		if err != nil {
+			return fmt.Errorf("%w: unknown condition %v", ErrInvalidRule, e.Value)
+		}
+		e.Value = string(condition)
+	default:
+		return fmt.Errorf("%w: field must be temperature, precipitation, wind_speed or condition", ErrInvalidRule)
+	}
+	return nil
+}
+
+// Matches evaluates a validated expression against in. Temperature
+// thresholds match if any part of the temperature range crosses them, so
+// "lt 0" on a forecast day means the low drops below freezing.
+func (e RuleExpr) Matches(in RuleInput) bool {
+	if len(e.All) > 0 {
+		for _, sub := range e.All {
+			if !sub.Matches(in) {
+				return false
+			}
+		}
+		return true
+	}
+	if len(e.Any) > 0 {
+		for _, sub := range e.Any {
+			if sub.Matches(in) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if e.Field == RuleCondition {
+		name, _ := e.Value.(string)
+		return (in.Condition == Condition(name)) == (e.Op == RuleIs)
+	}
+
+	value, _ := e.Value.(float64)
+	var actual float64
+	switch e.Field {
+	case RuleTemperature:
+		actual = in.MaxTemperature
+		if e.Op == RuleLess || e.Op == RuleLessOrEqual {
+			actual = in.MinTemperature
+		}
+	case RulePrecipitation:
+		actual = in.Precipitation
+	case RuleWindSpeed:
+		actual = in.WindSpeed
+	default:
+		return false
+	}
+
+	switch e.Op {
+	case RuleLess:
+		return actual < value
+	case RuleLessOrEqual:
+		return actual <= value
+	case RuleGreater:
+		return actual > value
+	case RuleGreaterOrEqual:
+		return actual >= value
+	}
+	return false
+}
+
+// String renders the expression for emails, e.g.
+// "temperature < 0 and (condition is rain or precipitation > 1)".
+func (e RuleExpr) String() string {
+	if len(e.All) > 0 || len(e.Any) > 0 {
+		parts, join := e.All, " and "
+		if len(e.Any) > 0 {
+			parts, join = e.Any, " or "
+		}
+		rendered := make([]string, len(parts))
+		for i, sub := range parts {
+			rendered[i] = sub.String()
+			if len(sub.All)+len(sub.Any) > 1 {
+				rendered[i] = "(" + rendered[i] + ")"
+			}
+		}
+		return strings.Join(rendered, join)
+	}
+
+	op := map[RuleOperator]string{
+		RuleLess: "<", RuleLessOrEqual: "<=", RuleGreater: ">", RuleGreaterOrEqual: ">=", RuleIs: "is", RuleIsNot: "is not",
+	}[e.Op]
+	value := fmt.Sprint(e.Value)
+	if number, ok := e.Value.(float64); ok {
+		value = strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%s %s %s", e.Field, op, value)
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseRule(t *testing.T, s string) RuleExpr {
+	t.Helper()
+	var e RuleExpr
+	require.NoError(t, json.Unmarshal([]byte(s), &e))
+	return e
+}
+
+func TestRuleExpr_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{name: "comparison", rule: `{"field":"temperature","op":"lt","value":0}`},
+		{name: "nested groups", rule: `{"any":[{"field":"precipitation","op":"gt","value":1},
+			{"all":[{"field":"condition","op":"is","value":"Heavy Rain"},{"field":"wind_speed","op":"gte","value":40}]}]}`},
+		{name: "empty", rule: `{}`, wantErr: true},
+		{name: "unknown field", rule: `{"field":"humidity","op":"gt","value":80}`, wantErr: true},
+		{name: "numeric op on condition", rule: `{"field":"condition","op":"gt","value":"rain"}`, wantErr: true},
+		{name: "unknown condition", rule: `{"field":"condition","op":"is","value":"hail"}`, wantErr: true},
+		{name: "text value for temperature", rule: `{"field":"temperature","op":"lt","value":"0"}`, wantErr: true},
+		{name: "missing value", rule: `{"field":"temperature","op":"lt"}`, wantErr: true},
+		{name: "group and comparison", rule: `{"all":[{"field":"temperature","op":"lt","value":0}],"field":"temperature"}`, wantErr: true},
+		{name: "all and any", rule: `{"all":[{"field":"temperature","op":"lt","value":0}],"any":[{"field":"temperature","op":"gt","value":30}]}`, wantErr: true},
+		{name: "too deep", rule: `{"all":[{"any":[{"all":[{"field":"temperature","op":"lt","value":0}]}]}]}`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := parseRule(t, tc.rule)
+			err := e.Validate()
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("too many comparisons", func(t *testing.T) {
+		e := RuleExpr{}
+		for i := 0; i <= MaxRuleComparisons; i++ {
+			e.Any = append(e.Any, RuleExpr{Field: RuleTemperature, Op: RuleLess, Value: float64(i)})
+		}
+		assert.ErrorIs(t, e.Validate(), ErrInvalidRule)
+	})
+
+	t.Run("condition names are normalized", func(t *testing.T) {
+		e := parseRule(t, `{"field":"condition","op":"is","value":"Heavy Rain"}`)
+		require.NoError(t, e.Validate())
+		assert.Equal(t, "heavy_rain", e.Value)
+	})
+}
+
+func TestRuleExpr_Matches(t *testing.T) {
+	rainTomorrow := parseRule(t, `{"any":[{"field":"precipitation","op":"gt","value":0},{"field":"condition","op":"is","value":"rain"}]}`)
+	frost := parseRule(t, `{"field":"temperature","op":"lt","value":0}`)
+	heat := parseRule(t, `{"field":"temperature","op":"gte","value":30}`)
+	windyAndDry := parseRule(t, `{"all":[{"field":"wind_speed","op":"gt","value":40},{"field":"condition","op":"is_not","value":"rain"}]}`)
+	for _, e := range []*RuleExpr{&rainTomorrow, &frost, &heat, &windyAndDry} {
+		require.NoError(t, e.Validate())
+	}
+
+	tests := []struct {
+		name     string
+		rule     RuleExpr
+		input    RuleInput
+		expected bool
+	}{
+		{name: "rain by amount", rule: rainTomorrow, input: RuleInput{Precipitation: 2.5, Condition: ConditionCloudy}, expected: true},
+		{name: "rain by condition", rule: rainTomorrow, input: RuleInput{Condition: ConditionRain}, expected: true},
+		{name: "dry", rule: rainTomorrow, input: RuleInput{Condition: ConditionClear}, expected: false},
+		{name: "low below freezing", rule: frost, input: RuleInput{MinTemperature: -2, MaxTemperature: 5}, expected: true},
+		{name: "low above freezing", rule: frost, input: RuleInput{MinTemperature: 1, MaxTemperature: 5}, expected: false},
+		{name: "high reaches threshold", rule: heat, input: RuleInput{MinTemperature: 18, MaxTemperature: 30}, expected: true},
+		{name: "windy and dry", rule: windyAndDry, input: RuleInput{WindSpeed: 55, Condition: ConditionCloudy}, expected: true},
+		{name: "windy and wet", rule: windyAndDry, input: RuleInput{WindSpeed: 55, Condition: ConditionRain}, expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.rule.Matches(tc.input))
+		})
+	}
+}
+
+func TestRuleExpr_String(t *testing.T) {
+	e := parseRule(t, `{"all":[{"field":"temperature","op":"lt","value":-0.5},
+		{"any":[{"field":"condition","op":"is","value":"snow"},{"field":"precipitation","op":"gte","value":1}]}]}`)
+	assert.Equal(t, "temperature < -0.5 and (condition is snow or precipitation >= 1)", e.String())
+}
+
+func TestRuleInputs_UseTheRuleUnits(t *testing.T) {
+	day := ForecastDay{MinTemperature: -5, MaxTemperature: 0, TotalPrecip: 25.4, MaxWindSpeed: 16.09344, Condition: ConditionSnow}
+	in := RuleInputFromForecastDay(day, ImperialUnits)
+	assert.InDelta(t, 23, in.MinTemperature, 0.001)
+	assert.InDelta(t, 32, in.MaxTemperature, 0.001)
+	assert.InDelta(t, 1, in.Precipitation, 0.001)
+	assert.InDelta(t, 10, in.WindSpeed, 0.001)
+	assert.Equal(t, ConditionSnow, in.Condition)
+
+	in = RuleInputFromWeather(Weather{Temperature: 12, WindSpeed: 20, Condition: ConditionRain}, MetricUnits)
+	assert.Equal(t, RuleInput{MinTemperature: 12, MaxTemperature: 12, WindSpeed: 20, Condition: ConditionRain}, in)
+}
+
+func TestParseRulePeriod(t *testing.T) {
+	period, err := ParseRulePeriod("")
+	require.NoError(t, err)
+	assert.Equal(t, RuleTomorrow, period)
+	period, err = ParseRulePeriod("Now")
+	require.NoError(t, err)
+	assert.Equal(t, RuleNow, period)
+	_, err = ParseRulePeriod("next week")
+	assert.ErrorIs(t, err, ErrInvalidRule)
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidUnits = errors.New("invalid units")
+
+// UnitSystem holds the unit chosen for each kind of measurement. Weather data
+// is always fetched in metric and converted on the way out.
+type UnitSystem struct {
+	Temperature   string `json:"temperature"`   // "C" or "F"
+	Speed         string `json:"speed"`         // "kph", "mph", "ms" or "kn"
+	Pressure      string `json:"pressure"`      // "hPa", "inHg" or "mmHg"
+	Distance      string `json:"distance"`      // "km" or "mi"
+	Precipitation string `json:"precipitation"` // "mm" or "in"
+}
+
+var (
+	MetricUnits   = UnitSystem{Temperature: "C", Speed: "kph", Pressure: "hPa", Distance: "km", Precipitation: "mm"}
+	ImperialUnits = UnitSystem{Temperature: "F", Speed: "mph", Pressure: "inHg", Distance: "mi", Precipitation: "in"}
+)
+
+// allowed units per measurement, keyed by lower-case spelling
+var unitChoices = map[string]map[string]string{
+	"temperature":   {"c": "C", "f": "F"},
+	"speed":         {"kph": "kph", "mph": "mph", "ms": "ms", "kn": "kn"},
+	"pressure":      {"hpa": "hPa", "inhg": "inHg", "mmhg": "mmHg"},
+	"distance":      {"km": "km", "mi": "mi"},
+	"precipitation": {"mm": "mm", "in": "in"},
+}
+
+// ParseUnits accepts "metric", "imperial" or a custom list such as
+// "temperature=F,speed=ms". Measurements left out of a custom list stay metric.
+// An empty string means metric.
+func ParseUnits(s string) (UnitSystem, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	switch s {
+	case "", "metric":
+		return MetricUnits, nil
+	case "imperial":
+		return ImperialUnits, nil
+	}
+
+	u := MetricUnits
+	for _, part := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return UnitSystem{}, fmt.Errorf("%w: %q is not of the form measurement=unit", ErrInvalidUnits, part)
+		}
+		choices, ok := unitChoices[strings.TrimSpace(key)]
+		if !ok {
+			return UnitSystem{}, fmt.Errorf("%w: unknown measurement %q", ErrInvalidUnits, key)
+		}
+		canonical, ok := choices[strings.TrimSpace(value)]
+		if !ok {
+			return UnitSystem{}, fmt.Errorf("%w: unknown %s unit %q", ErrInvalidUnits, key, value)
+		}
+		switch strings.TrimSpace(key) {
+		case "temperature":
+			u.Temperature = canonical
+		case "speed":
+			u.Speed = canonical
+		case "pressure":
+			u.Pressure = canonical
+		case "distance":
+			u.Distance = canonical
+		case "precipitation":
+			u.Precipitation = canonical
+		}
+	}
+	return u, nil
+}
+
+// String returns the canonical form accepted by ParseUnits, as stored in the database.
+func (u UnitSystem) String() string {
+	switch u {
+	case MetricUnits:
+		return "metric"
+	case ImperialUnits:
+		return "imperial"
+	}
+	return fmt.Sprintf("temperature=%s,speed=%s,pressure=%s,distance=%s,precipitation=%s",
+		u.Temperature, u.Speed, u.Pressure, u.Distance, u.Precipitation)
+}
+
+func (u UnitSystem) ConvertTemperature(celsius float64) float64 {
+	if u.Temperature == "F" {
+		return celsius*9/5 + 32
+	}
+	return celsius
+}
+
+func (u UnitSystem) ConvertSpeed(kph float64) float64 {
+	switch u.Speed {
+	case "mph":
+		return kph / 1.609344
+	case "ms":
+		return kph / 3.6
+	case "kn":
+		return kph / 1.852
+	default:
+		return kph
+	}
+}
+
+func (u UnitSystem) ConvertPressure(hPa float64) float64 {
+	switch u.Pressure {
+	case "inHg":
+		return hPa * 0.02953
+	case "mmHg":
+		return hPa * 0.750062
+	default:
+		return hPa
+	}
+}
+
+func (u UnitSystem) ConvertDistance(km float64) float64 {
+	if u.Distance == "mi" {
+		return km / 1.609344
+	}
+	return km
+}
+
+func (u UnitSystem) ConvertPrecipitation(mm float64) float64 {
+	if u.Precipitation == "in" {
+		return mm / 25.4
+	}
+	return mm
+}
+
+func (u UnitSystem) FormatTemperature(celsius float64) string {
+	return fmt.Sprintf("%.1f°%s", u.ConvertTemperature(celsius), u.Temperature)
+}
+
+func (u UnitSystem) FormatSpeed(kph float64) string {
+	label := map[string]string{"kph": "km/h", "mph": "mph", "ms": "m/s", "kn": "kn"}[u.Speed]
+	return fmt.Sprintf("%.0f %s", u.ConvertSpeed(kph), label)
+}
+
+func (u UnitSystem) FormatPressure(hPa float64) string {
+	if u.Pressure == "inHg" {
+		return fmt.Sprintf("%.2f inHg", u.ConvertPressure(hPa))
+	}
+	return fmt.Sprintf("%.0f %s", u.ConvertPressure(hPa), u.Pressure)
+}
+
+func (u UnitSystem) FormatDistance(km float64) string {
+	return fmt.Sprintf("%.0f %s", u.ConvertDistance(km), u.Distance)
+}
+
+func (u UnitSystem) FormatPrecipitation(mm float64) string {
+	if u.Precipitation == "in" {
+		return fmt.Sprintf("%.2f in", u.ConvertPrecipitation(mm))
+	}
+	return fmt.Sprintf("%.1f mm", u.ConvertPrecipitation(mm))
+}
+
+// ConvertTo returns a copy of w with every measurement expressed in u.
+func (w Weather) ConvertTo(u UnitSystem) Weather {
+	w.Temperature = u.ConvertTemperature(w.Temperature)
+	w.FeelsLike = u.ConvertTemperature(w.FeelsLike)
+	w.WindSpeed = u.ConvertSpeed(w.WindSpeed)
+	w.WindGust = u.ConvertSpeed(w.WindGust)
+	w.Pressure = u.ConvertPressure(w.Pressure)
+	w.Precipitation = u.ConvertPrecipitation(w.Precipitation)
+	w.Visibility = u.ConvertDistance(w.Visibility)
+	return w
+}
+
+// ConvertTo returns a copy of f with every measurement expressed in u.
+func (f Forecast) ConvertTo(u UnitSystem) Forecast {
+	days := make([]ForecastDay, len(f.Days))
+	for i, d := range f.Days {
+		d.MaxTemperature = u.ConvertTemperature(d.MaxTemperature)
+		d.MinTemperature = u.ConvertTemperature(d.MinTemperature)
+		d.TotalPrecip = u.ConvertPrecipitation(d.TotalPrecip)
+		d.MaxWindSpeed = u.ConvertSpeed(d.MaxWindSpeed)
+		hours := make([]ForecastHour, len(d.Hours))
+		for j, h := range d.Hours {
+			h.Temperature = u.ConvertTemperature(h.Temperature)
+			hours[j] = h
+		}
+		if d.Hours == nil {
+			hours = nil
+		}
+		d.Hours = hours
+		days[i] = d
+	}
+	f.Days = days
+	return f
+}
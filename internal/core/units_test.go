@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  UnitSystem
+		canonical string
+		wantErr   bool
+	}{
+		{input: "", expected: MetricUnits, canonical: "metric"},
+		{input: "Metric", expected: MetricUnits, canonical: "metric"},
+		{input: "imperial", expected: ImperialUnits, canonical: "imperial"},
+		{
+			input:     "temperature=F, speed=ms",
+			expected:  UnitSystem{Temperature: "F", Speed: "ms", Pressure: "hPa", Distance: "km", Precipitation: "mm"},
+			canonical: "temperature=F,speed=ms,pressure=hPa,distance=km,precipitation=mm",
+		},
+		{
+			input:     "temperature=f,speed=mph,pressure=inhg,distance=mi,precipitation=in",
+			expected:  ImperialUnits,
+			canonical: "imperial",
+		},
+		{input: "kelvin", wantErr: true},
+		{input: "temperature=K", wantErr: true},
+		{input: "altitude=ft", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			u, err := ParseUnits(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidUnits)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, u)
+			assert.Equal(t, tc.canonical, u.String())
+
+			roundTrip, err := ParseUnits(u.String())
+			require.NoError(t, err)
+			assert.Equal(t, u, roundTrip)
+		})
+	}
+}
+
+func TestUnitSystem_Formatting(t *testing.T) {
+	assert.Equal(t, "15.5°C", MetricUnits.FormatTemperature(15.5))
+	assert.Equal(t, "59.9°F", ImperialUnits.FormatTemperature(15.5))
+	assert.Equal(t, "10 mph", ImperialUnits.FormatSpeed(16.09344))
+	assert.Equal(t, "5 m/s", UnitSystem{Speed: "ms"}.FormatSpeed(18))
+	assert.Equal(t, "1013 hPa", MetricUnits.FormatPressure(1013))
+	assert.Equal(t, "29.91 inHg", ImperialUnits.FormatPressure(1013))
+	assert.Equal(t, "0.50 in", ImperialUnits.FormatPrecipitation(12.7))
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// WeatherAlert is a severe weather warning issued by a national weather service.
+type WeatherAlert struct {
+	// ID stays the same when the issuer updates an alert, so an update can be
+	// told apart from a new alert. See Fingerprint for detecting changes.
+	ID          string    `json:"id"`
+	Event       string    `json:"event"`
+	Headline    string    `json:"headline"`
+	Severity    string    `json:"severity"`
+	Urgency     string    `json:"urgency"`
+	Certainty   string    `json:"certainty"`
+	Areas       string    `json:"areas"`
+	Description string    `json:"description"`
+	Instruction string    `json:"instruction"`
+	Effective   time.Time `json:"effective"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Fingerprint hashes the alert's content; it changes whenever the issuer
+// revises the alert.
+func (a WeatherAlert) Fingerprint() string {
+	h := sha256.New()
+	for _, field := range []string{
+		a.Event, a.Headline, a.Severity, a.Urgency, a.Certainty, a.Areas, a.Description, a.Instruction,
+		a.Effective.UTC().Format(time.RFC3339), a.Expires.UTC().Format(time.RFC3339),
+	} {
+		h.Write([]byte(strings.TrimSpace(field)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,90 @@
+// Package i18n holds the message catalog for user-facing text in API
+// responses and emails.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	English   = "en"
+	Ukrainian = "uk"
+
+	// Default is used when no language is asked for, and for messages
+	// missing from a catalog.
+	Default = English
+)
+
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// Message identifies a catalog entry. Entries are fmt format strings.
+type Message string
+
+// Supported lists the languages with a catalog, sorted.
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Parse normalizes a language tag such as "uk", "UK" or "uk-UA" to a supported
+// language. An empty tag is Default.
+func Parse(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return Default, nil
+	}
+	base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	base, _, _ = strings.Cut(base, "_")
+	if _, ok := catalogs[base]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedLanguage, tag)
+	}
+	return base, nil
+}
+
+// FromAcceptLanguage picks the supported language the client prefers most,
+// honouring q-values, or Default if there is none.
+func FromAcceptLanguage(header string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang, err := Parse(tag)
+		if tag == "" || tag == "*" || err != nil {
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// T formats msg in lang, falling back to Default and then to the message ID.
+func T(lang string, msg Message, args ...interface{}) string {
+	format, ok := catalogs[lang][msg]
+	if !ok {
+		format, ok = catalogs[Default][msg]
+	}
+	if !ok {
+		format = string(msg)
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var formatVerb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for msg, english := range catalogs[English] {
+			translated, ok := catalog[msg]
+			if !assert.True(t, ok, "%s is missing %q", lang, msg) {
+				continue
+			}
+			assert.Equal(t, formatVerb.FindAllString(english, -1), formatVerb.FindAllString(translated, -1),
+				"%s %q must take the same arguments as English", lang, msg)
+		}
+		assert.Len(t, catalog, len(catalogs[English]), "%s has messages English doesn't", lang)
+	}
+}
+
+func TestDomainValuesHaveNames(t *testing.T) {
+	for _, c := range append(core.Conditions(), core.ConditionUnknown) {
+		assert.Contains(t, catalogs[English], Condition(string(c)), "condition %q has no name", c)
+	}
+	for _, phase := range []core.MoonPhase{core.NewMoon, core.WaxingCrescent, core.FirstQuarter, core.WaxingGibbous,
+		core.FullMoon, core.WaningGibbous, core.LastQuarter, core.WaningCrescent} {
+		assert.Contains(t, catalogs[English], MoonPhase(string(phase)), "moon phase %q has no name", phase)
+	}
+	for _, period := range []core.RulePeriod{core.RuleNow, core.RuleToday, core.RuleTomorrow} {
+		assert.Contains(t, catalogs[English], RulePeriod(string(period)), "rule period %q has no name", period)
+	}
+	assert.Equal(t, "Мінлива хмарність", T(Ukrainian, Condition("partly_cloudy")))
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		tag      string
+		expected string
+		wantErr  bool
+	}{
+		{tag: "", expected: English},
+		{tag: "en", expected: English},
+		{tag: "UK", expected: Ukrainian},
+		{tag: "uk-UA", expected: Ukrainian},
+		{tag: "en_GB", expected: English},
+		{tag: "fr", wantErr: true},
+		{tag: "ukrainian", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.tag, func(t *testing.T) {
+			lang, err := Parse(tc.tag)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, lang)
+		})
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{header: "", expected: English},
+		{header: "uk-UA,uk;q=0.9,en;q=0.8", expected: Ukrainian},
+		{header: "fr-FR, uk;q=0.5, en;q=0.7", expected: English},
+		{header: "de, fr", expected: English},
+		{header: "*, uk;q=0.1", expected: Ukrainian},
+		{header: "uk;q=bogus", expected: English},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			assert.Equal(t, tc.expected, FromAcceptLanguage(tc.header))
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "City not found", T(English, MsgCityNotFound))
+	assert.Equal(t, "Місто не знайдено", T(Ukrainian, MsgCityNotFound))
+	assert.Equal(t, "days must be an integer between 1 and 14", T(English, MsgInvalidDays, 14))
+	assert.Equal(t, "City not found", T("fr", MsgCityNotFound), "unknown languages fall back to English")
+	assert.Equal(t, "no_such_message", T(Ukrainian, "no_such_message"))
+	assert.Equal(t, "Помірна", T(Ukrainian, USEPACategory(2)))
+	assert.Equal(t, "Unknown", T(English, USEPACategory(0)))
+}
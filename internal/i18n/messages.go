@@ -0,0 +1,383 @@
+package i18n
+
+import "fmt"
+
+// API responses
+const (
+	MsgNoLocation             Message = "no_location"
+	MsgConflictingLocation    Message = "conflicting_location"
+	MsgIncompleteCoordinates  Message = "incomplete_coordinates"
+	MsgInvalidCoordinates     Message = "invalid_coordinates"
+	MsgEmptyCity              Message = "empty_city"
+	MsgCoordinatesOutOfRange  Message = "coordinates_out_of_range"
+	MsgInvalidPostcode        Message = "invalid_postcode"
+	MsgInvalidAirport         Message = "invalid_airport"
+	MsgInvalidIP              Message = "invalid_ip"
+	MsgInvalidUnits           Message = "invalid_units"
+	MsgInvalidAQIParam        Message = "invalid_aqi_param"
+	MsgInvalidLanguage        Message = "invalid_language"
+	MsgCityNotFound           Message = "city_not_found"
+	MsgLookupNotSupported     Message = "lookup_not_supported"
+	MsgProviderTimeout        Message = "provider_timeout"
+	MsgWeatherFetchFailed     Message = "weather_fetch_failed"
+	MsgForecastFetchFailed    Message = "forecast_fetch_failed"
+	MsgCitySearchFailed       Message = "city_search_failed"
+	MsgUnexpectedError        Message = "unexpected_error"
+	MsgRateLimited            Message = "rate_limited"
+	MsgInvalidInterval        Message = "invalid_interval"
+	MsgInvalidFrom            Message = "invalid_from"
+	MsgInvalidTo              Message = "invalid_to"
+	MsgFromNotBeforeTo        Message = "from_not_before_to"
+	MsgHistorySpanTooLong     Message = "history_span_too_long"
+	MsgHistoryFailed          Message = "history_failed"
+	MsgInvalidDays            Message = "invalid_days"
+	MsgInvalidDate            Message = "invalid_date"
+	MsgInvalidAstronomyParam  Message = "invalid_astronomy_param"
+	MsgAstronomyFetchFailed   Message = "astronomy_fetch_failed"
+	MsgInvalidSearchQuery     Message = "invalid_search_query"
+	MsgInvalidLimit           Message = "invalid_limit"
+	MsgFormParseFailed        Message = "form_parse_failed"
+	MsgInvalidAQIThreshold    Message = "invalid_aqi_threshold"
+	MsgSubscribeFieldsMissing Message = "subscribe_fields_missing"
+	MsgInvalidFrequency       Message = "invalid_frequency"
+	MsgInvalidDeliveryTime    Message = "invalid_delivery_time"
+	MsgInvalidTimezone        Message = "invalid_timezone"
+	MsgAlreadySubscribed      Message = "already_subscribed"
+	MsgSubscriberSettings     Message = "subscriber_settings"
+	MsgCityUnverifiable       Message = "city_unverifiable"
+	MsgSubscribeFailed        Message = "subscribe_failed"
+	MsgSubscribed             Message = "subscribed"
+	MsgSubscriptionAdded      Message = "subscription_added"
+	MsgTokenRequired          Message = "token_required"
+	MsgInvalidOrExpiredToken  Message = "invalid_or_expired_token"
+	MsgAlreadyConfirmed       Message = "already_confirmed"
+	MsgConfirmFailed          Message = "confirm_failed"
+	MsgConfirmed              Message = "confirmed"
+	MsgConfirmationExpired    Message = "confirmation_expired"
+	MsgEmailRequired          Message = "email_required"
+	MsgConfirmationTooSoon    Message = "confirmation_too_soon"
+	MsgConfirmationResent     Message = "confirmation_resent"
+	MsgResendLimited          Message = "resend_limited"
+	MsgInvalidTokenFormat     Message = "invalid_token_format"
+	MsgTokenNotFound          Message = "token_not_found"
+	MsgUnsubscribeFailed      Message = "unsubscribe_failed"
+	MsgUnsubscribed           Message = "unsubscribed"
+	MsgInvalidRuleBody        Message = "invalid_rule_body"
+	MsgInvalidRule            Message = "invalid_rule"
+	MsgTooManyRules           Message = "too_many_rules"
+	MsgRuleNotFound           Message = "rule_not_found"
+	MsgRulesFailed            Message = "rules_failed"
+	MsgRuleDeleted            Message = "rule_deleted"
+	MsgInvalidUpdateBody      Message = "invalid_update_body"
+	MsgUpdateFailed           Message = "update_failed"
+)
+
+// Emails
+const (
+	MsgConfirmationSubject    Message = "confirmation_subject"
+	MsgConfirmationBody       Message = "confirmation_body"
+	MsgWeatherUpdateSubject   Message = "weather_update_subject"
+	MsgAirQualityAlertSubject Message = "air_quality_alert_subject"
+	MsgWeatherAlertSubject    Message = "weather_alert_subject"
+	MsgUnsubscribeLink        Message = "unsubscribe_link"
+	MsgManageLink             Message = "manage_link"
+	MsgDailyForecast          Message = "daily_forecast"
+	MsgCurrentWeather         Message = "current_weather"
+	MsgAirQualityAlert        Message = "air_quality_alert"
+	MsgWeatherAlertDetails    Message = "weather_alert_details"
+	MsgWeatherAlertFrom       Message = "weather_alert_from"
+	MsgWeatherAlertUntil      Message = "weather_alert_until"
+	MsgWeatherAlertUpdated    Message = "weather_alert_updated"
+	MsgRuleSubject            Message = "rule_subject"
+	MsgRuleMatched            Message = "rule_matched"
+	MsgRuleForecast           Message = "rule_forecast"
+	MsgUSEPAUnknown           Message = "us_epa_unknown"
+	MsgAstronomy              Message = "astronomy"
+	MsgMoonPhaseUnknown       Message = "moon_unknown"
+	MsgConditionUnknown       Message = "condition_unknown"
+)
+
+// USEPACategory is the message naming a US EPA index band, e.g. "Moderate" for 2.
+func USEPACategory(index int) Message {
+	if index < 1 || index > 6 {
+		return MsgUSEPAUnknown
+	}
+	return Message(fmt.Sprintf("us_epa_%d", index))
+}
+
+// Condition is the message naming a core.Condition, e.g. "Partly cloudy" for
+// "partly_cloudy".
+func Condition(condition string) Message {
+	if condition == "" {
+		return MsgConditionUnknown
+	}
+	return Message("condition_" + condition)
+}
+
+// RulePeriod is the message naming a core.RulePeriod as the weather a rule
+// matched, e.g. "tomorrow's forecast" for "tomorrow".
+func RulePeriod(period string) Message {
+	return Message("rule_period_" + period)
+}
+
+// MoonPhase is the message naming a core.MoonPhase, e.g. "Full moon" for
+// "full_moon".
+func MoonPhase(phase string) Message {
+	if phase == "" {
+		return MsgMoonPhaseUnknown
+	}
+	return Message("moon_" + phase)
+}
+
+var catalogs = map[string]map[Message]string{
+	English: {
+		MsgNoLocation:             "one of city, lat and lon, postcode, airport or ip query parameters is required",
+		MsgConflictingLocation:    "only one of city, lat and lon, postcode, airport or ip may be given",
+		MsgIncompleteCoordinates:  "lat and lon must be given together",
+		MsgInvalidCoordinates:     "lat and lon must be numbers",
+		MsgEmptyCity:              "city is empty",
+		MsgCoordinatesOutOfRange:  "lat must be within [-90, 90] and lon within [-180, 180]",
+		MsgInvalidPostcode:        "postcode must be 2-10 letters, digits, spaces or dashes",
+		MsgInvalidAirport:         "airport must be a 3-letter IATA code",
+		MsgInvalidIP:              "ip must be an IPv4 or IPv6 address",
+		MsgInvalidUnits:           "units must be 'metric', 'imperial' or a list like 'temperature=F,speed=ms'",
+		MsgInvalidAQIParam:        "aqi must be true or false",
+		MsgInvalidLanguage:        "lang must be one of %s",
+		MsgCityNotFound:           "City not found",
+		MsgLookupNotSupported:     "This kind of location lookup is not supported by the weather provider",
+		MsgProviderTimeout:        "Weather provider timed out",
+		MsgWeatherFetchFailed:     "Failed to fetch weather data from provider",
+		MsgForecastFetchFailed:    "Failed to fetch forecast data from provider",
+		MsgCitySearchFailed:       "Failed to search cities with provider",
+		MsgUnexpectedError:        "An unexpected error occurred",
+		MsgRateLimited:            "Weather API quota exhausted, please try again later",
+		MsgInvalidInterval:        "interval must be 'hour' or 'day'",
+		MsgInvalidFrom:            "from must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		MsgInvalidTo:              "to must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+		MsgFromNotBeforeTo:        "from must be before to",
+		MsgHistorySpanTooLong:     "%s history can cover at most %d days",
+		MsgHistoryFailed:          "Failed to load weather history",
+		MsgInvalidDays:            "days must be an integer between 1 and %d",
+		MsgInvalidDate:            "date must be a YYYY-MM-DD date",
+		MsgInvalidAstronomyParam:  "astronomy must be true or false",
+		MsgAstronomyFetchFailed:   "Failed to fetch astronomy data from provider",
+		MsgInvalidSearchQuery:     "q must be between %d and %d characters",
+		MsgInvalidLimit:           "limit must be an integer between 1 and %d",
+		MsgFormParseFailed:        "Failed to parse form data",
+		MsgInvalidAQIThreshold:    "aqi_threshold must be a US EPA index between 1 and 6",
+		MsgSubscribeFieldsMissing: "email, city, and frequency are required",
+		MsgInvalidFrequency:       "frequency must be hourly, every_Nh (N is 2, 3, 4, 6, 8 or 12), twice_daily, daily, weekdays, weekly with a weekday, or alerts",
+		MsgInvalidDeliveryTime:    "delivery_time must be a 24-hour HH:MM time",
+		MsgInvalidTimezone:        "timezone must be an IANA timezone such as Europe/Kyiv",
+		MsgAlreadySubscribed:      "Email already subscribed for this city",
+		MsgSubscriberSettings:     "This email's subscriptions use %s units and language %s; subscribe with the same units and language",
+		MsgCityUnverifiable:       "Could not verify the city right now, please try again later",
+		MsgSubscribeFailed:        "Failed to create subscription",
+		MsgSubscribed:             "Subscription successful. Confirmation email sent.",
+		MsgSubscriptionAdded:      "Subscription successful. Your email is already confirmed, so updates start right away.",
+		MsgTokenRequired:          "Token is required",
+		MsgInvalidOrExpiredToken:  "Invalid or expired token",
+		MsgAlreadyConfirmed:       "Subscription already confirmed.",
+		MsgConfirmFailed:          "Failed to confirm subscription",
+		MsgConfirmed:              "Subscription confirmed successfully",
+		MsgConfirmationExpired:    "Confirmation link has expired. Request a new one with POST /api/confirm/resend or by subscribing again",
+		MsgEmailRequired:          "email is required",
+		MsgConfirmationTooSoon:    "A confirmation email was sent to this address recently, please try again later",
+		MsgConfirmationResent:     "If this email has subscriptions waiting for confirmation, a new confirmation link is on its way. Earlier links no longer work.",
+		MsgResendLimited:          "Too many confirmation requests, please try again later",
+		MsgInvalidTokenFormat:     "Invalid token format",
+		MsgTokenNotFound:          "Token not found",
+		MsgUnsubscribeFailed:      "Failed to process unsubscription",
+		MsgUnsubscribed:           "Unsubscribed successfully",
+		MsgInvalidRuleBody:        "request body must be a JSON notification rule",
+		MsgInvalidRule:            "Invalid notification rule: %s",
+		MsgTooManyRules:           "a subscription can have at most %d notification rules",
+		MsgRuleNotFound:           "Notification rule not found",
+		MsgRulesFailed:            "Failed to process notification rules",
+		MsgRuleDeleted:            "Notification rule deleted",
+		MsgInvalidUpdateBody:      "Invalid subscription update: expected a JSON object with city, frequency, weekday, delivery_time, units or paused",
+		MsgUpdateFailed:           "Failed to update subscription",
+
+		MsgConfirmationSubject:    "Confirm your Weather Subscription for %s",
+		MsgConfirmationBody:       "Please confirm your subscription by clicking this link: %s",
+		MsgWeatherUpdateSubject:   "Your Weather Update for %s",
+		MsgAirQualityAlertSubject: "Air quality alert for %s",
+		MsgWeatherAlertSubject:    "Weather alert for %s: %s",
+		MsgUnsubscribeLink:        "Unsubscribe: %s",
+		MsgManageLink:             "Change or pause this subscription: %s",
+		MsgDailyForecast: "Today's forecast for %s:\nHigh: %s\nLow: %s\nChance of rain: %d%%\n" +
+			"Precipitation: %s\nDescription: %s",
+		MsgCurrentWeather: "Current weather in %s:\nTemperature: %s (feels like %s)\nHumidity: %.0f%%\nDescription: %s\n" +
+			"Wind: %s %s (gusts %s)\nPressure: %s\nPrecipitation: %s\nCloud cover: %d%%\n" +
+			"UV index: %.0f\nVisibility: %s\nObserved at: %s",
+		MsgAirQualityAlert: "Air quality in %s has reached US EPA index %d (%s), at or above your threshold of %d.\n" +
+			"PM2.5: %.1f μg/m³\nPM10: %.1f μg/m³\nO3: %.1f μg/m³\nNO2: %.1f μg/m³\nUK DEFRA index: %d",
+		MsgWeatherAlertDetails: "%s\nSeverity: %s\nUrgency: %s\nCertainty: %s\nAreas: %s",
+		MsgWeatherAlertFrom:    "From: %s",
+		MsgWeatherAlertUntil:   "Until: %s",
+		MsgWeatherAlertUpdated: "Updated: %s",
+		MsgRuleSubject:         "Weather notification for %s: %s",
+		MsgRuleMatched:         "Your notification rule \"%s\" matched %s in %s.",
+		MsgRuleForecast: "Forecast for %s on %s:\nHigh: %s\nLow: %s\nPrecipitation: %s (chance of rain %d%%)\n" +
+			"Max wind: %s\nDescription: %s",
+		"rule_period_now":         "the current weather",
+		"rule_period_today":       "today's forecast",
+		"rule_period_tomorrow":    "tomorrow's forecast",
+		"us_epa_1":                "Good",
+		"us_epa_2":                "Moderate",
+		"us_epa_3":                "Unhealthy for sensitive groups",
+		"us_epa_4":                "Unhealthy",
+		"us_epa_5":                "Very unhealthy",
+		"us_epa_6":                "Hazardous",
+		MsgUSEPAUnknown:           "Unknown",
+		MsgAstronomy:              "Sunrise: %s\nSunset: %s\nDay length: %dh %02dm\nMoon: %s (%d%% illuminated)",
+		"moon_new_moon":           "New moon",
+		"moon_waxing_crescent":    "Waxing crescent",
+		"moon_first_quarter":      "First quarter",
+		"moon_waxing_gibbous":     "Waxing gibbous",
+		"moon_full_moon":          "Full moon",
+		"moon_waning_gibbous":     "Waning gibbous",
+		"moon_last_quarter":       "Last quarter",
+		"moon_waning_crescent":    "Waning crescent",
+		MsgMoonPhaseUnknown:       "Unknown",
+		"condition_clear":         "Clear",
+		"condition_partly_cloudy": "Partly cloudy",
+		"condition_cloudy":        "Cloudy",
+		"condition_overcast":      "Overcast",
+		"condition_fog":           "Fog",
+		"condition_drizzle":       "Drizzle",
+		"condition_rain":          "Rain",
+		"condition_heavy_rain":    "Heavy rain",
+		"condition_freezing_rain": "Freezing rain",
+		"condition_sleet":         "Sleet",
+		"condition_snow":          "Snow",
+		"condition_heavy_snow":    "Heavy snow",
+		"condition_thunder":       "Thunderstorm",
+		MsgConditionUnknown:       "Unknown",
+	},
+	Ukrainian: {
+		MsgNoLocation:             "потрібен один із параметрів запиту city, lat і lon, postcode, airport або ip",
+		MsgConflictingLocation:    "можна вказати лише один із параметрів city, lat і lon, postcode, airport або ip",
+		MsgIncompleteCoordinates:  "lat і lon потрібно вказувати разом",
+		MsgInvalidCoordinates:     "lat і lon мають бути числами",
+		MsgEmptyCity:              "city порожній",
+		MsgCoordinatesOutOfRange:  "lat має бути в межах [-90, 90], а lon — у межах [-180, 180]",
+		MsgInvalidPostcode:        "postcode має складатися з 2–10 літер, цифр, пробілів або дефісів",
+		MsgInvalidAirport:         "airport має бути трилітерним кодом IATA",
+		MsgInvalidIP:              "ip має бути адресою IPv4 або IPv6",
+		MsgInvalidUnits:           "units має бути 'metric', 'imperial' або списком на кшталт 'temperature=F,speed=ms'",
+		MsgInvalidAQIParam:        "aqi має бути true або false",
+		MsgInvalidLanguage:        "lang має бути одним із: %s",
+		MsgCityNotFound:           "Місто не знайдено",
+		MsgLookupNotSupported:     "Постачальник погоди не підтримує такий спосіб пошуку місця",
+		MsgProviderTimeout:        "Постачальник погоди не відповів вчасно",
+		MsgWeatherFetchFailed:     "Не вдалося отримати дані про погоду від постачальника",
+		MsgForecastFetchFailed:    "Не вдалося отримати прогноз від постачальника",
+		MsgCitySearchFailed:       "Не вдалося виконати пошук міст у постачальника",
+		MsgUnexpectedError:        "Сталася неочікувана помилка",
+		MsgRateLimited:            "Ліміт запитів до API погоди вичерпано, спробуйте пізніше",
+		MsgInvalidInterval:        "interval має бути 'hour' або 'day'",
+		MsgInvalidFrom:            "from має бути міткою часу RFC 3339 або датою у форматі YYYY-MM-DD",
+		MsgInvalidTo:              "to має бути міткою часу RFC 3339 або датою у форматі YYYY-MM-DD",
+		MsgFromNotBeforeTo:        "from має бути раніше за to",
+		MsgHistorySpanTooLong:     "історія з інтервалом %s може охоплювати не більше ніж %d дн.",
+		MsgHistoryFailed:          "Не вдалося завантажити історію погоди",
+		MsgInvalidDays:            "days має бути цілим числом від 1 до %d",
+		MsgInvalidDate:            "date має бути датою у форматі YYYY-MM-DD",
+		MsgInvalidAstronomyParam:  "astronomy має бути true або false",
+		MsgAstronomyFetchFailed:   "Не вдалося отримати астрономічні дані від постачальника",
+		MsgInvalidSearchQuery:     "q має містити від %d до %d символів",
+		MsgInvalidLimit:           "limit має бути цілим числом від 1 до %d",
+		MsgFormParseFailed:        "Не вдалося розібрати дані форми",
+		MsgInvalidAQIThreshold:    "aqi_threshold має бути індексом US EPA від 1 до 6",
+		MsgSubscribeFieldsMissing: "email, city і frequency обов'язкові",
+		MsgInvalidFrequency:       "frequency має бути hourly, every_Nh (N — 2, 3, 4, 6, 8 або 12), twice_daily, daily, weekdays, weekly з днем тижня weekday або alerts",
+		MsgInvalidDeliveryTime:    "delivery_time має бути часом у 24-годинному форматі HH:MM",
+		MsgInvalidTimezone:        "timezone має бути часовим поясом IANA, наприклад Europe/Kyiv",
+		MsgAlreadySubscribed:      "Цю адресу вже підписано на це місто",
+		MsgSubscriberSettings:     "Підписки цієї адреси використовують одиниці %s і мову %s; підпишіться з тими самими одиницями й мовою",
+		MsgCityUnverifiable:       "Зараз не вдалося перевірити місто, спробуйте пізніше",
+		MsgSubscribeFailed:        "Не вдалося створити підписку",
+		MsgSubscribed:             "Підписку оформлено. Лист для підтвердження надіслано.",
+		MsgSubscriptionAdded:      "Підписку оформлено. Вашу адресу вже підтверджено, тож оновлення почнуть надходити одразу.",
+		MsgTokenRequired:          "Потрібен токен",
+		MsgInvalidOrExpiredToken:  "Недійсний або прострочений токен",
+		MsgAlreadyConfirmed:       "Підписку вже підтверджено.",
+		MsgConfirmFailed:          "Не вдалося підтвердити підписку",
+		MsgConfirmed:              "Підписку успішно підтверджено",
+		MsgConfirmationExpired:    "Термін дії посилання для підтвердження минув. Запросіть нове через POST /api/confirm/resend або підпишіться ще раз",
+		MsgEmailRequired:          "email обов'язковий",
+		MsgConfirmationTooSoon:    "Лист для підтвердження нещодавно надіслано на цю адресу, спробуйте пізніше",
+		MsgConfirmationResent:     "Якщо ця адреса має підписки, що очікують підтвердження, на неї надіслано нове посилання. Попередні посилання більше не діють.",
+		MsgResendLimited:          "Забагато запитів на підтвердження, спробуйте пізніше",
+		MsgInvalidTokenFormat:     "Неправильний формат токена",
+		MsgTokenNotFound:          "Токен не знайдено",
+		MsgUnsubscribeFailed:      "Не вдалося скасувати підписку",
+		MsgUnsubscribed:           "Підписку успішно скасовано",
+		MsgInvalidRuleBody:        "тіло запиту має бути правилом сповіщення у форматі JSON",
+		MsgInvalidRule:            "Некоректне правило сповіщення: %s",
+		MsgTooManyRules:           "підписка може мати не більше ніж %d правил сповіщень",
+		MsgRuleNotFound:           "Правило сповіщення не знайдено",
+		MsgRulesFailed:            "Не вдалося обробити правила сповіщень",
+		MsgRuleDeleted:            "Правило сповіщення видалено",
+		MsgInvalidUpdateBody:      "Некоректна зміна підписки: очікується JSON-об'єкт з city, frequency, weekday, delivery_time, units або paused",
+		MsgUpdateFailed:           "Не вдалося змінити підписку",
+
+		MsgConfirmationSubject:    "Підтвердьте підписку на погоду: %s",
+		MsgConfirmationBody:       "Будь ласка, підтвердьте підписку, перейшовши за посиланням: %s",
+		MsgWeatherUpdateSubject:   "Оновлення погоди: %s",
+		MsgAirQualityAlertSubject: "Попередження про якість повітря: %s",
+		MsgWeatherAlertSubject:    "Погодне попередження (%s): %s",
+		MsgUnsubscribeLink:        "Відписатися: %s",
+		MsgManageLink:             "Змінити або призупинити підписку: %s",
+		MsgDailyForecast: "Прогноз на сьогодні: %s\nМаксимум: %s\nМінімум: %s\nЙмовірність дощу: %d%%\n" +
+			"Опади: %s\nОпис: %s",
+		MsgCurrentWeather: "Поточна погода: %s\nТемпература: %s (відчувається як %s)\nВологість: %.0f%%\nОпис: %s\n" +
+			"Вітер: %s %s (пориви до %s)\nТиск: %s\nОпади: %s\nХмарність: %d%%\n" +
+			"УФ-індекс: %.0f\nВидимість: %s\nЧас спостереження: %s",
+		MsgAirQualityAlert: "Якість повітря (%s) досягла індексу US EPA %d (%s), що не нижче за ваш поріг %d.\n" +
+			"PM2.5: %.1f мкг/м³\nPM10: %.1f мкг/м³\nO3: %.1f мкг/м³\nNO2: %.1f мкг/м³\nІндекс UK DEFRA: %d",
+		MsgWeatherAlertDetails: "%s\nСерйозність: %s\nТерміновість: %s\nДостовірність: %s\nРайони: %s",
+		MsgWeatherAlertFrom:    "Початок: %s",
+		MsgWeatherAlertUntil:   "Закінчення: %s",
+		MsgWeatherAlertUpdated: "Оновлено: %s",
+		MsgRuleSubject:         "Погодне сповіщення (%s): %s",
+		MsgRuleMatched:         "Ваше правило сповіщення «%s» спрацювало: %s, %s.",
+		MsgRuleForecast: "Прогноз: %s, %s\nМаксимум: %s\nМінімум: %s\nОпади: %s (ймовірність дощу %d%%)\n" +
+			"Максимальний вітер: %s\nОпис: %s",
+		"rule_period_now":         "поточна погода",
+		"rule_period_today":       "прогноз на сьогодні",
+		"rule_period_tomorrow":    "прогноз на завтра",
+		"us_epa_1":                "Добра",
+		"us_epa_2":                "Помірна",
+		"us_epa_3":                "Шкідлива для чутливих груп",
+		"us_epa_4":                "Шкідлива",
+		"us_epa_5":                "Дуже шкідлива",
+		"us_epa_6":                "Небезпечна",
+		MsgUSEPAUnknown:           "Невідомо",
+		MsgAstronomy:              "Схід сонця: %s\nЗахід сонця: %s\nТривалість дня: %d год %02d хв\nМісяць: %s (освітлено %d%%)",
+		"moon_new_moon":           "Молодик",
+		"moon_waxing_crescent":    "Молодий місяць",
+		"moon_first_quarter":      "Перша чверть",
+		"moon_waxing_gibbous":     "Прибуваючий місяць",
+		"moon_full_moon":          "Повня",
+		"moon_waning_gibbous":     "Спадаючий місяць",
+		"moon_last_quarter":       "Остання чверть",
+		"moon_waning_crescent":    "Старий місяць",
+		MsgMoonPhaseUnknown:       "Невідомо",
+		"condition_clear":         "Ясно",
+		"condition_partly_cloudy": "Мінлива хмарність",
+		"condition_cloudy":        "Хмарно",
+		"condition_overcast":      "Похмуро",
+		"condition_fog":           "Туман",
+		"condition_drizzle":       "Мряка",
+		"condition_rain":          "Дощ",
+		"condition_heavy_rain":    "Сильний дощ",
+		"condition_freezing_rain": "Крижаний дощ",
+		"condition_sleet":         "Мокрий сніг",
+		"condition_snow":          "Сніг",
+		"condition_heavy_snow":    "Сильний сніг",
+		"condition_thunder":       "Гроза",
+		MsgConditionUnknown:       "Невідомо",
+	},
+}
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type EmailService struct {
+	mock.Mock
+}
+
+func (m *EmailService) SendConfirmationEmail(ctx context.Context, toEmail, lang, city, confirmationLink, manageLink string) error {
+	return m.Called(ctx, toEmail, lang, city, confirmationLink, manageLink).Error(0)
+}
+
+func (m *EmailService) SendWeatherUpdateEmail(ctx context.Context, toEmail, lang, city, weatherInfo, manageLink, unsubscribeLink string) error {
+	return m.Called(ctx, toEmail, lang, city, weatherInfo, manageLink, unsubscribeLink).Error(0)
+}
+
+func (m *EmailService) SendAirQualityAlertEmail(ctx context.Context, toEmail, lang, city, alertInfo, manageLink, unsubscribeLink string) error {
+	return m.Called(ctx, toEmail, lang, city, alertInfo, manageLink, unsubscribeLink).Error(0)
+}
+
+func (m *EmailService) SendWeatherAlertEmail(ctx context.Context, toEmail, lang, city, headline, alertInfo, manageLink, unsubscribeLink string) error {
+	return m.Called(ctx, toEmail, lang, city, headline, alertInfo, manageLink, unsubscribeLink).Error(0)
+}
+
+func (m *EmailService) SendRuleNotificationEmail(ctx context.Context, toEmail, lang, city, ruleName, ruleInfo, manageLink, unsubscribeLink string) error {
+	return m.Called(ctx, toEmail, lang, city, ruleName, ruleInfo, manageLink, unsubscribeLink).Error(0)
+}
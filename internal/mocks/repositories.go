@@ -0,0 +1,212 @@
+package mocks
+
+import (
+	"context"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SubscriptionRepository struct {
+	mock.Mock
+}
+
+// subscription returns the *core.Subscription in args at i, which may be nil.
+func subscription(args mock.Arguments, i int) *core.Subscription {
+	if args.Get(i) == nil {
+		return nil
+	}
+	return args.Get(i).(*core.Subscription)
+}
+
+func subscriptions(args mock.Arguments, i int) []core.Subscription {
+	if args.Get(i) == nil {
+		return nil
+	}
+	return args.Get(i).([]core.Subscription)
+}
+
+func (m *SubscriptionRepository) Create(ctx context.Context, sub *core.Subscription) error {
+	return m.Called(ctx, sub).Error(0)
+}
+
+func (m *SubscriptionRepository) FindByID(ctx context.Context, id string) (*core.Subscription, error) {
+	args := m.Called(ctx, id)
+	return subscription(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) FindBySubscriberAndCity(ctx context.Context, subscriberID, city string) (*core.Subscription, error) {
+	args := m.Called(ctx, subscriberID, city)
+	return subscription(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) FindBySubscriberAndLocation(ctx context.Context, subscriberID, locationID string) (*core.Subscription, error) {
+	args := m.Called(ctx, subscriberID, locationID)
+	return subscription(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) ListBySubscriber(ctx context.Context, subscriberID string) ([]core.Subscription, error) {
+	args := m.Called(ctx, subscriberID)
+	return subscriptions(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) FindByUnsubscribeToken(ctx context.Context, token string) (*core.Subscription, error) {
+	args := m.Called(ctx, token)
+	return subscription(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) FindByManageToken(ctx context.Context, token string) (*core.Subscription, error) {
+	args := m.Called(ctx, token)
+	return subscription(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) UpdateSettings(ctx context.Context, sub *core.Subscription, reschedule bool) error {
+	return m.Called(ctx, sub, reschedule).Error(0)
+}
+
+func (m *SubscriptionRepository) Delete(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *SubscriptionRepository) GetDue(ctx context.Context, now time.Time) ([]core.Subscription, error) {
+	args := m.Called(ctx, now)
+	return subscriptions(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) GetUnscheduled(ctx context.Context) ([]core.Subscription, error) {
+	args := m.Called(ctx)
+	return subscriptions(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) SetNextDueAt(ctx context.Context, id string, nextDueAt *time.Time) error {
+	return m.Called(ctx, id, nextDueAt).Error(0)
+}
+
+func (m *SubscriptionRepository) ClaimDue(ctx context.Context, id string, dueAt, nextDueAt, sentAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, dueAt, nextDueAt, sentAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) ConfirmPending(ctx context.Context, token string) (*core.Subscription, error) {
+	args := m.Called(ctx, token)
+	return subscription(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) ReleaseClaim(ctx context.Context, id string, dueAt, nextDueAt, sentAt time.Time, lastSentAt *time.Time) (bool, error) {
+	args := m.Called(ctx, id, dueAt, nextDueAt, sentAt, lastSentAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) GetConfirmedByFrequency(ctx context.Context, frequency string) ([]core.Subscription, error) {
+	args := m.Called(ctx, frequency)
+	return subscriptions(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) GetConfirmedWithAQIThreshold(ctx context.Context) ([]core.Subscription, error) {
+	args := m.Called(ctx)
+	return subscriptions(args, 0), args.Error(1)
+}
+
+func (m *SubscriptionRepository) SetAQIAlertActive(ctx context.Context, id string, active bool) error {
+	return m.Called(ctx, id, active).Error(0)
+}
+
+type SubscriberRepository struct {
+	mock.Mock
+}
+
+func subscriber(args mock.Arguments, i int) *core.Subscriber {
+	if args.Get(i) == nil {
+		return nil
+	}
+	return args.Get(i).(*core.Subscriber)
+}
+
+func (m *SubscriberRepository) Create(ctx context.Context, subscriber *core.Subscriber) error {
+	return m.Called(ctx, subscriber).Error(0)
+}
+
+func (m *SubscriberRepository) FindByEmail(ctx context.Context, email string) (*core.Subscriber, error) {
+	args := m.Called(ctx, email)
+	return subscriber(args, 0), args.Error(1)
+}
+
+func (m *SubscriberRepository) FindByConfirmationToken(ctx context.Context, token string) (*core.Subscriber, error) {
+	args := m.Called(ctx, token)
+	return subscriber(args, 0), args.Error(1)
+}
+
+func (m *SubscriberRepository) Confirm(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *SubscriberRepository) ReissueConfirmation(ctx context.Context, id, token string, expiresAt, sentAt, sentBefore time.Time) (bool, error) {
+	args := m.Called(ctx, id, token, expiresAt, sentAt, sentBefore)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *SubscriberRepository) DeleteIfUnused(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *SubscriberRepository) DeleteUnconfirmed(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type AlertRepository struct {
+	mock.Mock
+}
+
+func (m *AlertRepository) GetNotifiedFingerprints(ctx context.Context, subscriptionID string) (map[string]string, error) {
+	args := m.Called(ctx, subscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *AlertRepository) RecordNotification(ctx context.Context, subscriptionID string, alert core.WeatherAlert) error {
+	return m.Called(ctx, subscriptionID, alert).Error(0)
+}
+
+func (m *AlertRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type RuleRepository struct {
+	mock.Mock
+}
+
+func rules(args mock.Arguments, i int) []core.NotificationRule {
+	if args.Get(i) == nil {
+		return nil
+	}
+	return args.Get(i).([]core.NotificationRule)
+}
+
+func (m *RuleRepository) Create(ctx context.Context, rule *core.NotificationRule) error {
+	return m.Called(ctx, rule).Error(0)
+}
+
+func (m *RuleRepository) ListBySubscription(ctx context.Context, subscriptionID string) ([]core.NotificationRule, error) {
+	args := m.Called(ctx, subscriptionID)
+	return rules(args, 0), args.Error(1)
+}
+
+func (m *RuleRepository) Delete(ctx context.Context, subscriptionID, id string) (bool, error) {
+	args := m.Called(ctx, subscriptionID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *RuleRepository) GetEligible(ctx context.Context, now time.Time) ([]core.NotificationRule, error) {
+	args := m.Called(ctx, now)
+	return rules(args, 0), args.Error(1)
+}
+
+func (m *RuleRepository) ClaimFired(ctx context.Context, id string, lastFiredAt *time.Time, firedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, lastFiredAt, firedAt)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,62 @@
+// Package mocks holds testify mocks of the repositories and providers the
+// services depend on, shared by the tests of several packages.
+package mocks
+
+import (
+	"context"
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WeatherProvider struct {
+	mock.Mock
+}
+
+func (m *WeatherProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*core.Weather), args.Error(1)
+}
+
+func (m *WeatherProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	args := m.Called(ctx, query, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*core.Forecast), args.Error(1)
+}
+
+func (m *WeatherProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*core.AirQuality), args.Error(1)
+}
+
+func (m *WeatherProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]core.WeatherAlert), args.Error(1)
+}
+
+func (m *WeatherProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]core.Location), args.Error(1)
+}
+
+func (m *WeatherProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	args := m.Called(ctx, query, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*core.Astronomy), args.Error(1)
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AlertRepository remembers which weather alerts each subscription has been
+// notified about, so the scheduler only sends new or updated ones.
+type AlertRepository interface {
+	// GetNotifiedFingerprints maps alert ID to the fingerprint last sent to the subscription.
+	GetNotifiedFingerprints(ctx context.Context, subscriptionID string) (map[string]string, error)
+	RecordNotification(ctx context.Context, subscriptionID string, alert core.WeatherAlert) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PGAlertRepository struct {
+	db *sqlx.DB
+}
+
+func NewPGAlertRepository(db *sqlx.DB) *PGAlertRepository {
+	return &PGAlertRepository{db: db}
+}
+
+func (r *PGAlertRepository) GetNotifiedFingerprints(ctx context.Context, subscriptionID string) (map[string]string, error) {
+	var rows []struct {
+		AlertID     string `db:"alert_id"`
+		Fingerprint string `db:"fingerprint"`
+	}
+	query := `SELECT alert_id, fingerprint FROM weather_alert_notifications WHERE subscription_id = $1`
+	if err := r.db.SelectContext(ctx, &rows, query, subscriptionID); err != nil {
+		return nil, fmt.Errorf("failed to get notified weather alerts: %w", err)
+	}
+
+	fingerprints := make(map[string]string, len(rows))
+	for _, row := range rows {
+		fingerprints[row.AlertID] = row.Fingerprint
+	}
+	return fingerprints, nil
+}
+
+func (r *PGAlertRepository) RecordNotification(ctx context.Context, subscriptionID string, alert core.WeatherAlert) error {
+	query := `INSERT INTO weather_alert_notifications (subscription_id, alert_id, fingerprint, expires_at, notified_at)
+              VALUES ($1, $2, $3, $4, $5)
+              ON CONFLICT (subscription_id, alert_id)
+              DO UPDATE SET fingerprint = EXCLUDED.fingerprint, expires_at = EXCLUDED.expires_at, notified_at = EXCLUDED.notified_at`
+
+	var expiresAt *time.Time
+	if !alert.Expires.IsZero() {
+		expiresAt = &alert.Expires
+	}
+	_, err := r.db.ExecContext(ctx, query, subscriptionID, alert.ID, alert.Fingerprint(), expiresAt, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record weather alert notification: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired forgets alerts that expired before the given time. Alerts
+// without an expiry are kept until their subscription is deleted.
+func (r *PGAlertRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM weather_alert_notifications WHERE expires_at < $1`
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired weather alert notifications: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected on delete: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ObservationRepository stores fetched current-weather observations and
+// aggregates them into history.
+type ObservationRepository interface {
+	// Record stores an observation fetched for query; one already stored for the
+	// same location and time, or one without an observation time, is ignored.
+	Record(ctx context.Context, query core.LocationQuery, weather *core.Weather) error
+	// Aggregate returns one point per non-empty bucket in [from, to), oldest first.
+	Aggregate(ctx context.Context, query core.LocationQuery, from, to time.Time, interval core.HistoryInterval) ([]core.HistoryPoint, error)
+}
+
+// ObservationLocationKey is the key observations fetched for query are stored
+// under: the normalized query, e.g. "city:kyiv", whatever the provider calls
+// the place and whatever language it was asked in.
+func ObservationLocationKey(query core.LocationQuery) string {
+	return query.WithLang("").String()
+}
+
+type PGObservationRepository struct {
+	db *sqlx.DB
+}
+
+func NewPGObservationRepository(db *sqlx.DB) *PGObservationRepository {
+	return &PGObservationRepository{db: db}
+}
+
+func (r *PGObservationRepository) Record(ctx context.Context, query core.LocationQuery, weather *core.Weather) error {
+	insert := `INSERT INTO weather_observations (location_key, location_name, country, lat, lon, observed_at,
+              temperature, feels_like, humidity, wind_speed, pressure, precipitation, description, recorded_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+              ON CONFLICT (location_key, observed_at) DO NOTHING`
+
+	if weather.ObservedAt.IsZero() {
+		return nil
+	}
+	loc := weather.Location
+	_, err := r.db.ExecContext(ctx, insert, ObservationLocationKey(query), loc.Name, loc.Country, loc.Lat, loc.Lon, weather.ObservedAt,
+		weather.Temperature, weather.FeelsLike, weather.Humidity, weather.WindSpeed, weather.Pressure, weather.Precipitation,
+		weather.Description, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record weather observation: %w", err)
+	}
+	return nil
+}
+
+type historyRow struct {
+	Bucket           time.Time `db:"bucket"`
+	Observations     int       `db:"observations"`
+	MinTemperature   float64   `db:"min_temperature"`
+	MaxTemperature   float64   `db:"max_temperature"`
+	AvgTemperature   float64   `db:"avg_temperature"`
+	MinHumidity      float64   `db:"min_humidity"`
+	MaxHumidity      float64   `db:"max_humidity"`
+	AvgHumidity      float64   `db:"avg_humidity"`
+	MinWindSpeed     float64   `db:"min_wind_speed"`
+	MaxWindSpeed     float64   `db:"max_wind_speed"`
+	AvgWindSpeed     float64   `db:"avg_wind_speed"`
+	MinPressure      float64   `db:"min_pressure"`
+	MaxPressure      float64   `db:"max_pressure"`
+	AvgPressure      float64   `db:"avg_pressure"`
+	MinPrecipitation float64   `db:"min_precipitation"`
+	MaxPrecipitation float64   `db:"max_precipitation"`
+	AvgPrecipitation float64   `db:"avg_precipitation"`
+}
+
+func (r *PGObservationRepository) Aggregate(ctx context.Context, query core.LocationQuery, from, to time.Time, interval core.HistoryInterval) ([]core.HistoryPoint, error) {
+	// buckets are cut in UTC regardless of the session time zone
+	selectBuckets := `SELECT date_trunc($1, observed_at AT TIME ZONE 'UTC') AS bucket, COUNT(*) AS observations,
+              MIN(temperature) AS min_temperature, MAX(temperature) AS max_temperature, AVG(temperature) AS avg_temperature,
+              MIN(humidity) AS min_humidity, MAX(humidity) AS max_humidity, AVG(humidity) AS avg_humidity,
+              MIN(wind_speed) AS min_wind_speed, MAX(wind_speed) AS max_wind_speed, AVG(wind_speed) AS avg_wind_speed,
+              MIN(pressure) AS min_pressure, MAX(pressure) AS max_pressure, AVG(pressure) AS avg_pressure,
+              MIN(precipitation) AS min_precipitation, MAX(precipitation) AS max_precipitation, AVG(precipitation) AS avg_precipitation
+              FROM weather_observations
+              WHERE location_key = $2 AND observed_at >= $3 AND observed_at < $4
+              GROUP BY bucket ORDER BY bucket`
+
+	var rows []historyRow
+	if err := r.db.SelectContext(ctx, &rows, selectBuckets, string(interval), ObservationLocationKey(query), from, to); err != nil {
+		return nil, fmt.Errorf("failed to aggregate weather observations: %w", err)
+	}
+
+	points := make([]core.HistoryPoint, 0, len(rows))
+	for _, row := range rows {
+		points = append(points, core.HistoryPoint{
+			Time:          time.Date(row.Bucket.Year(), row.Bucket.Month(), row.Bucket.Day(), row.Bucket.Hour(), 0, 0, 0, time.UTC),
+			Observations:  row.Observations,
+			Temperature:   core.Stats{Min: row.MinTemperature, Max: row.MaxTemperature, Avg: row.AvgTemperature},
+			Humidity:      core.Stats{Min: row.MinHumidity, Max: row.MaxHumidity, Avg: row.AvgHumidity},
+			WindSpeed:     core.Stats{Min: row.MinWindSpeed, Max: row.MaxWindSpeed, Avg: row.AvgWindSpeed},
+			Pressure:      core.Stats{Min: row.MinPressure, Max: row.MaxPressure, Avg: row.AvgPressure},
+			Precipitation: core.Stats{Min: row.MinPrecipitation, Max: row.MaxPrecipitation, Avg: row.AvgPrecipitation},
+		})
+	}
+	return points, nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PGQuotaStore keeps monthly weather API call counts per provider.
+type PGQuotaStore struct {
+	db *sqlx.DB
+}
+
+func NewPGQuotaStore(db *sqlx.DB) *PGQuotaStore {
+	return &PGQuotaStore{db: db}
+}
+
+func (r *PGQuotaStore) GetMonthlyUsage(ctx context.Context, name string, month time.Time) (int, error) {
+	var calls int
+	query := `SELECT calls FROM api_quota_usage WHERE provider = $1 AND month = $2`
+	err := r.db.GetContext(ctx, &calls, query, name, month.Format("2006-01-02"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+	return calls, nil
+}
+
+func (r *PGQuotaStore) IncrementMonthlyUsage(ctx context.Context, name string, month time.Time) error {
+	query := `INSERT INTO api_quota_usage (provider, month, calls, updated_at) VALUES ($1, $2, 1, $3)
+              ON CONFLICT (provider, month) DO UPDATE SET calls = api_quota_usage.calls + 1, updated_at = EXCLUDED.updated_at`
+	_, err := r.db.ExecContext(ctx, query, name, month.Format("2006-01-02"), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to increment quota usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RuleRepository stores subscriptions' notification rules and when each last
+// fired.
+type RuleRepository interface {
+	Create(ctx context.Context, rule *core.NotificationRule) error
+	ListBySubscription(ctx context.Context, subscriptionID string) ([]core.NotificationRule, error)
+	// Delete reports whether the subscription had a rule with the given ID.
+	Delete(ctx context.Context, subscriptionID, id string) (bool, error)
+	// GetEligible returns the rules of confirmed, unpaused subscriptions that
+	// are not cooling down at now.
+	GetEligible(ctx context.Context, now time.Time) ([]core.NotificationRule, error)
+	// ClaimFired records that the rule fired at firedAt. It reports false when
+	// the rule has fired since lastFiredAt was read, e.g. in another run.
+	ClaimFired(ctx context.Context, id string, lastFiredAt *time.Time, firedAt time.Time) (bool, error)
+}
+
+const ruleColumns = `r.id, r.subscription_id, r.name, r.period, r.units, r.expression, r.cooldown_hours, r.last_fired_at, r.created_at`
+
+// ruleRow is a notification_rules row; the expression is stored as JSON.
+type ruleRow struct {
+	ID             string     `db:"id"`
+	SubscriptionID string     `db:"subscription_id"`
+	Name           string     `db:"name"`
+	Period         string     `db:"period"`
+	Units          string     `db:"units"`
+	Expression     []byte     `db:"expression"`
+	CooldownHours  int        `db:"cooldown_hours"`
+	LastFiredAt    *time.Time `db:"last_fired_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+func (row ruleRow) rule() (core.NotificationRule, error) {
+	rule := core.NotificationRule{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		Name:           row.Name,
+		Period:         core.RulePeriod(row.Period),
+		Units:          row.Units,
+		CooldownHours:  row.CooldownHours,
+		LastFiredAt:    row.LastFiredAt,
+		CreatedAt:      row.CreatedAt,
+	}
+	if err := json.Unmarshal(row.Expression, &rule.Expression); err != nil {
+		return core.NotificationRule{}, fmt.Errorf("failed to decode expression of notification rule %s: %w", row.ID, err)
+	}
+	return rule, nil
+}
+
+type PGRuleRepository struct {
+	db *sqlx.DB
+}
+
+func NewPGRuleRepository(db *sqlx.DB) *PGRuleRepository {
+	return &PGRuleRepository{db: db}
+}
+
+func (r *PGRuleRepository) Create(ctx context.Context, rule *core.NotificationRule) error {
+	expression, err := json.Marshal(rule.Expression)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification rule expression: %w", err)
+	}
+	query := `INSERT INTO notification_rules (id, subscription_id, name, period, units, expression, cooldown_hours, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	rule.CreatedAt = time.Now().UTC()
+
+	_, err = r.db.ExecContext(ctx, query, rule.ID, rule.SubscriptionID, rule.Name, rule.Period, rule.Units,
+		expression, rule.CooldownHours, rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification rule: %w", err)
+	}
+	return nil
+}
+
+func (r *PGRuleRepository) ListBySubscription(ctx context.Context, subscriptionID string) ([]core.NotificationRule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM notification_rules r WHERE r.subscription_id = $1 ORDER BY r.created_at`
+	return r.selectRules(ctx, query, subscriptionID)
+}
+
+func (r *PGRuleRepository) Delete(ctx context.Context, subscriptionID, id string) (bool, error) {
+	query := `DELETE FROM notification_rules WHERE id = $1 AND subscription_id = $2`
+	res, err := r.db.ExecContext(ctx, query, id, subscriptionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete notification rule: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected on delete: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *PGRuleRepository) GetEligible(ctx context.Context, now time.Time) ([]core.NotificationRule, error) {
+	query := `SELECT ` + ruleColumns + `
+              FROM notification_rules r
+              JOIN subscriptions s ON s.id = r.subscription_id
+              JOIN subscribers b ON b.id = s.subscriber_id
+              WHERE b.is_confirmed = TRUE AND s.pending_confirmation_token IS NULL AND s.is_paused = FALSE
+                AND (r.last_fired_at IS NULL OR r.last_fired_at + make_interval(hours => r.cooldown_hours) <= $1)
+              ORDER BY r.subscription_id, r.created_at`
+	return r.selectRules(ctx, query, now.UTC())
+}
+
+func (r *PGRuleRepository) ClaimFired(ctx context.Context, id string, lastFiredAt *time.Time, firedAt time.Time) (bool, error) {
+	query := `UPDATE notification_rules SET last_fired_at = $1
+              WHERE id = $2 AND last_fired_at IS NOT DISTINCT FROM $3::timestamptz`
+	res, err := r.db.ExecContext(ctx, query, firedAt.UTC(), id, lastFiredAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record fired notification rule: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected on claim: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+func (r *PGRuleRepository) selectRules(ctx context.Context, query string, args ...interface{}) ([]core.NotificationRule, error) {
+	var rows []ruleRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get notification rules: %w", err)
+	}
+
+	rules := make([]core.NotificationRule, 0, len(rows))
+	for _, row := range rows {
+		rule, err := row.rule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
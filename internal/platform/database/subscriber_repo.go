@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SubscriberRepository interface {
+	Create(ctx context.Context, subscriber *core.Subscriber) error
+	FindByEmail(ctx context.Context, email string) (*core.Subscriber, error)
+	FindByConfirmationToken(ctx context.Context, token string) (*core.Subscriber, error)
+	Confirm(ctx context.Context, id string) error
+	// ReissueConfirmation gives an unconfirmed subscriber a new confirmation
+	// token valid until expiresAt and records it as sent at sentAt. It reports
+	// false when the last one was sent after sentBefore, or the subscriber is
+	// already confirmed.
+	ReissueConfirmation(ctx context.Context, id, token string, expiresAt, sentAt, sentBefore time.Time) (bool, error)
+	// DeleteIfUnused deletes the subscriber once it has no subscriptions left.
+	DeleteIfUnused(ctx context.Context, id string) error
+	// DeleteUnconfirmed deletes the unconfirmed subscribers, with their
+	// subscriptions, that were last sent a confirmation before before.
+	DeleteUnconfirmed(ctx context.Context, before time.Time) (int64, error)
+}
+
+const subscriberColumns = `id, email, units, language, confirmation_token, confirmation_expires_at, confirmation_sent_at,
+              is_confirmed, created_at, updated_at`
+
+type PGSubscriberRepository struct {
+	db *sqlx.DB
+}
+
+func NewPGSubscriberRepository(db *sqlx.DB) *PGSubscriberRepository {
+	return &PGSubscriberRepository{db: db}
+}
+
+func (r *PGSubscriberRepository) Create(ctx context.Context, subscriber *core.Subscriber) error {
+	query := `INSERT INTO subscribers (id, email, units, language, confirmation_token, confirmation_expires_at,
+              confirmation_sent_at, is_confirmed, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	subscriber.CreatedAt = time.Now().UTC()
+	subscriber.UpdatedAt = time.Now().UTC()
+
+	_, err := r.db.ExecContext(ctx, query, subscriber.ID, subscriber.Email, subscriber.Units, subscriber.Language,
+		subscriber.ConfirmationToken, subscriber.ConfirmationExpiresAt, subscriber.ConfirmationSentAt, subscriber.IsConfirmed,
+		subscriber.CreatedAt, subscriber.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create subscriber: %w", err)
+	}
+	return nil
+}
+
+func (r *PGSubscriberRepository) FindByEmail(ctx context.Context, email string) (*core.Subscriber, error) {
+	var subscriber core.Subscriber
+	query := `SELECT ` + subscriberColumns + ` FROM subscribers WHERE email = $1`
+	err := r.db.GetContext(ctx, &subscriber, query, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find subscriber by email: %w", err)
+	}
+	return &subscriber, nil
+}
+
+func (r *PGSubscriberRepository) FindByConfirmationToken(ctx context.Context, token string) (*core.Subscriber, error) {
+	var subscriber core.Subscriber
+	query := `SELECT ` + subscriberColumns + ` FROM subscribers WHERE confirmation_token = $1`
+	err := r.db.GetContext(ctx, &subscriber, query, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find subscriber by confirmation token: %w", err)
+	}
+	return &subscriber, nil
+}
+
+func (r *PGSubscriberRepository) Confirm(ctx context.Context, id string) error {
+	query := `UPDATE subscribers SET is_confirmed = TRUE, confirmation_token = NULL, confirmation_expires_at = NULL, updated_at = $1
+              WHERE id = $2 AND is_confirmed = FALSE`
+
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscriber: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errors.New("subscriber not found or already confirmed")
+	}
+	return nil
+}
+
+func (r *PGSubscriberRepository) ReissueConfirmation(ctx context.Context, id, token string, expiresAt, sentAt, sentBefore time.Time) (bool, error) {
+	query := `UPDATE subscribers SET confirmation_token = $1, confirmation_expires_at = $2, confirmation_sent_at = $3, updated_at = $4
+              WHERE id = $5 AND is_confirmed = FALSE AND (confirmation_sent_at IS NULL OR confirmation_sent_at <= $6)`
+	res, err := r.db.ExecContext(ctx, query, token, expiresAt.UTC(), sentAt.UTC(), time.Now().UTC(), id, sentBefore.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to reissue confirmation token: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected on reissue: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+func (r *PGSubscriberRepository) DeleteIfUnused(ctx context.Context, id string) error {
+	query := `DELETE FROM subscribers WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM subscriptions WHERE subscriber_id = $1)`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete subscriber: %w", err)
+	}
+	return nil
+}
+
+func (r *PGSubscriberRepository) DeleteUnconfirmed(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM subscribers WHERE is_confirmed = FALSE AND COALESCE(confirmation_sent_at, created_at) < $1`
+	res, err := r.db.ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unconfirmed subscribers: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected on delete: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -10,16 +11,51 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrSubscriptionNotFound is returned when the subscription to change or
+// delete no longer exists.
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
 type SubscriptionRepository interface {
-	Create(sub *core.Subscription) error
-	FindByEmailAndCity(email, city string) (*core.Subscription, error)
-	FindByConfirmationToken(token string) (*core.Subscription, error)
-	Confirm(id string) error
-	FindByUnsubscribeToken(token string) (*core.Subscription, error)
-	Delete(id string) error
-	GetAllConfirmed() ([]core.Subscription, error)
+	Create(ctx context.Context, sub *core.Subscription) error
+	FindByID(ctx context.Context, id string) (*core.Subscription, error)
+	FindBySubscriberAndCity(ctx context.Context, subscriberID, city string) (*core.Subscription, error)
+	FindBySubscriberAndLocation(ctx context.Context, subscriberID, locationID string) (*core.Subscription, error)
+	ListBySubscriber(ctx context.Context, subscriberID string) ([]core.Subscription, error)
+	FindByUnsubscribeToken(ctx context.Context, token string) (*core.Subscription, error)
+	FindByManageToken(ctx context.Context, token string) (*core.Subscription, error)
+	// UpdateSettings saves the location, schedule and pause state of sub, and
+	// its units to the subscriber, in one transaction. Its next_due_at is only
+	// written when reschedule is set, so a delivery claimed since sub was read
+	// isn't undone, and an active air quality alert is only cleared when the
+	// location changes. Both are read back into sub.
+	UpdateSettings(ctx context.Context, sub *core.Subscription, reschedule bool) error
+	Delete(ctx context.Context, id string) error
+	GetDue(ctx context.Context, now time.Time) ([]core.Subscription, error)
+	// GetUnscheduled returns confirmed, unpaused subscriptions with routine updates but no next_due_at.
+	GetUnscheduled(ctx context.Context) ([]core.Subscription, error)
+	SetNextDueAt(ctx context.Context, id string, nextDueAt *time.Time) error
+	ClaimDue(ctx context.Context, id string, dueAt, nextDueAt, sentAt time.Time) (bool, error)
+	// ConfirmPending confirms the subscription waiting on its own confirmation
+	// token and returns it, or nil when no subscription has that token.
+	ConfirmPending(ctx context.Context, token string) (*core.Subscription, error)
+	ReleaseClaim(ctx context.Context, id string, dueAt, nextDueAt, sentAt time.Time, lastSentAt *time.Time) (bool, error)
+	GetConfirmedByFrequency(ctx context.Context, frequency string) ([]core.Subscription, error)
+	GetConfirmedWithAQIThreshold(ctx context.Context) ([]core.Subscription, error)
+	SetAQIAlertActive(ctx context.Context, id string, active bool) error
 }
 
+// subscriptionColumns lists the columns scanned into core.Subscription,
+// including the preferences shared through its subscriber. A subscription is
+// confirmed once its subscriber is and it isn't waiting on its own link.
+const subscriptionColumns = `s.id, s.subscriber_id, b.email, s.city, s.location_id, s.country, s.lat, s.lon, s.timezone, s.frequency,
+              b.units, s.aqi_threshold, s.aqi_alert_active, b.language, s.include_astronomy, s.delivery_time,
+              s.delivery_timezone, s.next_due_at, s.last_sent_at,
+              (b.is_confirmed AND s.pending_confirmation_token IS NULL) AS is_confirmed, s.is_paused,
+              s.pending_confirmation_token, s.unsubscribe_token, s.manage_token, s.created_at, s.updated_at`
+
+// subscriptionsFrom is the FROM clause subscriptionColumns are selected with.
+const subscriptionsFrom = `subscriptions s JOIN subscribers b ON b.id = s.subscriber_id`
+
 type PGSubscriptionRepository struct {
 	db *sqlx.DB
 }
@@ -28,70 +64,81 @@ func NewPGSubscriptionRepository(db *sqlx.DB) *PGSubscriptionRepository {
 	return &PGSubscriptionRepository{db: db}
 }
 
-func (r *PGSubscriptionRepository) Create(sub *core.Subscription) error {
-	query := `INSERT INTO subscriptions (id, email, city, frequency, confirmation_token, unsubscribe_token, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+func (r *PGSubscriptionRepository) Create(ctx context.Context, sub *core.Subscription) error {
+	query := `INSERT INTO subscriptions (id, subscriber_id, city, location_id, country, lat, lon, timezone, frequency,
+              aqi_threshold, include_astronomy, delivery_time, delivery_timezone, next_due_at, unsubscribe_token, manage_token,
+              created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
 	sub.CreatedAt = time.Now().UTC()
 	sub.UpdatedAt = time.Now().UTC()
 
-	_, err := r.db.Exec(query, sub.ID, sub.Email, sub.City, sub.Frequency, sub.ConfirmationToken, sub.UnsubscribeToken, sub.CreatedAt, sub.UpdatedAt)
+	_, err := r.db.ExecContext(ctx, query, sub.ID, sub.SubscriberID, sub.City, sub.LocationID, sub.Country, sub.Lat, sub.Lon,
+		sub.Timezone, sub.Frequency, sub.AQIThreshold, sub.IncludeAstronomy, sub.DeliveryTime, sub.DeliveryTimezone, sub.NextDueAt,
+		sub.UnsubscribeToken, sub.ManageToken, sub.CreatedAt, sub.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create subscription: %w", err)
 	}
 	return nil
 }
 
-func (r *PGSubscriptionRepository) FindByEmailAndCity(email, city string) (*core.Subscription, error) {
+func (r *PGSubscriptionRepository) FindByID(ctx context.Context, id string) (*core.Subscription, error) {
 	var sub core.Subscription
-	query := `SELECT id, email, city, frequency, confirmation_token, is_confirmed, unsubscribe_token, created_at, updated_at
-              FROM subscriptions WHERE email = $1 AND city = $2`
-	err := r.db.Get(&sub, query, email, city)
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE s.id = $1`
+	err := r.db.GetContext(ctx, &sub, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to find subscription by email and city: %w", err)
+		return nil, fmt.Errorf("failed to find subscription by id: %w", err)
 	}
 	return &sub, nil
 }
 
-func (r *PGSubscriptionRepository) FindByConfirmationToken(token string) (*core.Subscription, error) {
+func (r *PGSubscriptionRepository) FindBySubscriberAndCity(ctx context.Context, subscriberID, city string) (*core.Subscription, error) {
 	var sub core.Subscription
-	query := `SELECT id, email, city, frequency, confirmation_token, is_confirmed, unsubscribe_token, created_at, updated_at
-              FROM subscriptions WHERE confirmation_token = $1`
-	err := r.db.Get(&sub, query, token)
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE s.subscriber_id = $1 AND s.city = $2`
+	err := r.db.GetContext(ctx, &sub, query, subscriberID, city)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to find subscription by confirmation token: %w", err)
+		return nil, fmt.Errorf("failed to find subscription by subscriber and city: %w", err)
 	}
 	return &sub, nil
 }
 
-func (r *PGSubscriptionRepository) Confirm(id string) error {
-	query := `UPDATE subscriptions SET is_confirmed = TRUE, confirmation_token = NULL, updated_at = $1
-              WHERE id = $2 AND is_confirmed = FALSE`
-
-	res, err := r.db.Exec(query, time.Now().UTC(), id)
+func (r *PGSubscriptionRepository) FindBySubscriberAndLocation(ctx context.Context, subscriberID, locationID string) (*core.Subscription, error) {
+	var sub core.Subscription
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE s.subscriber_id = $1 AND s.location_id = $2`
+	err := r.db.GetContext(ctx, &sub, query, subscriberID, locationID)
 	if err != nil {
-		return fmt.Errorf("failed to confirm subscription: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find subscription by subscriber and location: %w", err)
 	}
-	rowsAffected, err := res.RowsAffected()
+	return &sub, nil
+}
+
+func (r *PGSubscriptionRepository) ListBySubscriber(ctx context.Context, subscriberID string) ([]core.Subscription, error) {
+	var subs []core.Subscription
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE s.subscriber_id = $1 ORDER BY s.created_at`
+	err := r.db.SelectContext(ctx, &subs, query, subscriberID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return errors.New("subscription not found or already confirmed")
+		return nil, fmt.Errorf("failed to list subscriptions of subscriber: %w", err)
 	}
-	return nil
+	return subs, nil
 }
 
-func (r *PGSubscriptionRepository) FindByUnsubscribeToken(token string) (*core.Subscription, error) {
+func (r *PGSubscriptionRepository) FindByUnsubscribeToken(ctx context.Context, token string) (*core.Subscription, error) {
 	var sub core.Subscription
-	query := `SELECT id, email, city, frequency, confirmation_token, is_confirmed, unsubscribe_token, created_at, updated_at
-              FROM subscriptions WHERE unsubscribe_token = $1`
-	err := r.db.Get(&sub, query, token)
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE s.unsubscribe_token = $1`
+	err := r.db.GetContext(ctx, &sub, query, token)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -101,9 +148,56 @@ func (r *PGSubscriptionRepository) FindByUnsubscribeToken(token string) (*core.S
 	return &sub, nil
 }
 
-func (r *PGSubscriptionRepository) Delete(id string) error {
+func (r *PGSubscriptionRepository) FindByManageToken(ctx context.Context, token string) (*core.Subscription, error) {
+	var sub core.Subscription
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE s.manage_token = $1`
+	err := r.db.GetContext(ctx, &sub, query, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find subscription by manage token: %w", err)
+	}
+	return &sub, nil
+}
+
+func (r *PGSubscriptionRepository) UpdateSettings(ctx context.Context, sub *core.Subscription, reschedule bool) error {
+	query := `UPDATE subscriptions SET city = $1, location_id = $2, country = $3, lat = $4, lon = $5, timezone = $6,
+              frequency = $7, delivery_time = $8, delivery_timezone = $9, is_paused = $10,
+              aqi_alert_active = aqi_alert_active AND location_id = $2 AND city = $1,
+              next_due_at = CASE WHEN $11 THEN $12 ELSE next_due_at END, updated_at = $13
+              WHERE id = $14
+              RETURNING aqi_alert_active, next_due_at`
+	sub.UpdatedAt = time.Now().UTC()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin subscription update: %w", err)
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowxContext(ctx, query, sub.City, sub.LocationID, sub.Country, sub.Lat, sub.Lon, sub.Timezone,
+		sub.Frequency, sub.DeliveryTime, sub.DeliveryTimezone, sub.IsPaused, reschedule, sub.NextDueAt, sub.UpdatedAt, sub.ID)
+	if err := row.Scan(&sub.AQIAlertActive, &sub.NextDueAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSubscriptionNotFound
+		}
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	unitsQuery := `UPDATE subscribers SET units = $1, updated_at = $2 WHERE id = $3 AND units <> $1`
+	if _, err := tx.ExecContext(ctx, unitsQuery, sub.Units, sub.UpdatedAt, sub.SubscriberID); err != nil {
+		return fmt.Errorf("failed to update subscriber units: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subscription update: %w", err)
+	}
+	return nil
+}
+
+func (r *PGSubscriptionRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM subscriptions WHERE id = $1`
-	res, err := r.db.Exec(query, id)
+	res, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete subscription: %w", err)
 	}
@@ -112,21 +206,122 @@ func (r *PGSubscriptionRepository) Delete(id string) error {
 		return fmt.Errorf("failed to get rows affected on delete: %w", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("subscription not found for deletion")
+		return ErrSubscriptionNotFound
 	}
 	return nil
 }
 
-func (r *PGSubscriptionRepository) GetAllConfirmed() ([]core.Subscription, error) {
+// GetDue returns the confirmed subscriptions whose next update was due at or
+// before now, oldest first.
+func (r *PGSubscriptionRepository) GetDue(ctx context.Context, now time.Time) ([]core.Subscription, error) {
+	var subs []core.Subscription
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE b.is_confirmed = TRUE AND s.pending_confirmation_token IS NULL AND s.is_paused = FALSE AND s.next_due_at <= $1 ORDER BY s.next_due_at`
+	err := r.db.SelectContext(ctx, &subs, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *PGSubscriptionRepository) GetUnscheduled(ctx context.Context) ([]core.Subscription, error) {
 	var subs []core.Subscription
-	query := `SELECT id, email, city, frequency, confirmation_token, is_confirmed, unsubscribe_token, created_at, updated_at
-              FROM subscriptions WHERE is_confirmed = TRUE`
-	err := r.db.Select(&subs, query)
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE b.is_confirmed = TRUE AND s.pending_confirmation_token IS NULL AND s.is_paused = FALSE AND s.next_due_at IS NULL AND s.frequency <> $1`
+	err := r.db.SelectContext(ctx, &subs, query, string(core.FrequencyAlerts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unscheduled subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// SetNextDueAt schedules the subscription's next routine update; nil leaves
+// it unscheduled.
+func (r *PGSubscriptionRepository) SetNextDueAt(ctx context.Context, id string, nextDueAt *time.Time) error {
+	query := `UPDATE subscriptions SET next_due_at = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, nextDueAt, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule subscription: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue moves a subscription due at dueAt on to nextDueAt and records the
+// send. It reports false when the subscription is no longer due at dueAt,
+// because another run has already claimed it or it was rescheduled.
+func (r *PGSubscriptionRepository) ClaimDue(ctx context.Context, id string, dueAt, nextDueAt, sentAt time.Time) (bool, error) {
+	query := `UPDATE subscriptions SET next_due_at = $1, last_sent_at = $2, updated_at = $3
+              WHERE id = $4 AND next_due_at = $5`
+	res, err := r.db.ExecContext(ctx, query, nextDueAt.UTC(), sentAt.UTC(), time.Now().UTC(), id, dueAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim due subscription: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected on claim: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+func (r *PGSubscriptionRepository) ConfirmPending(ctx context.Context, token string) (*core.Subscription, error) {
+	var id string
+	query := `UPDATE subscriptions SET pending_confirmation_token = NULL, updated_at = $1
+              WHERE pending_confirmation_token = $2 RETURNING id`
+	err := r.db.GetContext(ctx, &id, query, time.Now().UTC(), token)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return []core.Subscription{}, nil
+			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to get all confirmed subscriptions: %w", err)
+		return nil, fmt.Errorf("failed to confirm pending subscription: %w", err)
+	}
+	return r.FindByID(ctx, id)
+}
+
+// ReleaseClaim undoes a ClaimDue whose update couldn't be sent, putting back
+// dueAt and the previous lastSentAt so the next run sends it. It reports false
+// when the subscription has moved on since the claim, for example because it
+// was rescheduled.
+func (r *PGSubscriptionRepository) ReleaseClaim(ctx context.Context, id string, dueAt, nextDueAt, sentAt time.Time, lastSentAt *time.Time) (bool, error) {
+	query := `UPDATE subscriptions SET next_due_at = $1, last_sent_at = $2, updated_at = $3
+              WHERE id = $4 AND next_due_at = $5 AND last_sent_at = $6`
+	res, err := r.db.ExecContext(ctx, query, dueAt.UTC(), lastSentAt, time.Now().UTC(), id, nextDueAt.UTC(), sentAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to release claimed subscription: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected on release: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+func (r *PGSubscriptionRepository) GetConfirmedByFrequency(ctx context.Context, frequency string) ([]core.Subscription, error) {
+	var subs []core.Subscription
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE b.is_confirmed = TRUE AND s.pending_confirmation_token IS NULL AND s.is_paused = FALSE AND s.frequency = $1`
+	err := r.db.SelectContext(ctx, &subs, query, frequency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get confirmed %s subscriptions: %w", frequency, err)
 	}
 	return subs, nil
 }
+
+func (r *PGSubscriptionRepository) GetConfirmedWithAQIThreshold(ctx context.Context) ([]core.Subscription, error) {
+	var subs []core.Subscription
+	query := `SELECT ` + subscriptionColumns + `
+              FROM ` + subscriptionsFrom + ` WHERE b.is_confirmed = TRUE AND s.pending_confirmation_token IS NULL AND s.is_paused = FALSE AND s.aqi_threshold IS NOT NULL`
+	err := r.db.SelectContext(ctx, &subs, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions with air quality alerts: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *PGSubscriptionRepository) SetAQIAlertActive(ctx context.Context, id string, active bool) error {
+	query := `UPDATE subscriptions SET aqi_alert_active = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, active, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update air quality alert state: %w", err)
+	}
+	return nil
+}
@@ -1,10 +1,19 @@
 package email
 
-import "log"
+import (
+	"context"
+	"log"
 
+	"weather-app/internal/i18n"
+)
+
+// Service sends the app's emails. lang is the recipient's language, e.g. "uk".
 type Service interface {
-	SendConfirmationEmail(toEmail, city, confirmationLink string) error
-	SendWeatherUpdateEmail(toEmail, city, weatherInfo, unsubscribeLink string) error
+	SendConfirmationEmail(ctx context.Context, toEmail, lang, city, confirmationLink, manageLink string) error
+	SendWeatherUpdateEmail(ctx context.Context, toEmail, lang, city, weatherInfo, manageLink, unsubscribeLink string) error
+	SendAirQualityAlertEmail(ctx context.Context, toEmail, lang, city, alertInfo, manageLink, unsubscribeLink string) error
+	SendWeatherAlertEmail(ctx context.Context, toEmail, lang, city, headline, alertInfo, manageLink, unsubscribeLink string) error
+	SendRuleNotificationEmail(ctx context.Context, toEmail, lang, city, ruleName, ruleInfo, manageLink, unsubscribeLink string) error
 }
 
 // for now just a dummy email service that logs to console.
@@ -15,22 +24,58 @@ func NewLogEmailService() *LogEmailService {
 }
 
 // TODO: change these send actual e-mails later
-func (s *LogEmailService) SendConfirmationEmail(toEmail, city, confirmationLink string) error {
+func (s *LogEmailService) SendConfirmationEmail(ctx context.Context, toEmail, lang, city, confirmationLink, manageLink string) error {
 	log.Printf("--- SENDING CONFIRMATION EMAIL ---")
 	log.Printf("To: %s", toEmail)
 	log.Printf("City: %s", city)
-	log.Printf("Subject: Confirm your Weather Subscription for %s", city)
-	log.Printf("Body: Please confirm your subscription by clicking this link: %s", confirmationLink)
+	log.Printf("Subject: %s", i18n.T(lang, i18n.MsgConfirmationSubject, city))
+	log.Printf("Body: %s\n\n%s", i18n.T(lang, i18n.MsgConfirmationBody, confirmationLink), i18n.T(lang, i18n.MsgManageLink, manageLink))
 	log.Printf("--- END EMAIL ---")
 	return nil
 }
 
-func (s *LogEmailService) SendWeatherUpdateEmail(toEmail, city, weatherInfo, unsubscribeLink string) error {
+func (s *LogEmailService) SendWeatherUpdateEmail(ctx context.Context, toEmail, lang, city, weatherInfo, manageLink, unsubscribeLink string) error {
 	log.Printf("--- SENDING WEATHER UPDATE EMAIL ---")
 	log.Printf("To: %s", toEmail)
 	log.Printf("City: %s", city)
-	log.Printf("Subject: Your Weather Update for %s", city)
-	log.Printf("Body: %s\n\nUnsubscribe: %s", weatherInfo, unsubscribeLink)
+	log.Printf("Subject: %s", i18n.T(lang, i18n.MsgWeatherUpdateSubject, city))
+	log.Printf("Body: %s\n\n%s", weatherInfo, footer(lang, manageLink, unsubscribeLink))
 	log.Printf("--- END EMAIL ---")
 	return nil
 }
+
+func (s *LogEmailService) SendAirQualityAlertEmail(ctx context.Context, toEmail, lang, city, alertInfo, manageLink, unsubscribeLink string) error {
+	log.Printf("--- SENDING AIR QUALITY ALERT EMAIL ---")
+	log.Printf("To: %s", toEmail)
+	log.Printf("City: %s", city)
+	log.Printf("Subject: %s", i18n.T(lang, i18n.MsgAirQualityAlertSubject, city))
+	log.Printf("Body: %s\n\n%s", alertInfo, footer(lang, manageLink, unsubscribeLink))
+	log.Printf("--- END EMAIL ---")
+	return nil
+}
+
+func (s *LogEmailService) SendWeatherAlertEmail(ctx context.Context, toEmail, lang, city, headline, alertInfo, manageLink, unsubscribeLink string) error {
+	log.Printf("--- SENDING WEATHER ALERT EMAIL ---")
+	log.Printf("To: %s", toEmail)
+	log.Printf("City: %s", city)
+	log.Printf("Subject: %s", i18n.T(lang, i18n.MsgWeatherAlertSubject, city, headline))
+	log.Printf("Body: %s\n\n%s", alertInfo, footer(lang, manageLink, unsubscribeLink))
+	log.Printf("--- END EMAIL ---")
+	return nil
+}
+
+func (s *LogEmailService) SendRuleNotificationEmail(ctx context.Context, toEmail, lang, city, ruleName, ruleInfo, manageLink, unsubscribeLink string) error {
+	log.Printf("--- SENDING RULE NOTIFICATION EMAIL ---")
+	log.Printf("To: %s", toEmail)
+	log.Printf("City: %s", city)
+	log.Printf("Subject: %s", i18n.T(lang, i18n.MsgRuleSubject, city, ruleName))
+	log.Printf("Body: %s\n\n%s", ruleInfo, footer(lang, manageLink, unsubscribeLink))
+	log.Printf("--- END EMAIL ---")
+	return nil
+}
+
+// footer ends every email about a subscription with its manage and
+// unsubscribe links.
+func footer(lang, manageLink, unsubscribeLink string) string {
+	return i18n.T(lang, i18n.MsgManageLink, manageLink) + "\n" + i18n.T(lang, i18n.MsgUnsubscribeLink, unsubscribeLink)
+}
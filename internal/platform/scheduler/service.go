@@ -8,13 +8,24 @@ import (
 )
 
 type JobService interface {
-	SendWeatherUpdates()
+	SendWeatherUpdates(ctx context.Context)
+	CheckAirQualityAlerts(ctx context.Context)
+	CheckWeatherAlerts(ctx context.Context)
+	CheckNotificationRules(ctx context.Context)
+	PurgeUnconfirmed(ctx context.Context)
 }
 
+// purgeUnconfirmedSpec runs the unconfirmed subscriber cleanup hourly.
+const purgeUnconfirmedSpec = "30 * * * *"
+
 type Scheduler struct {
 	cronner  *cron.Cron
 	jobSvc   JobService
-	jobSpecs map[string]func()
+	jobSpecs map[string]func(ctx context.Context)
+
+	// ctx is handed to every job run and cancelled by Stop.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewScheduler(jobService JobService) *Scheduler {
@@ -23,18 +34,21 @@ func NewScheduler(jobService JobService) *Scheduler {
 		cron.Recover(cron.DefaultLogger),
 	))
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		cronner:  c,
 		jobSvc:   jobService,
-		jobSpecs: make(map[string]func()),
+		jobSpecs: make(map[string]func(ctx context.Context)),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
-func (s *Scheduler) AddJob(jobName, spec string, jobFunc func()) error {
+func (s *Scheduler) AddJob(jobName, spec string, jobFunc func(ctx context.Context)) error {
 	log.Printf("Adding job '%s' with spec '%s'", jobName, spec)
 	_, err := s.cronner.AddFunc(spec, func() {
 		log.Printf("Scheduler triggered job: %s", jobName)
-		jobFunc()
+		jobFunc(s.ctx)
 	})
 	if err != nil {
 		return err
@@ -47,13 +61,32 @@ func (s *Scheduler) addWeatherUpdatesJob(spec string) error {
 	return s.AddJob("SendWeatherUpdates", spec, s.jobSvc.SendWeatherUpdates)
 }
 
+func (s *Scheduler) addAirQualityAlertsJob(spec string) error {
+	return s.AddJob("CheckAirQualityAlerts", spec, s.jobSvc.CheckAirQualityAlerts)
+}
+
+func (s *Scheduler) addWeatherAlertsJob(spec string) error {
+	return s.AddJob("CheckWeatherAlerts", spec, s.jobSvc.CheckWeatherAlerts)
+}
+
+func (s *Scheduler) addNotificationRulesJob(spec string) error {
+	return s.AddJob("CheckNotificationRules", spec, s.jobSvc.CheckNotificationRules)
+}
+
+func (s *Scheduler) addPurgeUnconfirmedJob(spec string) error {
+	return s.AddJob("PurgeUnconfirmed", spec, s.jobSvc.PurgeUnconfirmed)
+}
+
 func (s *Scheduler) Start() {
 	log.Println("Cron scheduler starting...")
 	s.cronner.Start()
 }
 
+// Stop cancels the context of running jobs and stops scheduling new ones.
+// The returned context is done once the running jobs have returned.
 func (s *Scheduler) Stop() context.Context {
 	log.Println("Cron scheduler stopping...")
+	s.cancel()
 	return s.cronner.Stop()
 }
 
@@ -61,6 +94,19 @@ func (s *Scheduler) SetupAndStartDefaultJobs(weatherUpdateSpec string) error {
 	if err := s.addWeatherUpdatesJob(weatherUpdateSpec); err != nil {
 		return err
 	}
+	// alerts and notification rules are checked on every weather run so they go out within one interval
+	if err := s.addAirQualityAlertsJob(weatherUpdateSpec); err != nil {
+		return err
+	}
+	if err := s.addWeatherAlertsJob(weatherUpdateSpec); err != nil {
+		return err
+	}
+	if err := s.addNotificationRulesJob(weatherUpdateSpec); err != nil {
+		return err
+	}
+	if err := s.addPurgeUnconfirmedJob(purgeUnconfirmedSpec); err != nil {
+		return err
+	}
 	s.Start()
 	return nil
 }
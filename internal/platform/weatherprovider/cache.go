@@ -0,0 +1,242 @@
+package weatherprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"weather-app/internal/core"
+)
+
+// maxCacheEntries triggers a sweep of expired entries once the cache grows past it.
+const maxCacheEntries = 1000
+
+// upstreamFetchTimeout bounds a shared upstream call, which is detached from
+// any single caller's context so one disconnect doesn't fail the others.
+const upstreamFetchTimeout = 15 * time.Second
+
+type CacheStats struct {
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+	Coalesced    uint64 `json:"coalesced"`
+	Entries      int    `json:"entries"`
+}
+
+type cacheEntry struct {
+	value     interface{}
+	err       error
+	expiresAt time.Time
+}
+
+// flight is an in-progress upstream call that concurrent misses for the same key wait on.
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// CachingProvider keeps upstream answers for ttl, remembers ErrCityNotFound
+// for negativeTTL and collapses concurrent misses for the same key into a
+// single upstream call. Callers stop waiting when their own context ends.
+type CachingProvider struct {
+	next        WeatherProvider
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	flights map[string]*flight
+
+	hits, negativeHits, misses, coalesced atomic.Uint64
+}
+
+func NewCachingProvider(next WeatherProvider, ttl, negativeTTL time.Duration) *CachingProvider {
+	return &CachingProvider{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]cacheEntry),
+		flights:     make(map[string]*flight),
+	}
+}
+
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.TrimSpace(query))
+}
+
+func (c *CachingProvider) get(ctx context.Context, key string, load func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if c.now().Before(e.expiresAt) {
+			c.mu.Unlock()
+			if e.err != nil {
+				c.negativeHits.Add(1)
+			} else {
+				c.hits.Add(1)
+			}
+			return e.value, e.err
+		}
+		delete(c.entries, key)
+	}
+
+	f, inFlight := c.flights[key]
+	if inFlight {
+		c.coalesced.Add(1)
+	} else {
+		f = &flight{done: make(chan struct{})}
+		c.flights[key] = f
+		c.misses.Add(1)
+		go c.load(ctx, key, f, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *CachingProvider) load(ctx context.Context, key string, f *flight, load func(ctx context.Context) (interface{}, error)) {
+	loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), upstreamFetchTimeout)
+	defer cancel()
+
+	f.value, f.err = load(loadCtx)
+
+	c.mu.Lock()
+	delete(c.flights, key)
+	switch {
+	case f.err == nil:
+		c.storeLocked(key, cacheEntry{value: f.value, expiresAt: c.now().Add(c.ttl)})
+	case errors.Is(f.err, ErrCityNotFound):
+		c.storeLocked(key, cacheEntry{err: f.err, expiresAt: c.now().Add(c.negativeTTL)})
+	}
+	c.mu.Unlock()
+	close(f.done)
+}
+
+func (c *CachingProvider) storeLocked(key string, e cacheEntry) {
+	if len(c.entries) >= maxCacheEntries {
+		now := c.now()
+		for k, existing := range c.entries {
+			if !now.Before(existing.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = e
+}
+
+func (c *CachingProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	v, err := c.get(ctx, "weather:"+query.String(), func(ctx context.Context) (interface{}, error) {
+		return c.next.FetchWeather(ctx, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// hand out copies so callers can't modify the cached value
+	weather := *v.(*core.Weather)
+	return &weather, nil
+}
+
+func (c *CachingProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	key := fmt.Sprintf("forecast:%d:%s", days, query.String())
+	v, err := c.get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return c.next.FetchForecast(ctx, query, days)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cloneForecast(v.(*core.Forecast)), nil
+}
+
+func (c *CachingProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	query = query.WithLang("") // nothing in it is localized, so all languages share an entry
+	v, err := c.get(ctx, "aqi:"+query.String(), func(ctx context.Context) (interface{}, error) {
+		return c.next.FetchAirQuality(ctx, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	airQuality := *v.(*core.AirQuality)
+	return &airQuality, nil
+}
+
+func (c *CachingProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	query = query.WithLang("")
+	v, err := c.get(ctx, "alerts:"+query.String(), func(ctx context.Context) (interface{}, error) {
+		return c.next.FetchAlerts(ctx, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cached := v.([]core.WeatherAlert)
+	alerts := make([]core.WeatherAlert, len(cached))
+	copy(alerts, cached)
+	return alerts, nil
+}
+
+func (c *CachingProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	query = query.WithLang("")
+	v, err := c.get(ctx, fmt.Sprintf("astronomy:%s:%s", date, query.String()), func(ctx context.Context) (interface{}, error) {
+		return c.next.FetchAstronomy(ctx, query, date)
+	})
+	if err != nil {
+		return nil, err
+	}
+	astronomy := *v.(*core.Astronomy)
+	if astronomy.Sunrise != nil {
+		sunrise := *astronomy.Sunrise
+		astronomy.Sunrise = &sunrise
+	}
+	if astronomy.Sunset != nil {
+		sunset := *astronomy.Sunset
+		astronomy.Sunset = &sunset
+	}
+	return &astronomy, nil
+}
+
+func (c *CachingProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	v, err := c.get(ctx, "search:"+normalizeQuery(query), func(ctx context.Context) (interface{}, error) {
+		return c.next.SearchLocations(ctx, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cached := v.([]core.Location)
+	locations := make([]core.Location, len(cached))
+	copy(locations, cached)
+	return locations, nil
+}
+
+func cloneForecast(f *core.Forecast) *core.Forecast {
+	clone := *f
+	clone.Days = make([]core.ForecastDay, len(f.Days))
+	for i, d := range f.Days {
+		d.Hours = append([]core.ForecastHour(nil), d.Hours...)
+		clone.Days[i] = d
+	}
+	return &clone
+}
+
+func (c *CachingProvider) Stats() CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Coalesced:    c.coalesced.Load(),
+		Entries:      entries,
+	}
+}
@@ -0,0 +1,228 @@
+package weatherprovider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (p *countingProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &core.Weather{Temperature: 20, Humidity: 50, Description: "Sunny"}, nil
+}
+
+func (p *countingProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &core.Forecast{City: query.City, Days: make([]core.ForecastDay, days)}, nil
+}
+
+func (p *countingProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []core.WeatherAlert{}, nil
+}
+
+func (p *countingProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &core.AirQuality{PM25: 5, USEPAIndex: 1, DEFRAIndex: 1}, nil
+}
+
+func (p *countingProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []core.Location{{ID: "test:1", Name: query}}, nil
+}
+
+func (p *countingProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	astronomy, err := core.ComputeAstronomy(core.Location{Name: query.City, Lat: 50.45, Lon: 30.52}, date)
+	if err != nil {
+		return nil, err
+	}
+	astronomy.Computed = false // as if a backend had reported it
+	return astronomy, nil
+}
+
+func TestCachingProvider_HitsAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingProvider{}
+	cache := NewCachingProvider(inner, time.Minute, time.Hour)
+	now := time.Date(2025, 5, 20, 8, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.FetchWeather(ctx, core.CityQuery("London"))
+	require.NoError(t, err)
+	w, err := cache.FetchWeather(ctx, core.CityQuery(" london "))
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, w.Temperature)
+	assert.Equal(t, int32(1), inner.calls.Load(), "second lookup should be served from cache")
+
+	w.Temperature = -100
+	w, _ = cache.FetchWeather(ctx, core.CityQuery("London"))
+	assert.Equal(t, 20.0, w.Temperature, "cached value must not be mutated by callers")
+
+	_, err = cache.FetchForecast(ctx, core.CityQuery("London"), 3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.calls.Load(), "forecast uses its own key")
+
+	now = now.Add(2 * time.Minute)
+	_, err = cache.FetchWeather(ctx, core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), inner.calls.Load(), "expired entry should be refetched")
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+}
+
+func TestCachingProvider_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingProvider{err: ErrCityNotFound}
+	cache := NewCachingProvider(inner, time.Minute, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.FetchWeather(ctx, core.CityQuery("Atlantis"))
+		assert.ErrorIs(t, err, ErrCityNotFound)
+	}
+	assert.Equal(t, int32(1), inner.calls.Load())
+	assert.Equal(t, uint64(2), cache.Stats().NegativeHits)
+
+	inner.err = errors.New("upstream down")
+	for i := 0; i < 2; i++ {
+		_, err := cache.FetchWeather(ctx, core.CityQuery("London"))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(3), inner.calls.Load(), "transient errors are not cached")
+}
+
+func TestCachingProvider_CoalescesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingProvider{release: make(chan struct{})}
+	cache := NewCachingProvider(inner, time.Minute, time.Hour)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w, err := cache.FetchWeather(ctx, core.CityQuery("Kyiv"))
+			assert.NoError(t, err)
+			assert.Equal(t, "Sunny", w.Description)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return cache.Stats().Coalesced == callers-1
+	}, time.Second, time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
+
+func TestCachingProvider_CallerCancellation(t *testing.T) {
+	inner := &countingProvider{release: make(chan struct{})}
+	cache := NewCachingProvider(inner, time.Minute, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.FetchWeather(ctx, core.CityQuery("Kyiv"))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(inner.release)
+	require.Eventually(t, func() bool {
+		return cache.Stats().Entries == 1
+	}, time.Second, time.Millisecond, "the detached upstream call should still fill the cache")
+
+	w, err := cache.FetchWeather(context.Background(), core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny", w.Description)
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
+
+func TestCachingProvider_LanguageKeys(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingProvider{}
+	cache := NewCachingProvider(inner, time.Minute, time.Hour)
+
+	_, err := cache.FetchWeather(ctx, core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	_, err = cache.FetchWeather(ctx, core.CityQuery("Kyiv").WithLang("en"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), inner.calls.Load(), "English is the default language")
+
+	_, err = cache.FetchWeather(ctx, core.CityQuery("Kyiv").WithLang("uk"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.calls.Load(), "descriptions are cached per language")
+
+	_, err = cache.FetchAirQuality(ctx, core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	_, err = cache.FetchAirQuality(ctx, core.CityQuery("Kyiv").WithLang("uk"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), inner.calls.Load(), "air quality is the same in every language")
+}
+
+// halfDayProvider reports a sunrise but no sunset, as a backend may on the day
+// the midnight sun begins.
+type halfDayProvider struct {
+	countingProvider
+	sunrise time.Time
+}
+
+func (p *halfDayProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	p.calls.Add(1)
+	sunrise := p.sunrise
+	return &core.Astronomy{Date: date, Sunrise: &sunrise}, nil
+}
+
+func TestCachingProvider_AstronomyCopies(t *testing.T) {
+	ctx := context.Background()
+	upstream := &halfDayProvider{sunrise: time.Date(2025, 5, 20, 1, 30, 0, 0, time.UTC)}
+	cache := NewCachingProvider(upstream, time.Minute, time.Minute)
+
+	first, err := cache.FetchAstronomy(ctx, core.CityQuery("Tromso"), "2025-05-20")
+	require.NoError(t, err)
+	require.NotNil(t, first.Sunrise)
+	assert.Nil(t, first.Sunset)
+	*first.Sunrise = first.Sunrise.Add(time.Hour)
+
+	second, err := cache.FetchAstronomy(ctx, core.CityQuery("Tromso"), "2025-05-20")
+	require.NoError(t, err)
+	assert.Equal(t, upstream.sunrise, *second.Sunrise, "callers get their own copy")
+	assert.Nil(t, second.Sunset)
+	assert.Equal(t, int32(1), upstream.calls.Load())
+}
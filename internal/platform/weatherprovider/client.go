@@ -1,93 +1,688 @@
 package weatherprovider
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"weather-app/internal/core"
 )
 
-const weatherAPIBaseURL = "http://api.weatherapi.com/v1/current.json"
+const weatherAPIBaseURL = "http://api.weatherapi.com/v1"
+
+// MaxForecastDays is the longest forecast weatherapi.com can return.
+const MaxForecastDays = 14
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type weatherAPILocation struct {
+	Name    string  `json:"name"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	TzID    string  `json:"tz_id"`
+}
+
+func (l weatherAPILocation) toCore() core.Location {
+	return core.Location{
+		Name:     l.Name,
+		Region:   l.Region,
+		Country:  l.Country,
+		Lat:      l.Lat,
+		Lon:      l.Lon,
+		Timezone: l.TzID,
+	}
+}
 
 type WeatherAPIResponse struct {
+	Location weatherAPILocation `json:"location"`
+	Current  struct {
+		LastUpdatedEpoch int64               `json:"last_updated_epoch"`
+		TempC            float64             `json:"temp_c"`
+		FeelsLikeC       float64             `json:"feelslike_c"`
+		Humidity         int                 `json:"humidity"`
+		WindKph          float64             `json:"wind_kph"`
+		WindDegree       int                 `json:"wind_degree"`
+		WindDir          string              `json:"wind_dir"`
+		GustKph          float64             `json:"gust_kph"`
+		PressureMb       float64             `json:"pressure_mb"`
+		PrecipMM         float64             `json:"precip_mm"`
+		Cloud            int                 `json:"cloud"`
+		UV               float64             `json:"uv"`
+		VisKm            float64             `json:"vis_km"`
+		IsDay            int                 `json:"is_day"`
+		Condition        weatherAPICondition `json:"condition"`
+	} `json:"current"`
+	Error *apiError `json:"error,omitempty"`
+}
+
+type weatherAPICondition struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
+// weatherAPIConditions maps weatherapi.com condition codes
+// (https://www.weatherapi.com/docs/weather_conditions.json) to core.Condition.
+var weatherAPIConditions = map[int]core.Condition{
+	1000: core.ConditionClear,
+	1003: core.ConditionPartlyCloudy,
+	1006: core.ConditionCloudy,
+	1009: core.ConditionOvercast,
+	1030: core.ConditionFog, // mist
+	1063: core.ConditionRain,
+	1066: core.ConditionSnow,
+	1069: core.ConditionSleet,
+	1072: core.ConditionFreezingRain,
+	1087: core.ConditionThunder,
+	1114: core.ConditionSnow, // blowing snow
+	1117: core.ConditionHeavySnow,
+	1135: core.ConditionFog,
+	1147: core.ConditionFog,
+	1150: core.ConditionDrizzle,
+	1153: core.ConditionDrizzle,
+	1168: core.ConditionFreezingRain,
+	1171: core.ConditionFreezingRain,
+	1180: core.ConditionRain,
+	1183: core.ConditionRain,
+	1186: core.ConditionRain,
+	1189: core.ConditionRain,
+	1192: core.ConditionHeavyRain,
+	1195: core.ConditionHeavyRain,
+	1198: core.ConditionFreezingRain,
+	1201: core.ConditionFreezingRain,
+	1204: core.ConditionSleet,
+	1207: core.ConditionSleet,
+	1210: core.ConditionSnow,
+	1213: core.ConditionSnow,
+	1216: core.ConditionSnow,
+	1219: core.ConditionSnow,
+	1222: core.ConditionHeavySnow,
+	1225: core.ConditionHeavySnow,
+	1237: core.ConditionSleet, // ice pellets
+	1240: core.ConditionRain,
+	1243: core.ConditionHeavyRain,
+	1246: core.ConditionHeavyRain,
+	1249: core.ConditionSleet,
+	1252: core.ConditionSleet,
+	1255: core.ConditionSnow,
+	1258: core.ConditionHeavySnow,
+	1261: core.ConditionSleet,
+	1264: core.ConditionSleet,
+	1273: core.ConditionThunder,
+	1276: core.ConditionThunder,
+	1279: core.ConditionThunder,
+	1282: core.ConditionThunder,
+}
+
+func (c weatherAPICondition) condition() core.Condition {
+	if condition, ok := weatherAPIConditions[c.Code]; ok {
+		return condition
+	}
+	return core.ConditionUnknown
+}
+
+type airQualityAPIResponse struct {
+	Current struct {
+		AirQuality struct {
+			CO         float64 `json:"co"`
+			NO2        float64 `json:"no2"`
+			O3         float64 `json:"o3"`
+			SO2        float64 `json:"so2"`
+			PM25       float64 `json:"pm2_5"`
+			PM10       float64 `json:"pm10"`
+			USEPAIndex int     `json:"us-epa-index"`
+			DEFRAIndex int     `json:"gb-defra-index"`
+		} `json:"air_quality"`
+	} `json:"current"`
+}
+
+type alertsAPIResponse struct {
+	Alerts struct {
+		Alert []struct {
+			Headline    string `json:"headline"`
+			Severity    string `json:"severity"`
+			Urgency     string `json:"urgency"`
+			Areas       string `json:"areas"`
+			Certainty   string `json:"certainty"`
+			Event       string `json:"event"`
+			Effective   string `json:"effective"`
+			Expires     string `json:"expires"`
+			Desc        string `json:"desc"`
+			Instruction string `json:"instruction"`
+		} `json:"alert"`
+	} `json:"alerts"`
+}
+
+type searchAPIResult struct {
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+type ForecastAPIResponse struct {
 	Location struct {
 		Name string `json:"name"`
 	} `json:"location"`
-	Current struct {
-		TempC     float64 `json:"temp_c"`
-		Humidity  int     `json:"humidity"`
-		Condition struct {
-			Text string `json:"text"`
-		} `json:"condition"`
-	} `json:"current"`
-	Error *struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
+	Forecast struct {
+		ForecastDay []struct {
+			Date string `json:"date"`
+			Day  struct {
+				MaxTempC          float64             `json:"maxtemp_c"`
+				MinTempC          float64             `json:"mintemp_c"`
+				TotalPrecipMM     float64             `json:"totalprecip_mm"`
+				MaxWindKph        float64             `json:"maxwind_kph"`
+				AvgHumidity       float64             `json:"avghumidity"`
+				DailyChanceOfRain int                 `json:"daily_chance_of_rain"`
+				DailyChanceOfSnow int                 `json:"daily_chance_of_snow"`
+				Condition         weatherAPICondition `json:"condition"`
+			} `json:"day"`
+			Hour []struct {
+				TimeEpoch    int64               `json:"time_epoch"`
+				TempC        float64             `json:"temp_c"`
+				Humidity     int                 `json:"humidity"`
+				ChanceOfRain int                 `json:"chance_of_rain"`
+				IsDay        int                 `json:"is_day"`
+				Condition    weatherAPICondition `json:"condition"`
+			} `json:"hour"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+	Error *apiError `json:"error,omitempty"`
 }
 
+// WeatherProvider looks up weather for a place. Adapters return ErrNotSupported
+// for query kinds their upstream can't answer.
 type WeatherProvider interface {
-	FetchWeather(city string) (*core.Weather, error)
+	FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error)
+	FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error)
+	FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error)
+	// FetchAlerts returns the severe weather alerts in force. None is an empty slice, not an error.
+	FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error)
+	// SearchLocations returns matching places, best match first. No match is an empty slice, not an error.
+	SearchLocations(ctx context.Context, query string) ([]core.Location, error)
+	// FetchAstronomy returns the sun and moon on date (YYYY-MM-DD, local to the place).
+	FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error)
 }
 
 type Client struct {
 	apiKey     string
+	baseURL    string
 	httpClient *http.Client
+	quota      *QuotaLimiter
+
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	retryDeadline  time.Duration
 }
 
-func NewClient(apiKey string) *Client {
+// NewClient returns a weatherapi.com client that charges every request it
+// makes, retries included, to quota. A nil quota leaves it unmetered.
+func NewClient(apiKey string, quota *QuotaLimiter) *Client {
 	return &Client{
-		apiKey: apiKey,
+		apiKey:  apiKey,
+		baseURL: weatherAPIBaseURL,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: 5 * time.Second,
 		},
+		quota:          quota,
+		maxAttempts:    4,
+		retryBaseDelay: 250 * time.Millisecond,
+		retryMaxDelay:  4 * time.Second,
+		// stays below the cache's upstream timeout so the last error is still reported
+		retryDeadline: 12 * time.Second,
 	}
 }
 
 var (
-	ErrCityNotFound = fmt.Errorf("city not found")
-	ErrAPIRequest   = fmt.Errorf("weather API request failed")
+	ErrCityNotFound    = fmt.Errorf("city not found")
+	ErrAPIRequest      = fmt.Errorf("weather API request failed")
+	ErrInvalidForecast = fmt.Errorf("invalid forecast request")
+	ErrInvalidDate     = fmt.Errorf("invalid date")
+
+	// The errors below always come wrapped together with ErrAPIRequest.
+	ErrTimeout    = errors.New("weather API timed out")
+	ErrAuth       = errors.New("weather API key invalid or not allowed")
+	ErrUpstream   = errors.New("weather API server error")
+	ErrBadPayload = errors.New("malformed weather API response")
 )
 
-func (c *Client) FetchWeather(city string) (*core.Weather, error) {
-	params := url.Values{}
-	params.Add("key", c.apiKey)
-	params.Add("q", city)
-	params.Add("aqi", "no")
+// weatherapi.com error codes: https://www.weatherapi.com/docs/
+const (
+	weatherAPICodeKeyMissing    = 1002
+	weatherAPICodeNoLocation    = 1006
+	weatherAPICodeKeyInvalid    = 2006
+	weatherAPICodeQuotaExceeded = 2007
+	weatherAPICodeKeyDisabled   = 2008
+	weatherAPICodeNoAccess      = 2009
+	weatherAPICodeInternal      = 9999
+)
+
+// get calls a weatherapi.com endpoint and decodes the JSON body into out.
+// Timeouts, network errors, 5xx answers and 429s are retried with jittered
+// exponential backoff (or after Retry-After, if longer) until maxAttempts or
+// retryDeadline is reached, or the quota has no call left for another attempt.
+func (c *Client) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	params.Set("key", c.apiKey)
+	reqURL := fmt.Sprintf("%s/%s?%s", c.baseURL, endpoint, params.Encode())
 
-	reqURL := fmt.Sprintf("%s?%s", weatherAPIBaseURL, params.Encode())
+	ctx, cancel := context.WithTimeout(ctx, c.retryDeadline)
+	defer cancel()
 
-	resp, err := c.httpClient.Get(reqURL)
+	var err error
+	for attempt := 1; ; attempt++ {
+		if c.quota != nil {
+			if quotaErr := c.quota.Acquire(ctx); quotaErr != nil {
+				if err != nil {
+					// report why the last attempt failed rather than the budget
+					return err
+				}
+				return quotaErr
+			}
+		}
+
+		var retryable bool
+		retryable, err = c.getOnce(ctx, reqURL, out)
+		if err == nil || !retryable || attempt >= c.maxAttempts {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > delay {
+			delay = rateLimitErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		log.Printf("weatherapi.com %s attempt %d failed, retrying in %s: %v", endpoint, attempt, delay.Round(time.Millisecond), err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff picks a random delay up to retryBaseDelay * 2^(attempt-1), capped at retryMaxDelay.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.retryBaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > c.retryMaxDelay {
+		ceiling = c.retryMaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(ceiling))) + time.Millisecond
+}
+
+// getOnce makes a single request and reports whether its failure is worth retrying.
+func (c *Client) getOnce(ctx context.Context, reqURL string, out interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrAPIRequest, withoutURL(err))
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrAPIRequest, err)
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("%w: %w", ErrAPIRequest, ctx.Err())
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true, fmt.Errorf("%w: %w: %v", ErrAPIRequest, ErrTimeout, withoutURL(err))
+		}
+		return true, fmt.Errorf("%w: %v", ErrAPIRequest, withoutURL(err))
 	}
 	defer resp.Body.Close()
 
-	var apiResp WeatherAPIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("%w: failed to decode response: %v", ErrAPIRequest, err)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("%w: failed to read response: %v", ErrAPIRequest, err)
 	}
 
-	if apiResp.Error != nil {
-		// WeatherAPI.com error codes: https://www.weatherapi.com/docs/
-		if apiResp.Error.Code == 1006 {
-			return nil, ErrCityNotFound
+	var envelope struct {
+		Error *apiError `json:"error,omitempty"`
+	}
+	// search.json answers with a bare array on success; error pages from
+	// proxies in front of the API may not be JSON at all
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		json.Unmarshal(body, &envelope)
+	}
+
+	if envelope.Error != nil {
+		switch envelope.Error.Code {
+		case weatherAPICodeNoLocation:
+			return false, ErrCityNotFound
+		case weatherAPICodeKeyMissing, weatherAPICodeKeyInvalid, weatherAPICodeKeyDisabled, weatherAPICodeNoAccess:
+			return false, fmt.Errorf("%w: %w: %s (code: %d)", ErrAPIRequest, ErrAuth, envelope.Error.Message, envelope.Error.Code)
+		case weatherAPICodeQuotaExceeded:
+			// the monthly quota won't come back within our retry deadline
+			rateLimitErr := &RateLimitError{Reason: "weatherapi.com monthly quota exceeded", RetryAfter: retryAfter(resp)}
+			return false, fmt.Errorf("%w: %w", ErrAPIRequest, rateLimitErr)
+		case weatherAPICodeInternal:
+			return true, fmt.Errorf("%w: %w: %s (code: %d)", ErrAPIRequest, ErrUpstream, envelope.Error.Message, envelope.Error.Code)
 		}
-		return nil, fmt.Errorf("%w: %s (code: %d)", ErrAPIRequest, apiResp.Error.Message, apiResp.Error.Code)
+		return false, fmt.Errorf("%w: %s (code: %d)", ErrAPIRequest, envelope.Error.Message, envelope.Error.Code)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		rateLimitErr := &RateLimitError{Reason: "weatherapi.com rate limit", RetryAfter: retryAfter(resp)}
+		return true, fmt.Errorf("%w: %w", ErrAPIRequest, rateLimitErr)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return false, fmt.Errorf("%w: %w: received status %d", ErrAPIRequest, ErrAuth, resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("%w: %w: received status %d", ErrAPIRequest, ErrUpstream, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("%w: received status %d", ErrAPIRequest, resp.StatusCode)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: received status %d", ErrAPIRequest, resp.StatusCode)
+	if err := json.Unmarshal(body, out); err != nil {
+		return false, fmt.Errorf("%w: %w: %v", ErrAPIRequest, ErrBadPayload, err)
 	}
+	return false, nil
+}
+
+// withoutURL drops the request URL, which carries the API key, from the
+// *url.Error that net/http returns, so the error can be logged.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// retryAfter reads the Retry-After header, given either in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
 
+// weatherAPIQuery translates a query into weatherapi.com's q parameter.
+func weatherAPIQuery(query core.LocationQuery) string {
+	switch query.Kind {
+	case core.QueryByCoordinates:
+		return fmt.Sprintf("%.4f,%.4f", query.Lat, query.Lon)
+	case core.QueryByPostcode:
+		return query.Postcode
+	case core.QueryByAirport:
+		return "iata:" + query.Airport
+	case core.QueryByIP:
+		return query.IP
+	default:
+		return query.City
+	}
+}
+
+func (c *Client) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	params := url.Values{}
+	params.Add("q", weatherAPIQuery(query))
+	params.Add("aqi", "no")
+	if query.Lang != "" {
+		params.Add("lang", query.Lang) // condition text; weatherapi.com uses "uk" for Ukrainian too
+	}
+
+	var apiResp WeatherAPIResponse
+	if err := c.get(ctx, "current.json", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	cur := apiResp.Current
 	weather := &core.Weather{
-		Temperature: apiResp.Current.TempC,
-		Humidity:    float64(apiResp.Current.Humidity),
-		Description: apiResp.Current.Condition.Text,
+		Temperature:   cur.TempC,
+		Humidity:      float64(cur.Humidity),
+		Description:   cur.Condition.Text,
+		Condition:     cur.Condition.condition(),
+		IsDay:         cur.IsDay == 1,
+		FeelsLike:     cur.FeelsLikeC,
+		WindSpeed:     cur.WindKph,
+		WindGust:      cur.GustKph,
+		WindDegree:    cur.WindDegree,
+		WindDirection: cur.WindDir,
+		Pressure:      cur.PressureMb,
+		Precipitation: cur.PrecipMM,
+		CloudCover:    cur.Cloud,
+		UVIndex:       cur.UV,
+		Visibility:    cur.VisKm,
+		ObservedAt:    time.Unix(cur.LastUpdatedEpoch, 0).UTC(),
+		Location:      apiResp.Location.toCore(),
+	}
+	if weather.WindDirection == "" {
+		weather.WindDirection = compassDirection(cur.WindDegree)
 	}
+	weather.Icon = weather.Condition.Icon(weather.IsDay)
 
 	return weather, nil
 }
+
+func (c *Client) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	if days < 1 || days > MaxForecastDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidForecast, MaxForecastDays)
+	}
+
+	params := url.Values{}
+	params.Add("q", weatherAPIQuery(query))
+	params.Add("days", strconv.Itoa(days))
+	params.Add("aqi", "no")
+	params.Add("alerts", "no")
+	if query.Lang != "" {
+		params.Add("lang", query.Lang)
+	}
+
+	var apiResp ForecastAPIResponse
+	if err := c.get(ctx, "forecast.json", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	forecast := &core.Forecast{
+		City: apiResp.Location.Name,
+		Days: make([]core.ForecastDay, 0, len(apiResp.Forecast.ForecastDay)),
+	}
+	for _, fd := range apiResp.Forecast.ForecastDay {
+		day := core.ForecastDay{
+			Date:           fd.Date,
+			MaxTemperature: fd.Day.MaxTempC,
+			MinTemperature: fd.Day.MinTempC,
+			AvgHumidity:    fd.Day.AvgHumidity,
+			TotalPrecip:    fd.Day.TotalPrecipMM,
+			MaxWindSpeed:   fd.Day.MaxWindKph,
+			ChanceOfRain:   fd.Day.DailyChanceOfRain,
+			ChanceOfSnow:   fd.Day.DailyChanceOfSnow,
+			Description:    fd.Day.Condition.Text,
+			Condition:      fd.Day.Condition.condition(),
+			Icon:           fd.Day.Condition.condition().Icon(true),
+			Hours:          make([]core.ForecastHour, 0, len(fd.Hour)),
+		}
+		for _, h := range fd.Hour {
+			day.Hours = append(day.Hours, core.ForecastHour{
+				Time:         time.Unix(h.TimeEpoch, 0).UTC(),
+				Temperature:  h.TempC,
+				Humidity:     float64(h.Humidity),
+				ChanceOfRain: h.ChanceOfRain,
+				Description:  h.Condition.Text,
+				Condition:    h.Condition.condition(),
+				IsDay:        h.IsDay == 1,
+				Icon:         h.Condition.condition().Icon(h.IsDay == 1),
+			})
+		}
+		forecast.Days = append(forecast.Days, day)
+	}
+
+	return forecast, nil
+}
+
+func (c *Client) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	params := url.Values{}
+	params.Add("q", query)
+
+	var results []searchAPIResult
+	if err := c.get(ctx, "search.json", params, &results); err != nil {
+		if errors.Is(err, ErrCityNotFound) {
+			return []core.Location{}, nil
+		}
+		return nil, err
+	}
+
+	locations := make([]core.Location, 0, len(results))
+	for _, r := range results {
+		locations = append(locations, core.Location{
+			ID:      fmt.Sprintf("weatherapi:%d", r.ID),
+			Name:    r.Name,
+			Region:  r.Region,
+			Country: r.Country,
+			Lat:     r.Lat,
+			Lon:     r.Lon,
+		})
+	}
+	return locations, nil
+}
+
+func (c *Client) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	params := url.Values{}
+	params.Add("q", weatherAPIQuery(query))
+	params.Add("aqi", "yes")
+
+	var apiResp airQualityAPIResponse
+	if err := c.get(ctx, "current.json", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	aq := apiResp.Current.AirQuality
+	return &core.AirQuality{
+		PM25:       aq.PM25,
+		PM10:       aq.PM10,
+		O3:         aq.O3,
+		NO2:        aq.NO2,
+		CO:         aq.CO,
+		SO2:        aq.SO2,
+		USEPAIndex: aq.USEPAIndex,
+		DEFRAIndex: aq.DEFRAIndex,
+	}, nil
+}
+
+func (c *Client) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	params := url.Values{}
+	params.Add("q", weatherAPIQuery(query))
+
+	var apiResp alertsAPIResponse
+	if err := c.get(ctx, "alerts.json", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]core.WeatherAlert, 0, len(apiResp.Alerts.Alert))
+	for _, a := range apiResp.Alerts.Alert {
+		// weatherapi.com alerts carry no identifier; an update to a warning
+		// keeps its event and areas, so those identify it
+		id := sha256.Sum256([]byte(a.Event + "\x00" + a.Areas))
+		effective, _ := time.Parse(time.RFC3339, a.Effective)
+		expires, _ := time.Parse(time.RFC3339, a.Expires)
+		alerts = append(alerts, core.WeatherAlert{
+			ID:          "weatherapi:" + hex.EncodeToString(id[:8]),
+			Event:       a.Event,
+			Headline:    a.Headline,
+			Severity:    a.Severity,
+			Urgency:     a.Urgency,
+			Certainty:   a.Certainty,
+			Areas:       a.Areas,
+			Description: a.Desc,
+			Instruction: a.Instruction,
+			Effective:   effective,
+			Expires:     expires,
+		})
+	}
+	return alerts, nil
+}
+
+// weatherAPIClockLayout parses a date joined with weatherapi.com's local clock times.
+const weatherAPIClockLayout = "2006-01-02 03:04 PM"
+
+// checkDate makes sure date is a YYYY-MM-DD calendar date.
+func checkDate(date string) error {
+	if _, err := time.Parse(core.DateLayout, date); err != nil {
+		return fmt.Errorf("%w: %q is not a YYYY-MM-DD date", ErrInvalidDate, date)
+	}
+	return nil
+}
+
+type astronomyAPIResponse struct {
+	Location  weatherAPILocation `json:"location"`
+	Astronomy struct {
+		Astro struct {
+			Sunrise   string `json:"sunrise"` // e.g. "04:47 AM", or "No sunrise"
+			Sunset    string `json:"sunset"`
+			MoonPhase string `json:"moon_phase"`
+			// a number, though older API versions send it as a string
+			MoonIllumination json.Number `json:"moon_illumination"`
+		} `json:"astro"`
+	} `json:"astronomy"`
+}
+
+// FetchAstronomy uses weatherapi.com's sun and moon times. Polar days and
+// nights, which it reports without times, get their day length from
+// core.ComputeAstronomy.
+func (c *Client) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	if err := checkDate(date); err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Add("q", weatherAPIQuery(query))
+	params.Add("dt", date)
+
+	var apiResp astronomyAPIResponse
+	if err := c.get(ctx, "astronomy.json", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	astronomy, err := core.ComputeAstronomy(apiResp.Location.toCore(), date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+	astronomy.Computed = false
+
+	astro := apiResp.Astronomy.Astro
+	tz := time.UTC
+	if l, err := time.LoadLocation(apiResp.Location.TzID); err == nil {
+		tz = l
+	}
+	sunrise, errRise := time.ParseInLocation(weatherAPIClockLayout, date+" "+astro.Sunrise, tz)
+	sunset, errSet := time.ParseInLocation(weatherAPIClockLayout, date+" "+astro.Sunset, tz)
+	if errRise == nil && errSet == nil {
+		astronomy.Sunrise, astronomy.Sunset = &sunrise, &sunset
+		astronomy.DayLengthMinutes = int(sunset.Sub(sunrise).Minutes())
+	}
+	if phase := core.ParseMoonPhase(astro.MoonPhase); phase != "" {
+		astronomy.MoonPhase = phase
+	}
+	if illumination, err := strconv.Atoi(astro.MoonIllumination.String()); err == nil {
+		astronomy.MoonIllumination = illumination
+	}
+	return astronomy, nil
+}
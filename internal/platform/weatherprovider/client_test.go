@@ -0,0 +1,303 @@
+package weatherprovider
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const currentWeatherBody = `{"location":{"name":"London"},"current":{"temp_c":15.5,"humidity":60,"condition":{"text":"Cloudy"}}}`
+
+type stubResponse struct {
+	status  int
+	header  http.Header
+	body    string
+	latency time.Duration
+}
+
+// newRetryingClient serves responses in order, repeating the last one, from a
+// client that retries almost immediately.
+func newRetryingClient(t *testing.T, responses ...stubResponse) (*Client, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		resp := responses[min(n, len(responses))-1]
+		time.Sleep(resp.latency)
+		for k, v := range resp.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.status)
+		w.Write([]byte(resp.body))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient("test-key", nil)
+	c.baseURL = srv.URL
+	c.retryBaseDelay = time.Millisecond
+	c.retryMaxDelay = 5 * time.Millisecond
+	return c, &calls
+}
+
+func TestClient_RetriesTransientFailures(t *testing.T) {
+	c, calls := newRetryingClient(t,
+		stubResponse{status: http.StatusBadGateway, body: "<html>bad gateway</html>"},
+		stubResponse{status: http.StatusServiceUnavailable, body: `{"error":{"code":9999,"message":"Internal application error."}}`},
+		stubResponse{status: http.StatusOK, body: currentWeatherBody},
+	)
+
+	weather, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, 15.5, weather.Temperature)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestClient_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      stubResponse
+		expectedErr   error
+		expectedCalls int32
+	}{
+		{
+			name:          "upstream 5xx after all attempts",
+			response:      stubResponse{status: http.StatusInternalServerError},
+			expectedErr:   ErrUpstream,
+			expectedCalls: 4,
+		},
+		{
+			name:          "invalid key",
+			response:      stubResponse{status: http.StatusUnauthorized, body: `{"error":{"code":2006,"message":"API key is invalid."}}`},
+			expectedErr:   ErrAuth,
+			expectedCalls: 1,
+		},
+		{
+			name:          "forbidden without error body",
+			response:      stubResponse{status: http.StatusForbidden},
+			expectedErr:   ErrAuth,
+			expectedCalls: 1,
+		},
+		{
+			name:          "monthly quota exceeded",
+			response:      stubResponse{status: http.StatusForbidden, body: `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`},
+			expectedErr:   ErrRateLimited,
+			expectedCalls: 1,
+		},
+		{
+			name:          "malformed body",
+			response:      stubResponse{status: http.StatusOK, body: `{"current":`},
+			expectedErr:   ErrBadPayload,
+			expectedCalls: 1,
+		},
+		{
+			name:          "city not found",
+			response:      stubResponse{status: http.StatusBadRequest, body: `{"error":{"code":1006,"message":"No matching location found."}}`},
+			expectedErr:   ErrCityNotFound,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, calls := newRetryingClient(t, tc.response)
+
+			_, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != ErrCityNotFound {
+				assert.ErrorIs(t, err, ErrAPIRequest)
+			}
+			assert.Equal(t, tc.expectedCalls, atomic.LoadInt32(calls))
+		})
+	}
+}
+
+func TestClient_TimeoutIsRetried(t *testing.T) {
+	c, calls := newRetryingClient(t,
+		stubResponse{status: http.StatusOK, body: currentWeatherBody, latency: 100 * time.Millisecond},
+		stubResponse{status: http.StatusOK, body: currentWeatherBody},
+	)
+	c.httpClient.Timeout = 20 * time.Millisecond
+
+	_, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	c.maxAttempts = 1
+	atomic.StoreInt32(calls, 0)
+	_, err = c.FetchWeather(context.Background(), core.CityQuery("London"))
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestClient_HonorsRetryAfter(t *testing.T) {
+	c, calls := newRetryingClient(t,
+		stubResponse{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"1"}}},
+		stubResponse{status: http.StatusOK, body: currentWeatherBody},
+	)
+
+	start := time.Now()
+	_, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	// a Retry-After beyond the total deadline returns the rate limit right away
+	c, calls = newRetryingClient(t, stubResponse{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"60"}}})
+	_, err = c.FetchWeather(context.Background(), core.CityQuery("London"))
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, time.Minute, rateLimitErr.RetryAfter)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestClient_StopsAtTotalDeadline(t *testing.T) {
+	c, calls := newRetryingClient(t, stubResponse{status: http.StatusServiceUnavailable})
+	c.maxAttempts = 100
+	c.retryDeadline = 50 * time.Millisecond
+	c.retryBaseDelay = 20 * time.Millisecond
+	c.retryMaxDelay = 20 * time.Millisecond
+
+	start := time.Now()
+	_, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+	assert.ErrorIs(t, err, ErrAPIRequest)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Less(t, atomic.LoadInt32(calls), int32(100))
+}
+
+func TestClient_TransportErrorsHideKey(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // every attempt is refused
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c := NewClient("secret-test-key", nil)
+	c.baseURL = srv.URL
+	c.retryBaseDelay = time.Millisecond
+	c.retryMaxDelay = 5 * time.Millisecond
+
+	_, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.ErrorIs(t, err, ErrAPIRequest)
+	assert.NotContains(t, err.Error(), "secret-test-key")
+	assert.Contains(t, logs.String(), "retrying", "the failed attempts are logged")
+	assert.NotContains(t, logs.String(), "secret-test-key")
+
+	owm := NewOpenWeatherMapClient("secret-test-key")
+	owm.baseURL = srv.URL
+	_, err = owm.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.ErrorIs(t, err, ErrAPIRequest)
+	assert.NotContains(t, err.Error(), "secret-test-key")
+}
+
+func TestClient_SearchLocations(t *testing.T) {
+	c, _ := newWeatherAPIStub(t, http.StatusOK,
+		`[{"id":2801268,"name":"Kyiv","region":"Kyyivs'ka Oblast'","country":"Ukraine","lat":50.43,"lon":30.52}]`)
+
+	locations, err := c.SearchLocations(context.Background(), "kiev")
+	require.NoError(t, err)
+	require.Len(t, locations, 1)
+	assert.Equal(t, "weatherapi:2801268", locations[0].ID)
+	assert.Equal(t, "Kyiv", locations[0].Name)
+	assert.Equal(t, "50.4300,30.5200", locations[0].Coordinates())
+}
+
+func TestClient_FetchAlerts(t *testing.T) {
+	body := `{"alerts":{"alert":[{"headline":"Flood Warning issued","severity":"Moderate","urgency":"Expected",
+		"areas":"Kyiv","certainty":"Likely","event":"Flood Warning","effective":"2025-05-20T08:00:00+00:00",
+		"expires":"2025-05-21T08:00:00+00:00","desc":"River levels rising.","instruction":"Avoid low ground."}]}}`
+	c, _ := newWeatherAPIStub(t, http.StatusOK, body)
+
+	alerts, err := c.FetchAlerts(context.Background(), core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "Flood Warning", alerts[0].Event)
+	assert.Equal(t, time.Date(2025, 5, 21, 8, 0, 0, 0, time.UTC), alerts[0].Expires.UTC())
+
+	// an update keeps the ID but changes the fingerprint
+	updated, _ := newWeatherAPIStub(t, http.StatusOK, strings.Replace(body, "2025-05-21T08", "2025-05-22T08", 1))
+	updatedAlerts, err := updated.FetchAlerts(context.Background(), core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	require.Len(t, updatedAlerts, 1)
+	assert.Equal(t, alerts[0].ID, updatedAlerts[0].ID)
+	assert.NotEqual(t, alerts[0].Fingerprint(), updatedAlerts[0].Fingerprint())
+}
+
+func TestClient_LocalizedDescriptions(t *testing.T) {
+	ctx := context.Background()
+	query := core.CityQuery("Kyiv").WithLang("uk")
+
+	var lang string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang = r.URL.Query().Get("lang")
+		w.Write([]byte(`{"location":{"name":"Kyiv"},"current":{"temp_c":18,"condition":{"text":"Сонячно"}}}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient("test-key", nil)
+	c.baseURL = srv.URL
+
+	weather, err := c.FetchWeather(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, "uk", lang)
+	assert.Equal(t, "Сонячно", weather.Description)
+
+	_, err = c.FetchWeather(ctx, core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	assert.Empty(t, lang, "English is the default and isn't sent")
+}
+
+func TestClient_FetchAstronomy(t *testing.T) {
+	c, _ := newWeatherAPIStub(t, http.StatusOK, `{"location":{"name":"Kyiv","lat":50.45,"lon":30.52,"tz_id":"Europe/Kyiv"},
+		"astronomy":{"astro":{"sunrise":"04:47 AM","sunset":"09:13 PM","moon_phase":"Waning Crescent","moon_illumination":"18"}}}`)
+
+	astronomy, err := c.FetchAstronomy(context.Background(), core.CityQuery("Kyiv"), "2025-06-21")
+	require.NoError(t, err)
+	assert.False(t, astronomy.Computed)
+	require.NotNil(t, astronomy.Sunrise)
+	assert.Equal(t, "04:47", astronomy.Sunrise.Format("15:04"))
+	assert.Equal(t, "Europe/Kyiv", astronomy.Sunrise.Location().String())
+	assert.Equal(t, "21:13", astronomy.Sunset.Format("15:04"))
+	assert.Equal(t, 16*60+26, astronomy.DayLengthMinutes)
+	assert.Equal(t, core.WaningCrescent, astronomy.MoonPhase)
+	assert.Equal(t, 18, astronomy.MoonIllumination)
+
+	_, err = c.FetchAstronomy(context.Background(), core.CityQuery("Kyiv"), "tomorrow")
+	assert.ErrorIs(t, err, ErrInvalidDate)
+}
+
+func TestClient_NormalizedConditions(t *testing.T) {
+	ctx := context.Background()
+
+	c, _ := newWeatherAPIStub(t, http.StatusOK, `{"location":{"name":"Kyiv"},
+		"current":{"is_day":0,"condition":{"text":"Clear","code":1000}}}`)
+	weather, err := c.FetchWeather(ctx, core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	assert.Equal(t, core.ConditionClear, weather.Condition)
+	assert.False(t, weather.IsDay)
+	assert.Equal(t, "clear-night", weather.Icon)
+
+	c, _ = newWeatherAPIStub(t, http.StatusOK, `{"location":{"name":"Kyiv"},"forecast":{"forecastday":[{"date":"2025-05-20",
+		"day":{"condition":{"text":"Patchy light rain with thunder","code":1273}},
+		"hour":[{"time_epoch":1747717200,"is_day":1,"condition":{"text":"Partly Cloudy","code":1003}}]}]}}`)
+	forecast, err := c.FetchForecast(ctx, core.CityQuery("Kyiv"), 1)
+	require.NoError(t, err)
+	require.Len(t, forecast.Days, 1)
+	assert.Equal(t, core.ConditionThunder, forecast.Days[0].Condition)
+	assert.Equal(t, "thunder", forecast.Days[0].Icon)
+	require.Len(t, forecast.Days[0].Hours, 1)
+	assert.Equal(t, "partly-cloudy-day", forecast.Days[0].Hours[0].Icon)
+
+	// unmapped codes are unknown
+	assert.Equal(t, core.ConditionUnknown, weatherAPICondition{Code: 4242}.condition())
+}
@@ -0,0 +1,18 @@
+package weatherprovider
+
+import "math"
+
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// compassDirection converts a wind bearing in degrees to a 16-point compass label.
+func compassDirection(degree int) string {
+	normalized := math.Mod(float64(degree), 360)
+	if normalized < 0 {
+		normalized += 360
+	}
+	idx := int(math.Round(normalized/22.5)) % len(compassPoints)
+	return compassPoints[idx]
+}
@@ -0,0 +1,235 @@
+package weatherprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"weather-app/internal/core"
+)
+
+var (
+	ErrNotSupported = errors.New("operation not supported by weather backend")
+	ErrCircuitOpen  = errors.New("weather backend circuit is open")
+)
+
+// Backend is a named WeatherProvider taking part in a failover chain.
+type Backend struct {
+	Name     string
+	Provider WeatherProvider
+}
+
+type BackendHealth struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+}
+
+type failoverBackend struct {
+	Backend
+	breaker *CircuitBreaker
+
+	mu            sync.Mutex
+	lastError     string
+	lastFailureAt *time.Time
+}
+
+// FailoverProvider tries its backends in order and returns the first usable
+// answer. Each backend sits behind its own circuit breaker so a dead upstream
+// is skipped quickly instead of costing a timeout on every call.
+type FailoverProvider struct {
+	backends []*failoverBackend
+}
+
+func NewFailoverProvider(failureThreshold int, cooldown time.Duration, backends ...Backend) *FailoverProvider {
+	fp := &FailoverProvider{}
+	for _, b := range backends {
+		fp.backends = append(fp.backends, &failoverBackend{
+			Backend: b,
+			breaker: NewCircuitBreaker(failureThreshold, cooldown),
+		})
+	}
+	return fp
+}
+
+// isDefinitive reports errors that are a valid answer rather than a backend
+// failure; asking another backend would not change the outcome.
+func isDefinitive(err error) bool {
+	return errors.Is(err, ErrCityNotFound) || errors.Is(err, ErrInvalidForecast) || errors.Is(err, ErrInvalidDate)
+}
+
+func (f *FailoverProvider) do(ctx context.Context, op string, call func(p WeatherProvider) error) error {
+	if len(f.backends) == 0 {
+		return fmt.Errorf("%w: no weather backends configured", ErrAPIRequest)
+	}
+
+	var errs []error
+	var rateLimited *RateLimitError
+	allUnsupported := true
+	for _, b := range f.backends {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !b.breaker.Allow() {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name, ErrCircuitOpen))
+			allUnsupported = false
+			continue
+		}
+
+		err := call(b.Provider)
+		switch {
+		case err == nil || isDefinitive(err):
+			b.breaker.RecordSuccess()
+			return err
+		case errors.Is(err, ErrNotSupported):
+			b.breaker.Release()
+		case errors.As(err, &rateLimited):
+			// an exhausted budget is not a sign of an unhealthy backend
+			allUnsupported = false
+			b.breaker.Release()
+		case ctx.Err() != nil:
+			// the caller gave up; that says nothing about the backend's health
+			b.breaker.Release()
+			return err
+		default:
+			allUnsupported = false
+			b.recordFailure(err)
+			log.Printf("Weather backend %s failed on %s (breaker %s): %v", b.Name, op, b.breaker.State(), err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+	}
+
+	if allUnsupported {
+		return fmt.Errorf("%w: no weather backend supports %s", ErrNotSupported, op)
+	}
+	if rateLimited != nil {
+		// let callers tell the client when to come back
+		return fmt.Errorf("%w: %w: all weather backends failed: %v", ErrAPIRequest, rateLimited, errors.Join(errs...))
+	}
+	return fmt.Errorf("%w: all weather backends failed: %v", ErrAPIRequest, errors.Join(errs...))
+}
+
+func (b *failoverBackend) recordFailure(err error) {
+	b.breaker.RecordFailure()
+
+	now := time.Now().UTC()
+	b.mu.Lock()
+	b.lastError = err.Error()
+	b.lastFailureAt = &now
+	b.mu.Unlock()
+}
+
+func (f *FailoverProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	var weather *core.Weather
+	err := f.do(ctx, "FetchWeather", func(p WeatherProvider) error {
+		var err error
+		weather, err = p.FetchWeather(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return weather, nil
+}
+
+func (f *FailoverProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	var forecast *core.Forecast
+	err := f.do(ctx, "FetchForecast", func(p WeatherProvider) error {
+		var err error
+		forecast, err = p.FetchForecast(ctx, query, days)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return forecast, nil
+}
+
+// Health returns a snapshot of every backend's breaker state, in failover order.
+func (f *FailoverProvider) Health() []BackendHealth {
+	health := make([]BackendHealth, 0, len(f.backends))
+	for _, b := range f.backends {
+		b.mu.Lock()
+		h := BackendHealth{
+			Name:                b.Name,
+			State:               b.breaker.State().String(),
+			ConsecutiveFailures: b.breaker.ConsecutiveFailures(),
+			LastError:           b.lastError,
+			LastFailureAt:       b.lastFailureAt,
+		}
+		b.mu.Unlock()
+		health = append(health, h)
+	}
+	return health
+}
+
+func (f *FailoverProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	var locations []core.Location
+	err := f.do(ctx, "SearchLocations", func(p WeatherProvider) error {
+		var err error
+		locations, err = p.SearchLocations(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+func (f *FailoverProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	var airQuality *core.AirQuality
+	err := f.do(ctx, "FetchAirQuality", func(p WeatherProvider) error {
+		var err error
+		airQuality, err = p.FetchAirQuality(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return airQuality, nil
+}
+
+func (f *FailoverProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	var alerts []core.WeatherAlert
+	err := f.do(ctx, "FetchAlerts", func(p WeatherProvider) error {
+		var err error
+		alerts, err = p.FetchAlerts(ctx, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// FetchAstronomy calculates the sun and moon locally when no backend can
+// answer a coordinates query, since that needs nothing but the coordinates.
+// The result's times are then in UTC.
+func (f *FailoverProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	var astronomy *core.Astronomy
+	err := f.do(ctx, "FetchAstronomy", func(p WeatherProvider) error {
+		var err error
+		astronomy, err = p.FetchAstronomy(ctx, query, date)
+		return err
+	})
+	if err == nil {
+		return astronomy, nil
+	}
+	if isDefinitive(err) || ctx.Err() != nil || query.Kind != core.QueryByCoordinates {
+		return nil, err
+	}
+
+	log.Printf("Computing astronomy for %s locally: %v", query, err)
+	loc := core.Location{Lat: query.Lat, Lon: query.Lon}
+	loc.Name = loc.Coordinates()
+	astronomy, computeErr := core.ComputeAstronomy(loc, date)
+	if computeErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDate, computeErr)
+	}
+	return astronomy, nil
+}
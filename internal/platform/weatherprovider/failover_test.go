@@ -0,0 +1,171 @@
+package weatherprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWeatherAPIStub(t *testing.T, status int, body string) (*Client, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient("test-key", nil)
+	c.baseURL = srv.URL
+	// failover tests count upstream calls; retries are covered in client_test.go
+	c.maxAttempts = 1
+	return c, &calls
+}
+
+func TestFailoverProvider_FallsBackToNextBackend(t *testing.T) {
+	weatherAPI, _ := newWeatherAPIStub(t, http.StatusInternalServerError, `{}`)
+	fp := NewFailoverProvider(3, time.Minute,
+		Backend{Name: "weatherapi", Provider: weatherAPI},
+		Backend{Name: "openmeteo", Provider: newOpenMeteoStub(t)},
+	)
+
+	weather, err := fp.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, 12.3, weather.Temperature)
+	assert.Equal(t, 81.0, weather.Humidity)
+	assert.Equal(t, "Slight rain", weather.Description)
+
+	forecast, err := fp.FetchForecast(context.Background(), core.CityQuery("London"), 1)
+	require.NoError(t, err)
+	require.Len(t, forecast.Days, 1)
+	assert.Equal(t, "2025-05-20", forecast.Days[0].Date)
+	assert.Equal(t, 17.2, forecast.Days[0].MaxTemperature)
+	assert.Equal(t, 65, forecast.Days[0].ChanceOfRain)
+
+	health := fp.Health()
+	assert.Equal(t, 2, health[0].ConsecutiveFailures)
+	assert.Equal(t, "closed", health[1].State)
+}
+
+func TestFailoverProvider_CityNotFoundIsNotRetried(t *testing.T) {
+	weatherAPI, _ := newWeatherAPIStub(t, http.StatusBadRequest, `{"error":{"code":1006,"message":"No matching location found."}}`)
+	owm := newOpenWeatherMapStub(t)
+	fp := NewFailoverProvider(1, time.Minute,
+		Backend{Name: "weatherapi", Provider: weatherAPI},
+		Backend{Name: "openweathermap", Provider: owm},
+	)
+
+	_, err := fp.FetchWeather(context.Background(), core.CityQuery("Atlantis"))
+	assert.ErrorIs(t, err, ErrCityNotFound)
+	assert.Equal(t, "closed", fp.Health()[0].State)
+}
+
+func TestFailoverProvider_OpensCircuitAfterThreshold(t *testing.T) {
+	weatherAPI, calls := newWeatherAPIStub(t, http.StatusServiceUnavailable, `{}`)
+	fp := NewFailoverProvider(2, time.Minute,
+		Backend{Name: "weatherapi", Provider: weatherAPI},
+		Backend{Name: "openweathermap", Provider: newOpenWeatherMapStub(t)},
+	)
+
+	for i := 0; i < 4; i++ {
+		weather, err := fp.FetchWeather(context.Background(), core.CityQuery("London"))
+		require.NoError(t, err)
+		assert.Equal(t, "Light rain", weather.Description)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls), "open breaker should stop calls to the failing backend")
+	assert.Equal(t, "open", fp.Health()[0].State)
+}
+
+func TestFailoverProvider_AllBackendsFail(t *testing.T) {
+	first, _ := newWeatherAPIStub(t, http.StatusInternalServerError, `{}`)
+	second, _ := newWeatherAPIStub(t, http.StatusBadGateway, `{}`)
+	fp := NewFailoverProvider(3, time.Minute,
+		Backend{Name: "primary", Provider: first},
+		Backend{Name: "secondary", Provider: second},
+	)
+
+	_, err := fp.FetchWeather(context.Background(), core.CityQuery("London"))
+	assert.ErrorIs(t, err, ErrAPIRequest)
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	now := time.Date(2025, 5, 20, 8, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(1, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	require.True(t, b.Allow())
+	b.RecordFailure()
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+
+	now = now.Add(31 * time.Second)
+	assert.True(t, b.Allow(), "first call after cooldown is the probe")
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.False(t, b.Allow(), "only one probe at a time")
+
+	b.RecordFailure()
+	assert.Equal(t, BreakerOpen, b.State())
+
+	now = now.Add(31 * time.Second)
+	require.True(t, b.Allow())
+	b.RecordSuccess()
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+func TestFailoverProvider_UnsupportedQueryKind(t *testing.T) {
+	fp := NewFailoverProvider(3, time.Minute,
+		Backend{Name: "openmeteo", Provider: newOpenMeteoStub(t)},
+		Backend{Name: "openweathermap", Provider: newOpenWeatherMapStub(t)},
+	)
+
+	_, err := fp.FetchWeather(context.Background(), core.AirportQuery("LHR"))
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.NotErrorIs(t, err, ErrAPIRequest)
+	assert.Equal(t, "closed", fp.Health()[0].State)
+}
+
+func TestFailoverProvider_AlertsSkipUnsupportedBackends(t *testing.T) {
+	c, _ := newWeatherAPIStub(t, http.StatusOK, `{"alerts":{"alert":[]}}`)
+	fp := NewFailoverProvider(3, time.Minute,
+		Backend{Name: "openmeteo", Provider: newOpenMeteoStub(t)},
+		Backend{Name: "weatherapi", Provider: c},
+	)
+
+	alerts, err := fp.FetchAlerts(context.Background(), core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+	assert.Equal(t, "closed", fp.Health()[0].State)
+}
+
+func TestFailoverProvider_AstronomyComputedWhenBackendsFail(t *testing.T) {
+	weatherAPI, _ := newWeatherAPIStub(t, http.StatusInternalServerError, `{}`)
+	fp := NewFailoverProvider(3, time.Minute,
+		Backend{Name: "weatherapi", Provider: weatherAPI},
+		Backend{Name: "openweathermap", Provider: newOpenWeatherMapStub(t)},
+	)
+
+	astronomy, err := fp.FetchAstronomy(context.Background(), core.CoordinatesQuery(50.45, 30.52), "2025-06-21")
+	require.NoError(t, err)
+	assert.True(t, astronomy.Computed)
+	assert.Equal(t, "50.4500,30.5200", astronomy.Location.Name)
+	require.NotNil(t, astronomy.Sunrise)
+	assert.WithinDuration(t, time.Date(2025, 6, 21, 1, 47, 0, 0, time.UTC), *astronomy.Sunrise, 2*time.Minute)
+
+	// a city has to be geocoded first, which needs a backend
+	_, err = fp.FetchAstronomy(context.Background(), core.CityQuery("Kyiv"), "2025-06-21")
+	assert.ErrorIs(t, err, ErrAPIRequest)
+
+	_, err = fp.FetchAstronomy(context.Background(), core.CoordinatesQuery(50.45, 30.52), "21.06.2025")
+	assert.ErrorIs(t, err, ErrInvalidDate)
+}
@@ -0,0 +1,361 @@
+package weatherprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"weather-app/internal/core"
+)
+
+// Fixture directories, one per WeatherProvider method. Each holds
+// <key>.json files with the method's result encoded as the core type.
+const (
+	fixtureWeather    = "weather"
+	fixtureForecast   = "forecast"
+	fixtureAirQuality = "airquality"
+	fixtureAlerts     = "alerts"
+	fixtureSearch     = "search"
+	fixtureAstronomy  = "astronomy" // <key>_<date>.json
+)
+
+// fixtureMatchDegrees is how far a coordinates query may be from a weather
+// fixture's location and still be answered by it.
+const fixtureMatchDegrees = 0.25
+
+// fixtureKey names the fixture file for a query: the lower-cased city for city
+// queries, e.g. "kyiv", and the query kind plus value otherwise, e.g.
+// "coordinates_50.4500_30.5200". Fixtures hold one language, whatever the
+// query's Lang.
+func fixtureKey(query core.LocationQuery) string {
+	if query.Kind == core.QueryByCity || query.Kind == "" {
+		return sanitizeFixtureName(query.City)
+	}
+	return sanitizeFixtureName(query.WithLang("").String())
+}
+
+func sanitizeFixtureName(s string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteRune('_')
+			underscore = true
+		}
+	}
+	return strings.Trim(b.String(), "_.")
+}
+
+// FixtureProvider answers from JSON files instead of a weather API, so the
+// service can run without network access. A place without a weather fixture
+// is ErrCityNotFound; missing alert or search fixtures mean no results.
+//
+// Coordinates queries without a fixture of their own are answered by the
+// weather fixture whose location is nearest, so subscriptions (which are
+// fetched by coordinates) work with fixtures recorded by city name.
+type FixtureProvider struct {
+	dir string
+}
+
+func NewFixtureProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{dir: dir}
+}
+
+func (p *FixtureProvider) path(kind, key string) string {
+	return filepath.Join(p.dir, kind, key+".json")
+}
+
+// load decodes the fixture for key into out, reporting false if there is none.
+func (p *FixtureProvider) load(kind, key string, out interface{}) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(p.path(kind, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: reading fixture: %v", ErrAPIRequest, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("%w: %w: fixture %s/%s: %v", ErrAPIRequest, ErrBadPayload, kind, key, err)
+	}
+	return true, nil
+}
+
+// weatherFixtures returns every weather fixture by key.
+func (p *FixtureProvider) weatherFixtures() (map[string]core.Weather, error) {
+	entries, err := os.ReadDir(filepath.Join(p.dir, fixtureWeather))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading fixtures: %v", ErrAPIRequest, err)
+	}
+
+	fixtures := make(map[string]core.Weather, len(entries))
+	for _, e := range entries {
+		key, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		var w core.Weather
+		if _, err := p.load(fixtureWeather, key, &w); err != nil {
+			return nil, err
+		}
+		fixtures[key] = w
+	}
+	return fixtures, nil
+}
+
+// loadFor finds the fixture answering query: its own file, or for
+// coordinates the nearest recorded place.
+func (p *FixtureProvider) loadFor(kind string, query core.LocationQuery, out interface{}) (bool, error) {
+	if found, err := p.load(kind, fixtureKey(query), out); found || err != nil {
+		return found, err
+	}
+	if query.Kind != core.QueryByCoordinates {
+		return false, nil
+	}
+
+	fixtures, err := p.weatherFixtures()
+	if err != nil {
+		return false, err
+	}
+	nearestKey, nearest := "", math.Inf(1)
+	for key, w := range fixtures {
+		d := math.Hypot(w.Location.Lat-query.Lat, w.Location.Lon-query.Lon)
+		if d <= fixtureMatchDegrees && d < nearest {
+			nearestKey, nearest = key, d
+		}
+	}
+	return p.load(kind, nearestKey, out)
+}
+
+func (p *FixtureProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	var weather core.Weather
+	found, err := p.loadFor(fixtureWeather, query, &weather)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrCityNotFound
+	}
+	return &weather, nil
+}
+
+// FetchForecast returns the first days of the fixture; asking for more days
+// than it holds returns all of them.
+func (p *FixtureProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	if days < 1 || days > MaxForecastDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidForecast, MaxForecastDays)
+	}
+	var forecast core.Forecast
+	found, err := p.loadFor(fixtureForecast, query, &forecast)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrCityNotFound
+	}
+	if len(forecast.Days) > days {
+		forecast.Days = forecast.Days[:days]
+	}
+	return &forecast, nil
+}
+
+func (p *FixtureProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	var airQuality core.AirQuality
+	found, err := p.loadFor(fixtureAirQuality, query, &airQuality)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrCityNotFound
+	}
+	return &airQuality, nil
+}
+
+func (p *FixtureProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	alerts := []core.WeatherAlert{}
+	if _, err := p.loadFor(fixtureAlerts, query, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// FetchAstronomy uses an astronomy fixture for the date if there is one,
+// otherwise calculates it for the place's weather fixture location.
+func (p *FixtureProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	if err := checkDate(date); err != nil {
+		return nil, err
+	}
+	var astronomy core.Astronomy
+	found, err := p.load(fixtureAstronomy, fixtureKey(query)+"_"+date, &astronomy)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &astronomy, nil
+	}
+
+	var weather core.Weather
+	found, err = p.loadFor(fixtureWeather, query, &weather)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrCityNotFound
+	}
+	computed, err := core.ComputeAstronomy(weather.Location, date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+	return computed, nil
+}
+
+// SearchLocations uses a search fixture for the exact query if there is one,
+// otherwise the locations of weather fixtures whose name starts with it.
+func (p *FixtureProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	locations := []core.Location{}
+	found, err := p.load(fixtureSearch, sanitizeFixtureName(query), &locations)
+	if err != nil || found {
+		return locations, err
+	}
+
+	fixtures, err := p.weatherFixtures()
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.ToLower(strings.TrimSpace(query))
+	for key, w := range fixtures {
+		if w.Location.Name == "" || !strings.HasPrefix(strings.ToLower(w.Location.Name), prefix) {
+			continue
+		}
+		loc := w.Location
+		if loc.ID == "" {
+			loc.ID = "fixture:" + key
+		}
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Name < locations[j].Name })
+	return locations, nil
+}
+
+// RecordingProvider passes calls through to next and saves every successful
+// answer as a fixture in dir, in the layout FixtureProvider reads.
+type RecordingProvider struct {
+	next WeatherProvider
+	dir  string
+}
+
+func NewRecordingProvider(next WeatherProvider, dir string) *RecordingProvider {
+	return &RecordingProvider{next: next, dir: dir}
+}
+
+// save writes v to its fixture file atomically.
+func (r *RecordingProvider) save(kind, key string, v interface{}) error {
+	if key == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(r.dir, kind)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, key+".json"))
+}
+
+// record saves a fixture; failing to save never fails the call.
+func (r *RecordingProvider) record(kind, key string, v interface{}) {
+	if err := r.save(kind, key, v); err != nil {
+		log.Printf("Failed to record %s fixture %q: %v", kind, key, err)
+	}
+}
+
+func (r *RecordingProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	weather, err := r.next.FetchWeather(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	r.record(fixtureWeather, fixtureKey(query), weather)
+	return weather, nil
+}
+
+func (r *RecordingProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	forecast, err := r.next.FetchForecast(ctx, query, days)
+	if err != nil {
+		return nil, err
+	}
+	// don't let a short forecast overwrite a longer recording of the same place
+	var existing core.Forecast
+	replay := NewFixtureProvider(r.dir)
+	if found, _ := replay.load(fixtureForecast, fixtureKey(query), &existing); !found || len(existing.Days) <= len(forecast.Days) {
+		r.record(fixtureForecast, fixtureKey(query), forecast)
+	}
+	return forecast, nil
+}
+
+func (r *RecordingProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	airQuality, err := r.next.FetchAirQuality(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	r.record(fixtureAirQuality, fixtureKey(query), airQuality)
+	return airQuality, nil
+}
+
+func (r *RecordingProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	alerts, err := r.next.FetchAlerts(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	r.record(fixtureAlerts, fixtureKey(query), alerts)
+	return alerts, nil
+}
+
+func (r *RecordingProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	locations, err := r.next.SearchLocations(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	r.record(fixtureSearch, sanitizeFixtureName(query), locations)
+	return locations, nil
+}
+
+func (r *RecordingProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	astronomy, err := r.next.FetchAstronomy(ctx, query, date)
+	if err != nil {
+		return nil, err
+	}
+	if !astronomy.Computed {
+		r.record(fixtureAstronomy, fixtureKey(query)+"_"+date, astronomy)
+	}
+	return astronomy, nil
+}
@@ -0,0 +1,82 @@
+package weatherprovider
+
+import (
+	"context"
+	"testing"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingProvider_RecordsFixturesForReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	inner := &countingProvider{}
+	recorder := NewRecordingProvider(inner, dir)
+
+	_, err := recorder.FetchWeather(ctx, core.CityQuery("New York"))
+	require.NoError(t, err)
+	_, err = recorder.FetchForecast(ctx, core.CityQuery("New York"), 3)
+	require.NoError(t, err)
+	_, err = recorder.FetchForecast(ctx, core.CityQuery("New York"), 1)
+	require.NoError(t, err)
+	recorded, err := recorder.FetchAstronomy(ctx, core.CityQuery("New York"), "2025-03-20")
+	require.NoError(t, err)
+
+	replay := NewFixtureProvider(dir)
+	weather, err := replay.FetchWeather(ctx, core.CityQuery("new york"))
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny", weather.Description)
+
+	forecast, err := replay.FetchForecast(ctx, core.CityQuery("New York"), 14)
+	require.NoError(t, err)
+	assert.Len(t, forecast.Days, 3, "a shorter forecast must not overwrite a longer recording")
+
+	astronomy, err := replay.FetchAstronomy(ctx, core.CityQuery("New York"), "2025-03-20")
+	require.NoError(t, err)
+	assert.False(t, astronomy.Computed)
+	assert.Equal(t, recorded.DayLengthMinutes, astronomy.DayLengthMinutes)
+
+	_, err = replay.FetchWeather(ctx, core.CityQuery("Atlantis"))
+	assert.ErrorIs(t, err, ErrCityNotFound)
+	alerts, err := replay.FetchAlerts(ctx, core.CityQuery("New York"))
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestFixtureProvider_BundledFixtures(t *testing.T) {
+	ctx := context.Background()
+	p := NewFixtureProvider("../../../fixtures/weather")
+
+	weather, err := p.FetchWeather(ctx, core.CityQuery("Kyiv"))
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Kyiv", weather.Location.Timezone)
+	assert.Equal(t, core.ConditionPartlyCloudy, weather.Condition)
+
+	// subscriptions are fetched by coordinates, which match the nearest recorded place
+	forecast, err := p.FetchForecast(ctx, core.CoordinatesQuery(51.5074, -0.1278), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "London", forecast.City)
+	assert.Len(t, forecast.Days, 1)
+
+	_, err = p.FetchAirQuality(ctx, core.CoordinatesQuery(40.71, -74.01))
+	assert.ErrorIs(t, err, ErrCityNotFound)
+
+	// without an astronomy fixture the sun is calculated for the recorded place
+	astronomy, err := p.FetchAstronomy(ctx, core.CityQuery("Kyiv"), "2025-06-21")
+	require.NoError(t, err)
+	assert.True(t, astronomy.Computed)
+	require.NotNil(t, astronomy.Sunrise)
+	assert.Equal(t, "Europe/Kyiv", astronomy.Sunrise.Location().String())
+
+	locations, err := p.SearchLocations(ctx, "ky")
+	require.NoError(t, err)
+	require.Len(t, locations, 1)
+	assert.Equal(t, "weatherapi:2801268", locations[0].ID)
+
+	locations, err = p.SearchLocations(ctx, "Atlantis")
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+}
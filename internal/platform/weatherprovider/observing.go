@@ -0,0 +1,76 @@
+package weatherprovider
+
+import (
+	"context"
+	"log"
+
+	"weather-app/internal/core"
+)
+
+// ObservationRecorder stores current-weather observations for history, keyed
+// by the query they were fetched for.
+type ObservationRecorder interface {
+	Record(ctx context.Context, query core.LocationQuery, weather *core.Weather) error
+}
+
+// ObservingProvider passes calls through to next and records every current
+// weather it fetches. Placed under a CachingProvider it sees only the cache
+// misses, so each upstream reading is recorded once.
+type ObservingProvider struct {
+	next     WeatherProvider
+	recorder ObservationRecorder
+}
+
+func NewObservingProvider(next WeatherProvider, recorder ObservationRecorder) *ObservingProvider {
+	return &ObservingProvider{next: next, recorder: recorder}
+}
+
+// record stores weather under query, so history can be looked up by the same
+// query without asking the provider what it calls the place. The provider's
+// name is kept for display, falling back to the query. Lookups by IP are not
+// recorded, so client addresses aren't stored, and readings without an
+// observation time are skipped, as they can't be told apart from the ones
+// already stored. Failing to record never fails the call.
+func (p *ObservingProvider) record(ctx context.Context, query core.LocationQuery, weather core.Weather) {
+	if query.Kind == core.QueryByIP || weather.ObservedAt.IsZero() {
+		return
+	}
+	if weather.Location.Name == "" {
+		weather.Location.Name = query.City
+	}
+	if weather.Location.Name == "" {
+		weather.Location.Name = query.WithLang("").String()
+	}
+	if err := p.recorder.Record(ctx, query, &weather); err != nil {
+		log.Printf("Error recording weather observation for %s: %v", query, err)
+	}
+}
+
+func (p *ObservingProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	weather, err := p.next.FetchWeather(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	p.record(ctx, query, *weather)
+	return weather, nil
+}
+
+func (p *ObservingProvider) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	return p.next.FetchForecast(ctx, query, days)
+}
+
+func (p *ObservingProvider) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	return p.next.FetchAirQuality(ctx, query)
+}
+
+func (p *ObservingProvider) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	return p.next.FetchAlerts(ctx, query)
+}
+
+func (p *ObservingProvider) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	return p.next.SearchLocations(ctx, query)
+}
+
+func (p *ObservingProvider) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	return p.next.FetchAstronomy(ctx, query, date)
+}
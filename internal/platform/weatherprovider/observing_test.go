@@ -0,0 +1,101 @@
+package weatherprovider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRecorder struct {
+	mu       sync.Mutex
+	queries  []core.LocationQuery
+	recorded []core.Weather
+	err      error
+}
+
+func (r *fakeRecorder) Record(ctx context.Context, query core.LocationQuery, weather *core.Weather) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, query)
+	r.recorded = append(r.recorded, *weather)
+	return r.err
+}
+
+// observedProvider answers FetchWeather with a fixed reading.
+type observedProvider struct {
+	countingProvider
+	weather core.Weather
+}
+
+func (p *observedProvider) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	p.calls.Add(1)
+	weather := p.weather
+	return &weather, nil
+}
+
+func TestObservingProvider_RecordsCacheMissesOnly(t *testing.T) {
+	ctx := context.Background()
+	observedAt := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+	upstream := &observedProvider{weather: core.Weather{Temperature: 20, ObservedAt: observedAt, Location: core.Location{Name: "Kyiv"}}}
+	recorder := &fakeRecorder{}
+	cache := NewCachingProvider(NewObservingProvider(upstream, recorder), time.Minute, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.FetchWeather(ctx, core.CityQuery("kiev"))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), upstream.calls.Load())
+	require.Len(t, recorder.recorded, 1)
+	assert.Equal(t, core.CityQuery("kiev"), recorder.queries[0])
+	assert.Equal(t, "Kyiv", recorder.recorded[0].Location.Name)
+	assert.Equal(t, observedAt, recorder.recorded[0].ObservedAt)
+}
+
+func TestObservingProvider_Record(t *testing.T) {
+	ctx := context.Background()
+	observedAt := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+
+	kyiv := core.Weather{ObservedAt: observedAt, Location: core.Location{Name: "Kyiv"}}
+
+	tests := []struct {
+		name         string
+		query        core.LocationQuery
+		weather      core.Weather
+		recorderErr  error
+		expectedName string
+	}{
+		{name: "provider name", query: core.CityQuery("kiev"), weather: kyiv, expectedName: "Kyiv"},
+		{name: "falls back to the city asked for", query: core.CityQuery("kiev"), weather: core.Weather{ObservedAt: observedAt}, expectedName: "kiev"},
+		{name: "falls back to the query", query: core.CoordinatesQuery(50.45, 30.5233), weather: core.Weather{ObservedAt: observedAt}, expectedName: "coordinates:50.4500,30.5233"},
+		{name: "no observation time", query: core.CityQuery("kiev"), weather: core.Weather{Location: core.Location{Name: "Kyiv"}}},
+		{name: "IP lookups are not recorded", query: core.IPQuery("203.0.113.7"), weather: kyiv},
+		{name: "recording failure", query: core.CityQuery("kiev"), weather: kyiv, recorderErr: errors.New("db down"), expectedName: "Kyiv"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &fakeRecorder{err: tc.recorderErr}
+			provider := NewObservingProvider(&observedProvider{weather: tc.weather}, recorder)
+
+			// recording failures are logged, not returned
+			_, err := provider.FetchWeather(ctx, tc.query)
+			require.NoError(t, err)
+
+			if tc.expectedName == "" {
+				assert.Empty(t, recorder.recorded)
+				return
+			}
+			require.Len(t, recorder.recorded, 1)
+			assert.Equal(t, tc.query, recorder.queries[0])
+			assert.Equal(t, tc.expectedName, recorder.recorded[0].Location.Name)
+		})
+	}
+}
@@ -0,0 +1,550 @@
+package weatherprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"weather-app/internal/core"
+)
+
+const (
+	openMeteoBaseURL          = "https://api.open-meteo.com/v1"
+	openMeteoGeocodingBaseURL = "https://geocoding-api.open-meteo.com/v1"
+	openMeteoAirQualityURL    = "https://air-quality-api.open-meteo.com/v1"
+	openMeteoMaxForecastDays  = 16
+	openMeteoMaxSearchResults = 10
+)
+
+// wmoDescriptions maps WMO weather interpretation codes used by Open-Meteo to text.
+var wmoDescriptions = map[int]string{
+	0:  "Clear sky",
+	1:  "Mainly clear",
+	2:  "Partly cloudy",
+	3:  "Overcast",
+	45: "Fog",
+	48: "Depositing rime fog",
+	51: "Light drizzle",
+	53: "Moderate drizzle",
+	55: "Dense drizzle",
+	56: "Light freezing drizzle",
+	57: "Dense freezing drizzle",
+	61: "Slight rain",
+	63: "Moderate rain",
+	65: "Heavy rain",
+	66: "Light freezing rain",
+	67: "Heavy freezing rain",
+	71: "Slight snow fall",
+	73: "Moderate snow fall",
+	75: "Heavy snow fall",
+	77: "Snow grains",
+	80: "Slight rain showers",
+	81: "Moderate rain showers",
+	82: "Violent rain showers",
+	85: "Slight snow showers",
+	86: "Heavy snow showers",
+	95: "Thunderstorm",
+	96: "Thunderstorm with slight hail",
+	99: "Thunderstorm with heavy hail",
+}
+
+// wmoDescriptionsByLang holds translations of wmoDescriptions, since
+// Open-Meteo returns only the codes.
+var wmoDescriptionsByLang = map[string]map[int]string{
+	"uk": {
+		0:  "Ясно",
+		1:  "Переважно ясно",
+		2:  "Мінлива хмарність",
+		3:  "Похмуро",
+		45: "Туман",
+		48: "Туман з памороззю",
+		51: "Слабка мряка",
+		53: "Помірна мряка",
+		55: "Густа мряка",
+		56: "Слабка мряка з ожеледдю",
+		57: "Густа мряка з ожеледдю",
+		61: "Невеликий дощ",
+		63: "Помірний дощ",
+		65: "Сильний дощ",
+		66: "Слабкий крижаний дощ",
+		67: "Сильний крижаний дощ",
+		71: "Невеликий сніг",
+		73: "Помірний сніг",
+		75: "Сильний сніг",
+		77: "Снігові зерна",
+		80: "Невеликі зливи",
+		81: "Помірні зливи",
+		82: "Дуже сильні зливи",
+		85: "Невеликий снігопад",
+		86: "Сильний снігопад",
+		95: "Гроза",
+		96: "Гроза з невеликим градом",
+		99: "Гроза з сильним градом",
+	},
+}
+
+// wmoDescription names a WMO code in lang, falling back to English.
+func wmoDescription(code int, lang string) string {
+	if d, ok := wmoDescriptionsByLang[lang][code]; ok {
+		return d
+	}
+	if d, ok := wmoDescriptions[code]; ok {
+		return d
+	}
+	return "Unknown"
+}
+
+// wmoCondition normalizes a WMO weather interpretation code.
+func wmoCondition(code int) core.Condition {
+	switch code {
+	case 0, 1:
+		return core.ConditionClear
+	case 2:
+		return core.ConditionPartlyCloudy
+	case 3:
+		return core.ConditionOvercast
+	case 45, 48:
+		return core.ConditionFog
+	case 51, 53, 55:
+		return core.ConditionDrizzle
+	case 56, 57, 66, 67:
+		return core.ConditionFreezingRain
+	case 61, 63, 80, 81:
+		return core.ConditionRain
+	case 65, 82:
+		return core.ConditionHeavyRain
+	case 71, 73, 77, 85:
+		return core.ConditionSnow
+	case 75, 86:
+		return core.ConditionHeavySnow
+	case 95, 96, 99:
+		return core.ConditionThunder
+	}
+	return core.ConditionUnknown
+}
+
+type openMeteoGeocodingResponse struct {
+	Results []struct {
+		ID        int64   `json:"id"`
+		Name      string  `json:"name"`
+		Admin1    string  `json:"admin1"`
+		Country   string  `json:"country"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Timezone  string  `json:"timezone"`
+	} `json:"results"`
+}
+
+type openMeteoForecastResponse struct {
+	Timezone         string `json:"timezone"`
+	UTCOffsetSeconds int64  `json:"utc_offset_seconds"`
+	Current          struct {
+		Time          int64   `json:"time"`
+		Temperature   float64 `json:"temperature_2m"`
+		Humidity      float64 `json:"relative_humidity_2m"`
+		FeelsLike     float64 `json:"apparent_temperature"`
+		Precipitation float64 `json:"precipitation"`
+		CloudCover    float64 `json:"cloud_cover"`
+		Pressure      float64 `json:"pressure_msl"`
+		WindSpeed     float64 `json:"wind_speed_10m"`
+		WindDirection float64 `json:"wind_direction_10m"`
+		WindGusts     float64 `json:"wind_gusts_10m"`
+		UVIndex       float64 `json:"uv_index"`
+		Visibility    float64 `json:"visibility"` // meters
+		WeatherCode   int     `json:"weather_code"`
+		IsDay         int     `json:"is_day"`
+	} `json:"current"`
+	Daily struct {
+		Time                     []int64   `json:"time"`
+		TemperatureMax           []float64 `json:"temperature_2m_max"`
+		TemperatureMin           []float64 `json:"temperature_2m_min"`
+		PrecipitationSum         []float64 `json:"precipitation_sum"`
+		PrecipitationProbability []int     `json:"precipitation_probability_max"`
+		WindSpeedMax             []float64 `json:"wind_speed_10m_max"`
+		WeatherCode              []int     `json:"weather_code"`
+	} `json:"daily"`
+	Hourly struct {
+		Time                     []int64   `json:"time"`
+		Temperature              []float64 `json:"temperature_2m"`
+		Humidity                 []float64 `json:"relative_humidity_2m"`
+		PrecipitationProbability []int     `json:"precipitation_probability"`
+		WeatherCode              []int     `json:"weather_code"`
+		IsDay                    []int     `json:"is_day"`
+	} `json:"hourly"`
+	Error  bool   `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// OpenMeteoClient is a keyless WeatherProvider backed by open-meteo.com.
+// City names are resolved through the Open-Meteo geocoding API first.
+type OpenMeteoClient struct {
+	baseURL          string
+	geocodingBaseURL string
+	airQualityURL    string
+	httpClient       *http.Client
+}
+
+func NewOpenMeteoClient() *OpenMeteoClient {
+	return &OpenMeteoClient{
+		baseURL:          openMeteoBaseURL,
+		geocodingBaseURL: openMeteoGeocodingBaseURL,
+		airQualityURL:    openMeteoAirQualityURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (c *OpenMeteoClient) getJSON(ctx context.Context, reqURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAPIRequest, err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAPIRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: received status %d", ErrAPIRequest, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: failed to decode response: %v", ErrAPIRequest, err)
+	}
+	return nil
+}
+
+func (c *OpenMeteoClient) search(ctx context.Context, query string, count int) ([]core.Location, error) {
+	params := url.Values{}
+	params.Add("name", query)
+	params.Add("count", strconv.Itoa(count))
+
+	var geo openMeteoGeocodingResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/search?%s", c.geocodingBaseURL, params.Encode()), &geo); err != nil {
+		return nil, err
+	}
+
+	locations := make([]core.Location, 0, len(geo.Results))
+	for _, r := range geo.Results {
+		locations = append(locations, core.Location{
+			ID:       fmt.Sprintf("openmeteo:%d", r.ID),
+			Name:     r.Name,
+			Region:   r.Admin1,
+			Country:  r.Country,
+			Lat:      r.Latitude,
+			Lon:      r.Longitude,
+			Timezone: r.Timezone,
+		})
+	}
+	return locations, nil
+}
+
+// geocode resolves a query to coordinates. Open-Meteo has no postcode,
+// airport or IP lookup.
+func (c *OpenMeteoClient) geocode(ctx context.Context, query core.LocationQuery) (*core.Location, error) {
+	switch query.Kind {
+	case core.QueryByCity:
+	case core.QueryByCoordinates:
+		loc := core.Location{Lat: query.Lat, Lon: query.Lon}
+		loc.Name = loc.Coordinates()
+		return &loc, nil
+	default:
+		return nil, ErrNotSupported
+	}
+
+	locations, err := c.search(ctx, query.City, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, ErrCityNotFound
+	}
+	return &locations[0], nil
+}
+
+func (c *OpenMeteoClient) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	return c.search(ctx, query, openMeteoMaxSearchResults)
+}
+
+func (c *OpenMeteoClient) fetch(ctx context.Context, lat, lon float64, params url.Values) (*openMeteoForecastResponse, error) {
+	params.Add("latitude", strconv.FormatFloat(lat, 'f', 4, 64))
+	params.Add("longitude", strconv.FormatFloat(lon, 'f', 4, 64))
+	params.Add("timezone", "auto")
+	params.Add("timeformat", "unixtime")
+
+	var apiResp openMeteoForecastResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/forecast?%s", c.baseURL, params.Encode()), &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Error {
+		return nil, fmt.Errorf("%w: %s", ErrAPIRequest, apiResp.Reason)
+	}
+	return &apiResp, nil
+}
+
+func (c *OpenMeteoClient) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	loc, err := c.geocode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("current", "temperature_2m,relative_humidity_2m,apparent_temperature,precipitation,cloud_cover,"+
+		"pressure_msl,wind_speed_10m,wind_direction_10m,wind_gusts_10m,uv_index,visibility,weather_code,is_day")
+	apiResp, err := c.fetch(ctx, loc.Lat, loc.Lon, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.Timezone == "" {
+		loc.Timezone = apiResp.Timezone
+	}
+
+	cur := apiResp.Current
+	condition, isDay := wmoCondition(cur.WeatherCode), cur.IsDay == 1
+	return &core.Weather{
+		Temperature:   cur.Temperature,
+		Humidity:      cur.Humidity,
+		Description:   wmoDescription(cur.WeatherCode, query.Lang),
+		Condition:     condition,
+		IsDay:         isDay,
+		Icon:          condition.Icon(isDay),
+		FeelsLike:     cur.FeelsLike,
+		WindSpeed:     cur.WindSpeed,
+		WindGust:      cur.WindGusts,
+		WindDegree:    int(cur.WindDirection),
+		WindDirection: compassDirection(int(cur.WindDirection)),
+		Pressure:      cur.Pressure,
+		Precipitation: cur.Precipitation,
+		CloudCover:    int(cur.CloudCover),
+		UVIndex:       cur.UVIndex,
+		Visibility:    cur.Visibility / 1000,
+		ObservedAt:    time.Unix(cur.Time, 0).UTC(),
+		Location:      *loc,
+	}, nil
+}
+
+func (c *OpenMeteoClient) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	if days < 1 || days > MaxForecastDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidForecast, MaxForecastDays)
+	}
+	if days > openMeteoMaxForecastDays {
+		return nil, ErrNotSupported
+	}
+
+	loc, err := c.geocode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("daily", "temperature_2m_max,temperature_2m_min,precipitation_sum,precipitation_probability_max,wind_speed_10m_max,weather_code")
+	params.Add("hourly", "temperature_2m,relative_humidity_2m,precipitation_probability,weather_code,is_day")
+	params.Add("forecast_days", strconv.Itoa(days))
+	apiResp, err := c.fetch(ctx, loc.Lat, loc.Lon, params)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := time.Duration(apiResp.UTCOffsetSeconds) * time.Second
+	forecast := &core.Forecast{City: loc.Name}
+	d := apiResp.Daily
+	for i, ts := range d.Time {
+		if i >= len(d.TemperatureMax) || i >= len(d.TemperatureMin) {
+			break
+		}
+		day := core.ForecastDay{
+			Date:           time.Unix(ts, 0).UTC().Add(offset).Format("2006-01-02"),
+			MaxTemperature: d.TemperatureMax[i],
+			MinTemperature: d.TemperatureMin[i],
+		}
+		if i < len(d.PrecipitationSum) {
+			day.TotalPrecip = d.PrecipitationSum[i]
+		}
+		if i < len(d.PrecipitationProbability) {
+			day.ChanceOfRain = d.PrecipitationProbability[i]
+		}
+		if i < len(d.WindSpeedMax) {
+			day.MaxWindSpeed = d.WindSpeedMax[i]
+		}
+		if i < len(d.WeatherCode) {
+			day.Description = wmoDescription(d.WeatherCode[i], query.Lang)
+			day.Condition = wmoCondition(d.WeatherCode[i])
+			day.Icon = day.Condition.Icon(true)
+		}
+		forecast.Days = append(forecast.Days, day)
+	}
+
+	h := apiResp.Hourly
+	for i, ts := range h.Time {
+		date := time.Unix(ts, 0).UTC().Add(offset).Format("2006-01-02")
+		for j := range forecast.Days {
+			if forecast.Days[j].Date != date {
+				continue
+			}
+			hour := core.ForecastHour{Time: time.Unix(ts, 0).UTC()}
+			if i < len(h.Temperature) {
+				hour.Temperature = h.Temperature[i]
+			}
+			if i < len(h.Humidity) {
+				hour.Humidity = h.Humidity[i]
+			}
+			if i < len(h.PrecipitationProbability) {
+				hour.ChanceOfRain = h.PrecipitationProbability[i]
+			}
+			if i < len(h.WeatherCode) {
+				hour.Description = wmoDescription(h.WeatherCode[i], query.Lang)
+				hour.Condition = wmoCondition(h.WeatherCode[i])
+			}
+			if i < len(h.IsDay) {
+				hour.IsDay = h.IsDay[i] == 1
+			}
+			hour.Icon = hour.Condition.Icon(hour.IsDay)
+			forecast.Days[j].Hours = append(forecast.Days[j].Hours, hour)
+			break
+		}
+	}
+
+	for j := range forecast.Days {
+		hours := forecast.Days[j].Hours
+		if len(hours) == 0 {
+			continue
+		}
+		var total float64
+		for _, hour := range hours {
+			total += hour.Humidity
+		}
+		forecast.Days[j].AvgHumidity = total / float64(len(hours))
+	}
+
+	return forecast, nil
+}
+
+type openMeteoAirQualityResponse struct {
+	Current struct {
+		PM25  float64 `json:"pm2_5"`
+		PM10  float64 `json:"pm10"`
+		O3    float64 `json:"ozone"`
+		NO2   float64 `json:"nitrogen_dioxide"`
+		CO    float64 `json:"carbon_monoxide"`
+		SO2   float64 `json:"sulphur_dioxide"`
+		USAQI float64 `json:"us_aqi"`
+	} `json:"current"`
+}
+
+// usAQICategory maps the 0-500 US AQI value to the 1-6 category index weatherapi.com uses.
+func usAQICategory(aqi float64) int {
+	upperBounds := []float64{50, 100, 150, 200, 300}
+	for i, bound := range upperBounds {
+		if aqi <= bound {
+			return i + 1
+		}
+	}
+	return 6
+}
+
+func (c *OpenMeteoClient) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	loc, err := c.geocode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("latitude", strconv.FormatFloat(loc.Lat, 'f', 4, 64))
+	params.Add("longitude", strconv.FormatFloat(loc.Lon, 'f', 4, 64))
+	params.Add("current", "pm2_5,pm10,ozone,nitrogen_dioxide,carbon_monoxide,sulphur_dioxide,us_aqi")
+
+	var apiResp openMeteoAirQualityResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/air-quality?%s", c.airQualityURL, params.Encode()), &apiResp); err != nil {
+		return nil, err
+	}
+
+	cur := apiResp.Current
+	return &core.AirQuality{
+		PM25:       cur.PM25,
+		PM10:       cur.PM10,
+		O3:         cur.O3,
+		NO2:        cur.NO2,
+		CO:         cur.CO,
+		SO2:        cur.SO2,
+		USEPAIndex: usAQICategory(cur.USAQI),
+		DEFRAIndex: core.DEFRAIndexFromPM25(cur.PM25),
+	}, nil
+}
+
+type openMeteoSunResponse struct {
+	Timezone string `json:"timezone"`
+	Daily    struct {
+		Sunrise          []int64   `json:"sunrise"`
+		Sunset           []int64   `json:"sunset"`
+		DaylightDuration []float64 `json:"daylight_duration"` // seconds
+	} `json:"daily"`
+	Error  bool   `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// FetchAstronomy uses Open-Meteo's sun times; it has no moon data, so the
+// moon comes from core.ComputeAstronomy. Dates more than about three months
+// back or 16 days ahead are rejected by the API.
+func (c *OpenMeteoClient) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	if err := checkDate(date); err != nil {
+		return nil, err
+	}
+	loc, err := c.geocode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("latitude", strconv.FormatFloat(loc.Lat, 'f', 4, 64))
+	params.Add("longitude", strconv.FormatFloat(loc.Lon, 'f', 4, 64))
+	params.Add("timezone", "auto")
+	params.Add("timeformat", "unixtime")
+	params.Add("daily", "sunrise,sunset,daylight_duration")
+	params.Add("start_date", date)
+	params.Add("end_date", date)
+
+	var apiResp openMeteoSunResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/forecast?%s", c.baseURL, params.Encode()), &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Error {
+		return nil, fmt.Errorf("%w: %s", ErrAPIRequest, apiResp.Reason)
+	}
+	if loc.Timezone == "" {
+		loc.Timezone = apiResp.Timezone
+	}
+
+	astronomy, err := core.ComputeAstronomy(*loc, date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+	astronomy.Computed = false
+
+	d := apiResp.Daily
+	if len(d.Sunrise) == 0 || len(d.Sunset) == 0 || len(d.DaylightDuration) == 0 {
+		return nil, fmt.Errorf("%w: %w: no sun times for %s", ErrAPIRequest, ErrBadPayload, date)
+	}
+	astronomy.DayLengthMinutes = int(math.Round(d.DaylightDuration[0] / 60))
+	astronomy.Sunrise, astronomy.Sunset = nil, nil
+	if astronomy.DayLengthMinutes > 0 && astronomy.DayLengthMinutes < 24*60 {
+		tz := time.UTC
+		if l, err := time.LoadLocation(loc.Timezone); err == nil {
+			tz = l
+		}
+		sunrise, sunset := time.Unix(d.Sunrise[0], 0).In(tz), time.Unix(d.Sunset[0], 0).In(tz)
+		astronomy.Sunrise, astronomy.Sunset = &sunrise, &sunset
+	}
+	return astronomy, nil
+}
+
+// FetchAlerts is not supported: Open-Meteo has no weather warnings feed.
+func (c *OpenMeteoClient) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	return nil, ErrNotSupported
+}
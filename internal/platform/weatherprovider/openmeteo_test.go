@@ -0,0 +1,102 @@
+package weatherprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOpenMeteoStub(t *testing.T) *OpenMeteoClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "Atlantis" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{"name":"London","latitude":51.5,"longitude":-0.12}]}`))
+	})
+	mux.HandleFunc("/forecast", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"utc_offset_seconds":3600,
+			"current":{"temperature_2m":12.3,"relative_humidity_2m":81,"weather_code":61},
+			"daily":{"time":[1747695600],"temperature_2m_max":[17.2],"temperature_2m_min":[8.1],
+				"precipitation_sum":[2.4],"precipitation_probability_max":[65],"weather_code":[63]}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	c := NewOpenMeteoClient()
+	c.baseURL = srv.URL
+	c.geocodingBaseURL = srv.URL
+	return c
+}
+
+func TestOpenMeteoClient_CoordinatesSkipGeocoding(t *testing.T) {
+	weather, err := newOpenMeteoStub(t).FetchWeather(context.Background(), core.CityQuery("51.5000,-0.1200"))
+	require.NoError(t, err)
+	assert.Equal(t, 51.5, weather.Location.Lat)
+	assert.Equal(t, -0.12, weather.Location.Lon)
+}
+
+func TestOpenMeteoClient_LocalizedDescriptions(t *testing.T) {
+	ctx := context.Background()
+
+	c := newOpenMeteoStub(t)
+	weather, err := c.FetchWeather(ctx, core.CityQuery("London").WithLang("uk"))
+	require.NoError(t, err)
+	assert.Equal(t, "Невеликий дощ", weather.Description)
+
+	forecast, err := c.FetchForecast(ctx, core.CityQuery("London").WithLang("uk"), 1)
+	require.NoError(t, err)
+	require.Len(t, forecast.Days, 1)
+	assert.Equal(t, "Помірний дощ", forecast.Days[0].Description)
+
+	weather, err = c.FetchWeather(ctx, core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, "Slight rain", weather.Description)
+}
+
+func TestOpenMeteoClient_FetchAstronomy(t *testing.T) {
+	var daily string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		daily = r.URL.Query().Get("daily")
+		w.Write([]byte(`{"timezone":"Europe/Kyiv","daily":{"sunrise":[1750470420],"sunset":[1750529580],"daylight_duration":[59160.4]}}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewOpenMeteoClient()
+	c.baseURL = srv.URL
+
+	astronomy, err := c.FetchAstronomy(context.Background(), core.CoordinatesQuery(50.45, 30.52), "2025-06-21")
+	require.NoError(t, err)
+	assert.Equal(t, "sunrise,sunset,daylight_duration", daily)
+	assert.False(t, astronomy.Computed)
+	require.NotNil(t, astronomy.Sunrise)
+	assert.Equal(t, "04:47", astronomy.Sunrise.Format("15:04"))
+	assert.Equal(t, "21:13", astronomy.Sunset.Format("15:04"))
+	assert.Equal(t, 986, astronomy.DayLengthMinutes)
+	assert.NotEmpty(t, astronomy.MoonPhase, "the moon is always calculated locally")
+}
+
+func TestOpenMeteoClient_NormalizedConditions(t *testing.T) {
+	ctx := context.Background()
+
+	c := newOpenMeteoStub(t)
+	weather, err := c.FetchWeather(ctx, core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, core.ConditionRain, weather.Condition)
+	assert.Equal(t, "rain", weather.Icon)
+
+	forecast, err := c.FetchForecast(ctx, core.CityQuery("London"), 1)
+	require.NoError(t, err)
+	require.Len(t, forecast.Days, 1)
+	assert.Equal(t, core.ConditionRain, forecast.Days[0].Condition)
+
+	// unmapped codes are unknown
+	assert.Equal(t, core.ConditionUnknown, wmoCondition(42))
+}
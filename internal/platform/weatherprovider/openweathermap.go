@@ -0,0 +1,436 @@
+package weatherprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"weather-app/internal/core"
+)
+
+const (
+	openWeatherMapBaseURL    = "https://api.openweathermap.org/data/2.5"
+	openWeatherMapGeoBaseURL = "https://api.openweathermap.org/geo/1.0"
+	openWeatherMapMaxResults = 5
+	// the free 5 day / 3 hour forecast is the longest OpenWeatherMap offers without a paid plan
+	openWeatherMapMaxForecastDays = 5
+)
+
+type openWeatherMapCondition struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"` // e.g. "10d"; the suffix is d by day and n by night
+}
+
+type openWeatherMapCurrentResponse struct {
+	Name  string `json:"name"`
+	Dt    int64  `json:"dt"`
+	Coord struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"coord"`
+	Sys struct {
+		Country string `json:"country"`
+	} `json:"sys"`
+	Main struct {
+		Temp      float64 `json:"temp"`
+		FeelsLike float64 `json:"feels_like"`
+		Humidity  float64 `json:"humidity"`
+		Pressure  float64 `json:"pressure"`
+	} `json:"main"`
+	Wind struct {
+		Speed float64 `json:"speed"` // m/s
+		Deg   int     `json:"deg"`
+		Gust  float64 `json:"gust"` // m/s
+	} `json:"wind"`
+	Clouds struct {
+		All int `json:"all"`
+	} `json:"clouds"`
+	Rain struct {
+		OneHour float64 `json:"1h"`
+	} `json:"rain"`
+	Snow struct {
+		OneHour float64 `json:"1h"`
+	} `json:"snow"`
+	Visibility float64                   `json:"visibility"` // meters
+	Weather    []openWeatherMapCondition `json:"weather"`
+}
+
+// msToKph converts OpenWeatherMap's metric wind speeds (m/s) to km/h.
+func msToKph(v float64) float64 {
+	return v * 3.6
+}
+
+type openWeatherMapForecastResponse struct {
+	City struct {
+		Name     string `json:"name"`
+		Timezone int64  `json:"timezone"` // offset from UTC in seconds
+	} `json:"city"`
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			Temp     float64 `json:"temp"`
+			TempMin  float64 `json:"temp_min"`
+			TempMax  float64 `json:"temp_max"`
+			Humidity float64 `json:"humidity"`
+		} `json:"main"`
+		Weather []openWeatherMapCondition `json:"weather"`
+		Pop     float64                   `json:"pop"` // probability of precipitation, 0..1
+		Rain    struct {
+			ThreeHours float64 `json:"3h"`
+		} `json:"rain"`
+		Wind struct {
+			Speed float64 `json:"speed"` // m/s
+		} `json:"wind"`
+	} `json:"list"`
+}
+
+// OpenWeatherMapClient is a WeatherProvider backed by openweathermap.org.
+type OpenWeatherMapClient struct {
+	apiKey     string
+	baseURL    string
+	geoBaseURL string
+	httpClient *http.Client
+}
+
+func NewOpenWeatherMapClient(apiKey string) *OpenWeatherMapClient {
+	return &OpenWeatherMapClient{
+		apiKey:     apiKey,
+		baseURL:    openWeatherMapBaseURL,
+		geoBaseURL: openWeatherMapGeoBaseURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (c *OpenWeatherMapClient) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	params.Set("units", "metric")
+	return c.getURL(ctx, c.baseURL, endpoint, params, out)
+}
+
+func (c *OpenWeatherMapClient) getURL(ctx context.Context, baseURL, endpoint string, params url.Values, out interface{}) error {
+	params.Set("appid", c.apiKey)
+	reqURL := fmt.Sprintf("%s/%s?%s", baseURL, endpoint, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAPIRequest, withoutURL(err))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAPIRequest, withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read response: %v", ErrAPIRequest, err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrCityNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return fmt.Errorf("%w: %s (status: %d)", ErrAPIRequest, apiErr.Message, resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: failed to decode response: %v", ErrAPIRequest, err)
+	}
+	return nil
+}
+
+// locationParams translates a query into OpenWeatherMap's q, lat/lon or zip
+// parameters. Airport and IP lookups aren't available.
+func locationParams(query core.LocationQuery) (url.Values, error) {
+	params := url.Values{}
+	switch query.Kind {
+	case core.QueryByCity:
+		params.Add("q", query.City)
+	case core.QueryByCoordinates:
+		params.Add("lat", strconv.FormatFloat(query.Lat, 'f', 4, 64))
+		params.Add("lon", strconv.FormatFloat(query.Lon, 'f', 4, 64))
+	case core.QueryByPostcode:
+		params.Add("zip", query.Postcode)
+	default:
+		return nil, ErrNotSupported
+	}
+	return params, nil
+}
+
+func describe(conditions []openWeatherMapCondition) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	d := conditions[0].Description
+	if d == "" {
+		return d
+	}
+	first, size := utf8.DecodeRuneInString(d)
+	return string(unicode.ToUpper(first)) + d[size:]
+}
+
+// conditionOf normalizes OpenWeatherMap's condition ID
+// (https://openweathermap.org/weather-conditions) and tells day from night.
+func conditionOf(conditions []openWeatherMapCondition) (core.Condition, bool) {
+	if len(conditions) == 0 {
+		return core.ConditionUnknown, true
+	}
+	id, isDay := conditions[0].ID, !strings.HasSuffix(conditions[0].Icon, "n")
+	switch {
+	case id >= 200 && id < 300:
+		return core.ConditionThunder, isDay
+	case id >= 300 && id < 400:
+		return core.ConditionDrizzle, isDay
+	case id == 511:
+		return core.ConditionFreezingRain, isDay
+	case id == 502, id == 503, id == 504, id == 522:
+		return core.ConditionHeavyRain, isDay
+	case id >= 500 && id < 600:
+		return core.ConditionRain, isDay
+	case id >= 611 && id <= 616:
+		return core.ConditionSleet, isDay
+	case id == 602, id == 622:
+		return core.ConditionHeavySnow, isDay
+	case id >= 600 && id < 700:
+		return core.ConditionSnow, isDay
+	case id >= 700 && id < 800:
+		return core.ConditionFog, isDay // mist, haze, dust and the like
+	case id == 800:
+		return core.ConditionClear, isDay
+	case id == 801, id == 802:
+		return core.ConditionPartlyCloudy, isDay
+	case id == 803:
+		return core.ConditionCloudy, isDay
+	case id == 804:
+		return core.ConditionOvercast, isDay
+	}
+	return core.ConditionUnknown, isDay
+}
+
+func (c *OpenWeatherMapClient) FetchWeather(ctx context.Context, query core.LocationQuery) (*core.Weather, error) {
+	params, err := locationParams(query)
+	if err != nil {
+		return nil, err
+	}
+	if query.Lang != "" {
+		params.Add("lang", query.Lang)
+	}
+
+	var apiResp openWeatherMapCurrentResponse
+	if err := c.get(ctx, "weather", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	condition, isDay := conditionOf(apiResp.Weather)
+	// OpenWeatherMap's free tier has no UV index, and only a UTC offset instead of a timezone name
+	return &core.Weather{
+		Temperature:   apiResp.Main.Temp,
+		Humidity:      apiResp.Main.Humidity,
+		Description:   describe(apiResp.Weather),
+		Condition:     condition,
+		IsDay:         isDay,
+		Icon:          condition.Icon(isDay),
+		FeelsLike:     apiResp.Main.FeelsLike,
+		WindSpeed:     msToKph(apiResp.Wind.Speed),
+		WindGust:      msToKph(apiResp.Wind.Gust),
+		WindDegree:    apiResp.Wind.Deg,
+		WindDirection: compassDirection(apiResp.Wind.Deg),
+		Pressure:      apiResp.Main.Pressure,
+		Precipitation: apiResp.Rain.OneHour + apiResp.Snow.OneHour,
+		CloudCover:    apiResp.Clouds.All,
+		Visibility:    apiResp.Visibility / 1000,
+		ObservedAt:    time.Unix(apiResp.Dt, 0).UTC(),
+		Location: core.Location{
+			Name:    apiResp.Name,
+			Country: apiResp.Sys.Country,
+			Lat:     apiResp.Coord.Lat,
+			Lon:     apiResp.Coord.Lon,
+		},
+	}, nil
+}
+
+// FetchForecast aggregates the 3-hourly forecast into per-day summaries using
+// the city's local date.
+func (c *OpenWeatherMapClient) FetchForecast(ctx context.Context, query core.LocationQuery, days int) (*core.Forecast, error) {
+	if days < 1 || days > MaxForecastDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidForecast, MaxForecastDays)
+	}
+	if days > openWeatherMapMaxForecastDays {
+		return nil, ErrNotSupported
+	}
+
+	params, err := locationParams(query)
+	if err != nil {
+		return nil, err
+	}
+	if query.Lang != "" {
+		params.Add("lang", query.Lang)
+	}
+
+	var apiResp openWeatherMapForecastResponse
+	if err := c.get(ctx, "forecast", params, &apiResp); err != nil {
+		return nil, err
+	}
+
+	offset := time.Duration(apiResp.City.Timezone) * time.Second
+	forecast := &core.Forecast{City: apiResp.City.Name}
+	dayIndex := map[string]int{}
+	for _, item := range apiResp.List {
+		date := time.Unix(item.Dt, 0).UTC().Add(offset).Format("2006-01-02")
+		condition, isDay := conditionOf(item.Weather)
+		idx, ok := dayIndex[date]
+		if !ok {
+			if len(forecast.Days) == days {
+				break
+			}
+			forecast.Days = append(forecast.Days, core.ForecastDay{
+				Date:           date,
+				MaxTemperature: math.Inf(-1),
+				MinTemperature: math.Inf(1),
+				Description:    describe(item.Weather),
+				Condition:      condition,
+				Icon:           condition.Icon(true),
+			})
+			idx = len(forecast.Days) - 1
+			dayIndex[date] = idx
+		}
+
+		day := &forecast.Days[idx]
+		day.MaxTemperature = math.Max(day.MaxTemperature, item.Main.TempMax)
+		day.MinTemperature = math.Min(day.MinTemperature, item.Main.TempMin)
+		day.TotalPrecip += item.Rain.ThreeHours
+		day.MaxWindSpeed = math.Max(day.MaxWindSpeed, msToKph(item.Wind.Speed))
+		if pop := int(math.Round(item.Pop * 100)); pop > day.ChanceOfRain {
+			day.ChanceOfRain = pop
+		}
+		day.Hours = append(day.Hours, core.ForecastHour{
+			Time:         time.Unix(item.Dt, 0).UTC(),
+			Temperature:  item.Main.Temp,
+			Humidity:     item.Main.Humidity,
+			ChanceOfRain: int(math.Round(item.Pop * 100)),
+			Description:  describe(item.Weather),
+			Condition:    condition,
+			IsDay:        isDay,
+			Icon:         condition.Icon(isDay),
+		})
+	}
+
+	for i := range forecast.Days {
+		var total float64
+		for _, h := range forecast.Days[i].Hours {
+			total += h.Humidity
+		}
+		forecast.Days[i].AvgHumidity = total / float64(len(forecast.Days[i].Hours))
+	}
+
+	return forecast, nil
+}
+
+type openWeatherMapGeoResult struct {
+	Name    string  `json:"name"`
+	State   string  `json:"state"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// SearchLocations uses the OpenWeatherMap geocoding API, which has no place IDs,
+// so the coordinates double as the ID.
+func (c *OpenWeatherMapClient) SearchLocations(ctx context.Context, query string) ([]core.Location, error) {
+	params := url.Values{}
+	params.Add("q", query)
+	params.Add("limit", strconv.Itoa(openWeatherMapMaxResults))
+
+	var results []openWeatherMapGeoResult
+	if err := c.getURL(ctx, c.geoBaseURL, "direct", params, &results); err != nil {
+		if errors.Is(err, ErrCityNotFound) {
+			return []core.Location{}, nil
+		}
+		return nil, err
+	}
+
+	locations := make([]core.Location, 0, len(results))
+	for _, r := range results {
+		loc := core.Location{
+			Name:    r.Name,
+			Region:  r.State,
+			Country: r.Country,
+			Lat:     r.Lat,
+			Lon:     r.Lon,
+		}
+		loc.ID = "openweathermap:" + loc.Coordinates()
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
+
+type openWeatherMapAirPollutionResponse struct {
+	List []struct {
+		Components struct {
+			CO    float64 `json:"co"`
+			NO2   float64 `json:"no2"`
+			O3    float64 `json:"o3"`
+			SO2   float64 `json:"so2"`
+			PM2_5 float64 `json:"pm2_5"`
+			PM10  float64 `json:"pm10"`
+		} `json:"components"`
+	} `json:"list"`
+}
+
+// FetchAirQuality only works with coordinates; the air pollution API has no
+// city lookup. OpenWeatherMap's own 1-5 index is replaced by indices derived from PM2.5.
+func (c *OpenWeatherMapClient) FetchAirQuality(ctx context.Context, query core.LocationQuery) (*core.AirQuality, error) {
+	if query.Kind != core.QueryByCoordinates {
+		return nil, ErrNotSupported
+	}
+	params, err := locationParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp openWeatherMapAirPollutionResponse
+	if err := c.getURL(ctx, c.baseURL, "air_pollution", params, &apiResp); err != nil {
+		return nil, err
+	}
+	if len(apiResp.List) == 0 {
+		return nil, fmt.Errorf("%w: empty air pollution response", ErrAPIRequest)
+	}
+
+	comp := apiResp.List[0].Components
+	return &core.AirQuality{
+		PM25:       comp.PM2_5,
+		PM10:       comp.PM10,
+		O3:         comp.O3,
+		NO2:        comp.NO2,
+		CO:         comp.CO,
+		SO2:        comp.SO2,
+		USEPAIndex: core.USEPAIndexFromPM25(comp.PM2_5),
+		DEFRAIndex: core.DEFRAIndexFromPM25(comp.PM2_5),
+	}, nil
+}
+
+// FetchAlerts is not supported: alerts are only part of the paid One Call API.
+func (c *OpenWeatherMapClient) FetchAlerts(ctx context.Context, query core.LocationQuery) ([]core.WeatherAlert, error) {
+	return nil, ErrNotSupported
+}
+
+// FetchAstronomy is not supported: the free API only has today's sun times.
+func (c *OpenWeatherMapClient) FetchAstronomy(ctx context.Context, query core.LocationQuery, date string) (*core.Astronomy, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,71 @@
+package weatherprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOpenWeatherMapStub(t *testing.T) *OpenWeatherMapClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"London","main":{"temp":14.0,"humidity":70},"weather":[{"description":"light rain"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewOpenWeatherMapClient("test-key")
+	c.baseURL = srv.URL
+	return c
+}
+
+func TestOpenWeatherMapClient_UnsupportedForecastLength(t *testing.T) {
+	_, err := newOpenWeatherMapStub(t).FetchForecast(context.Background(), core.CityQuery("London"), 7)
+	assert.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestOpenWeatherMapClient_LocalizedDescriptions(t *testing.T) {
+	ctx := context.Background()
+	query := core.CityQuery("Kyiv").WithLang("uk")
+
+	var lang string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang = r.URL.Query().Get("lang")
+		w.Write([]byte(`{"name":"Kyiv","main":{"temp":18.0},"weather":[{"description":"легкий дощ"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewOpenWeatherMapClient("test-key")
+	c.baseURL = srv.URL
+
+	weather, err := c.FetchWeather(ctx, query)
+	require.NoError(t, err)
+	assert.Equal(t, "uk", lang)
+	assert.Equal(t, "Легкий дощ", weather.Description)
+}
+
+func TestOpenWeatherMapClient_NormalizedConditions(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"London","weather":[{"id":802,"description":"scattered clouds","icon":"03n"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewOpenWeatherMapClient("test-key")
+	c.baseURL = srv.URL
+
+	weather, err := c.FetchWeather(ctx, core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, core.ConditionPartlyCloudy, weather.Condition)
+	assert.False(t, weather.IsDay)
+	assert.Equal(t, "partly-cloudy-night", weather.Icon)
+
+	// unmapped codes are unknown
+	condition, _ := conditionOf(nil)
+	assert.Equal(t, core.ConditionUnknown, condition)
+}
@@ -0,0 +1,189 @@
+package weatherprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("weather API rate limit exceeded")
+
+// RateLimitError reports an exhausted call budget and when it is worth trying again.
+type RateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: %s, retry after %s", ErrRateLimited, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Priority decides who gets the last part of a call budget.
+type Priority int
+
+const (
+	// PriorityAdHoc is for calls made on behalf of API requests.
+	PriorityAdHoc Priority = iota
+	// PriorityScheduled is for subscriber deliveries, which may use the whole budget.
+	PriorityScheduled
+)
+
+type priorityKey struct{}
+
+// WithPriority marks upstream calls made with ctx as having priority p.
+// Calls without a priority are ad-hoc.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityAdHoc
+}
+
+// adHocShare is the part of each budget ad-hoc calls may use; the rest is
+// kept for scheduled deliveries.
+const adHocShare = 0.9
+
+// quotaPersistTimeout bounds saving a call to the QuotaStore, which happens
+// even if the caller has gone away since the upstream call counts anyway.
+const quotaPersistTimeout = 2 * time.Second
+
+// QuotaStore persists monthly call counts so a restart doesn't reset them.
+type QuotaStore interface {
+	GetMonthlyUsage(ctx context.Context, name string, month time.Time) (int, error)
+	IncrementMonthlyUsage(ctx context.Context, name string, month time.Time) error
+}
+
+type QuotaUsage struct {
+	Name          string `json:"name"`
+	MinuteCalls   int    `json:"minute_calls"`
+	MinuteLimit   int    `json:"minute_limit"`
+	MonthCalls    int    `json:"month_calls"`
+	MonthLimit    int    `json:"month_limit"`
+	RejectedCalls int    `json:"rejected_calls"`
+}
+
+// QuotaLimiter counts calls against a per-minute and a per-month budget
+// (0 means unlimited). Minute counts live in memory; month counts are loaded
+// from and saved to the store.
+type QuotaLimiter struct {
+	name       string
+	perMinute  int
+	perMonth   int
+	store      QuotaStore
+	now        func() time.Time
+	mu         sync.Mutex
+	minute     time.Time
+	minuteUsed int
+	month      time.Time
+	monthUsed  int
+	rejected   int
+}
+
+func NewQuotaLimiter(name string, perMinute, perMonth int, store QuotaStore) *QuotaLimiter {
+	return &QuotaLimiter{
+		name:      name,
+		perMinute: perMinute,
+		perMonth:  perMonth,
+		store:     store,
+		now:       time.Now,
+	}
+}
+
+// limitFor returns the part of limit available to calls of priority p.
+func limitFor(limit int, p Priority) int {
+	if limit <= 0 || p == PriorityScheduled {
+		return limit
+	}
+	return int(float64(limit) * adHocShare)
+}
+
+func monthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// loadMonth reads the stored usage once a new month has started. The store is
+// queried without holding mu so other callers don't wait on the database.
+func (l *QuotaLimiter) loadMonth(ctx context.Context) error {
+	month := monthOf(l.now().UTC())
+	l.mu.Lock()
+	loaded := !month.After(l.month)
+	l.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	used, err := l.store.GetMonthlyUsage(ctx, l.name, month)
+	if err != nil {
+		return fmt.Errorf("%w: loading %s quota usage: %v", ErrAPIRequest, l.name, err)
+	}
+	l.mu.Lock()
+	// a concurrent caller may have loaded the month and counted calls already
+	if month.After(l.month) {
+		l.month, l.monthUsed = month, used
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// Acquire takes one call from the budget or returns a *RateLimitError.
+func (l *QuotaLimiter) Acquire(ctx context.Context) error {
+	priority := priorityFrom(ctx)
+	if err := l.loadMonth(ctx); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	now := l.now().UTC()
+	if minute := now.Truncate(time.Minute); !minute.Equal(l.minute) {
+		l.minute, l.minuteUsed = minute, 0
+	}
+
+	if limit := limitFor(l.perMinute, priority); limit > 0 && l.minuteUsed >= limit {
+		l.rejected++
+		retryAfter := l.minute.Add(time.Minute).Sub(now)
+		l.mu.Unlock()
+		return &RateLimitError{Reason: l.name + " per-minute quota exhausted", RetryAfter: retryAfter}
+	}
+	if limit := limitFor(l.perMonth, priority); limit > 0 && l.monthUsed >= limit {
+		l.rejected++
+		retryAfter := l.month.AddDate(0, 1, 0).Sub(now)
+		l.mu.Unlock()
+		return &RateLimitError{Reason: l.name + " monthly quota exhausted", RetryAfter: retryAfter}
+	}
+	l.minuteUsed++
+	l.monthUsed++
+	month := l.month
+	l.mu.Unlock()
+
+	persistCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), quotaPersistTimeout)
+	defer cancel()
+	if err := l.store.IncrementMonthlyUsage(persistCtx, l.name, month); err != nil {
+		// the in-memory count still holds until the next restart
+		log.Printf("Failed to persist %s quota usage: %v", l.name, err)
+	}
+	return nil
+}
+
+func (l *QuotaLimiter) Usage() QuotaUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return QuotaUsage{
+		Name:          l.name,
+		MinuteCalls:   l.minuteUsed,
+		MinuteLimit:   l.perMinute,
+		MonthCalls:    l.monthUsed,
+		MonthLimit:    l.perMonth,
+		RejectedCalls: l.rejected,
+	}
+}
@@ -0,0 +1,172 @@
+package weatherprovider
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryQuotaStore struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newMemoryQuotaStore() *memoryQuotaStore {
+	return &memoryQuotaStore{calls: make(map[string]int)}
+}
+
+func (s *memoryQuotaStore) GetMonthlyUsage(ctx context.Context, name string, month time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[name+month.Format("2006-01")], nil
+}
+
+func (s *memoryQuotaStore) IncrementMonthlyUsage(ctx context.Context, name string, month time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[name+month.Format("2006-01")]++
+	return nil
+}
+
+func TestQuotaLimiter_ReservesBudgetForScheduledCalls(t *testing.T) {
+	now := time.Date(2025, 5, 20, 8, 0, 10, 0, time.UTC)
+	limiter := NewQuotaLimiter("weatherapi", 10, 0, newMemoryQuotaStore())
+	limiter.now = func() time.Time { return now }
+
+	adHoc := context.Background()
+	scheduled := WithPriority(context.Background(), PriorityScheduled)
+
+	for i := 0; i < 9; i++ {
+		require.NoError(t, limiter.Acquire(adHoc))
+	}
+	err := limiter.Acquire(adHoc)
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 50*time.Second, rateLimitErr.RetryAfter)
+
+	assert.NoError(t, limiter.Acquire(scheduled), "scheduled calls may use the reserve")
+	assert.Error(t, limiter.Acquire(scheduled))
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, limiter.Acquire(adHoc), "a new minute starts a new budget")
+	assert.Equal(t, 2, limiter.Usage().RejectedCalls)
+}
+
+func TestQuotaLimiter_MonthlyUsageSurvivesRestart(t *testing.T) {
+	now := time.Date(2025, 5, 31, 23, 0, 0, 0, time.UTC)
+	store := newMemoryQuotaStore()
+
+	first := NewQuotaLimiter("weatherapi", 0, 3, store)
+	first.now = func() time.Time { return now }
+	scheduled := WithPriority(context.Background(), PriorityScheduled)
+	require.NoError(t, first.Acquire(scheduled))
+	require.NoError(t, first.Acquire(scheduled))
+
+	restarted := NewQuotaLimiter("weatherapi", 0, 3, store)
+	restarted.now = func() time.Time { return now }
+	require.NoError(t, restarted.Acquire(scheduled))
+	err := restarted.Acquire(scheduled)
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, time.Hour, rateLimitErr.RetryAfter, "budget resets at the start of next month")
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, restarted.Acquire(scheduled))
+	assert.Equal(t, 1, restarted.Usage().MonthCalls)
+}
+
+func TestQuotaLimiter_LoadsUsageWithoutLocking(t *testing.T) {
+	store := &blockingQuotaStore{memoryQuotaStore: newMemoryQuotaStore(), release: make(chan struct{})}
+	limiter := NewQuotaLimiter("weatherapi", 0, 10, store)
+
+	done := make(chan error)
+	go func() { done <- limiter.Acquire(context.Background()) }()
+
+	// Usage takes the same lock Acquire would hold while loading
+	usage := make(chan QuotaUsage)
+	go func() { usage <- limiter.Usage() }()
+	select {
+	case <-usage:
+	case <-time.After(time.Second):
+		t.Fatal("Usage waited for the monthly usage query")
+	}
+
+	close(store.release)
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, limiter.Usage().MonthCalls)
+}
+
+type blockingQuotaStore struct {
+	*memoryQuotaStore
+	release chan struct{}
+}
+
+func (s *blockingQuotaStore) GetMonthlyUsage(ctx context.Context, name string, month time.Time) (int, error) {
+	<-s.release
+	return s.memoryQuotaStore.GetMonthlyUsage(ctx, name, month)
+}
+
+func TestClient_ChargesEveryAttempt(t *testing.T) {
+	limiter := NewQuotaLimiter("weatherapi", 0, 0, newMemoryQuotaStore())
+	c, calls := newRetryingClient(t,
+		stubResponse{status: http.StatusServiceUnavailable},
+		stubResponse{status: http.StatusBadGateway},
+		stubResponse{status: http.StatusOK, body: currentWeatherBody},
+	)
+	c.quota = limiter
+
+	_, err := c.FetchWeather(context.Background(), core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	assert.Equal(t, 3, limiter.Usage().MinuteCalls)
+	assert.Equal(t, 3, limiter.Usage().MonthCalls)
+}
+
+func TestClient_StopsRetryingWhenQuotaRunsOut(t *testing.T) {
+	limiter := NewQuotaLimiter("weatherapi", 2, 0, newMemoryQuotaStore())
+	c, calls := newRetryingClient(t, stubResponse{status: http.StatusServiceUnavailable})
+	c.quota = limiter
+	scheduled := WithPriority(context.Background(), PriorityScheduled)
+
+	_, err := c.FetchWeather(scheduled, core.CityQuery("London"))
+	assert.ErrorIs(t, err, ErrUpstream, "the last upstream failure is reported")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	_, err = c.FetchWeather(scheduled, core.CityQuery("London"))
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls), "no request is made without budget")
+}
+
+func TestFailoverProvider_RateLimitedBackendIsSkipped(t *testing.T) {
+	limiter := NewQuotaLimiter("weatherapi", 1, 0, newMemoryQuotaStore())
+	weatherAPI, calls := newWeatherAPIStub(t, http.StatusOK, currentWeatherBody)
+	weatherAPI.quota = limiter
+	fp := NewFailoverProvider(1, time.Minute,
+		Backend{Name: "weatherapi", Provider: weatherAPI},
+		Backend{Name: "openmeteo", Provider: newOpenMeteoStub(t)},
+	)
+	scheduled := WithPriority(context.Background(), PriorityScheduled)
+
+	_, err := fp.FetchWeather(scheduled, core.CityQuery("London"))
+	require.NoError(t, err)
+	weather, err := fp.FetchWeather(scheduled, core.CityQuery("London"))
+	require.NoError(t, err)
+	assert.Equal(t, 12.3, weather.Temperature, "second call should come from the next backend")
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	assert.Equal(t, "closed", fp.Health()[0].State, "rate limiting must not open the breaker")
+
+	only := NewFailoverProvider(1, time.Minute, Backend{Name: "weatherapi", Provider: weatherAPI})
+	_, err = only.FetchWeather(scheduled, core.CityQuery("London"))
+	var rateLimitErr *RateLimitError
+	assert.ErrorAs(t, err, &rateLimitErr)
+	assert.ErrorIs(t, err, ErrAPIRequest)
+}
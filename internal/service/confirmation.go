@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrConfirmationExpired     = errors.New("confirmation link expired")
+	ErrConfirmationRateLimited = errors.New("confirmation email sent too recently")
+)
+
+// ConfirmationRateLimitError is returned when a confirmation email went out to
+// the address too recently to send another.
+type ConfirmationRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ConfirmationRateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", ErrConfirmationRateLimited, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ConfirmationRateLimitError) Is(target error) bool {
+	return target == ErrConfirmationRateLimited
+}
+
+// ConfirmationPolicy decides how long confirmation links work and how long
+// unconfirmed emails are kept.
+type ConfirmationPolicy struct {
+	// TokenTTL is how long a confirmation link works after it is sent.
+	TokenTTL time.Duration
+	// ResendInterval is the least time between two confirmation emails to
+	// the same address.
+	ResendInterval time.Duration
+	// UnconfirmedRetention is how long after its last confirmation email an
+	// unconfirmed subscriber is purged; 0 keeps them.
+	UnconfirmedRetention time.Duration
+}
+
+func DefaultConfirmationPolicy() ConfirmationPolicy {
+	return ConfirmationPolicy{
+		TokenTTL:             48 * time.Hour,
+		ResendInterval:       5 * time.Minute,
+		UnconfirmedRetention: 7 * 24 * time.Hour,
+	}
+}
+
+// sendConfirmation issues the subscriber a fresh confirmation token and emails
+// its link, naming sub's city. Any earlier link stops working. It returns a
+// *ConfirmationRateLimitError when the last email went out less than
+// ResendInterval ago.
+func (s *SubscriptionService) sendConfirmation(ctx context.Context, subscriber *core.Subscriber, sub core.Subscription) error {
+	now := time.Now().UTC()
+	token := uuid.NewString()
+	expiresAt := now.Add(s.confirmation.TokenTTL)
+
+	claimed, err := s.subscriberRepo.ReissueConfirmation(ctx, subscriber.ID, token, expiresAt, now, now.Add(-s.confirmation.ResendInterval))
+	if err != nil {
+		log.Printf("Error issuing confirmation token for subscriber ID %s: %v", subscriber.ID, err)
+		return fmt.Errorf("could not send confirmation email")
+	}
+	if !claimed {
+		retryAfter := s.confirmation.ResendInterval
+		if subscriber.ConfirmationSentAt != nil {
+			retryAfter = subscriber.ConfirmationSentAt.Add(s.confirmation.ResendInterval).Sub(now)
+		}
+		return &ConfirmationRateLimitError{RetryAfter: max(retryAfter, time.Second)}
+	}
+
+	confirmationLink := s.link("/api/confirm/"+token, subscriber.Language)
+	if err := s.emailer.SendConfirmationEmail(ctx, subscriber.Email, subscriber.Language, sub.City, confirmationLink, s.manageLink(sub)); err != nil {
+		log.Printf("Failed to send confirmation email to %s: %v", subscriber.Email, err)
+	}
+	log.Printf("Confirmation token %s sent to %s, valid until %s.", token, subscriber.Email, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// confirmPending confirms and schedules the subscription waiting on token, a
+// city a confirmed email asked for before subscribers existed.
+func (s *SubscriptionService) confirmPending(ctx context.Context, token string) error {
+	sub, err := s.repo.ConfirmPending(ctx, token)
+	if err != nil {
+		log.Printf("Error confirming pending subscription for token %s: %v", token, err)
+		return fmt.Errorf("could not confirm subscription")
+	}
+	if sub == nil {
+		return ErrSubscriptionNotFound
+	}
+	if err := s.repo.SetNextDueAt(ctx, sub.ID, sub.NextDeliveryAfter(time.Now().UTC())); err != nil {
+		// SendWeatherUpdates schedules it if this fails
+		log.Printf("Error scheduling subscription ID %s: %v", sub.ID, err)
+	}
+	log.Printf("Pending subscription ID %s confirmed for email %s in %s", sub.ID, sub.Email, sub.City)
+	return nil
+}
+
+// resendPendingConfirmation emails the link of a pending subscription again
+// when its email subscribes to the city once more.
+func (s *SubscriptionService) resendPendingConfirmation(ctx context.Context, sub core.Subscription) {
+	confirmationLink := s.link("/api/confirm/"+*sub.PendingToken, sub.Language)
+	if err := s.emailer.SendConfirmationEmail(ctx, sub.Email, sub.Language, sub.City, confirmationLink, s.manageLink(sub)); err != nil {
+		log.Printf("Failed to send confirmation email to %s: %v", sub.Email, err)
+		return
+	}
+	log.Printf("Pending confirmation link for subscription ID %s sent to %s.", sub.ID, sub.Email)
+}
+
+// ResendConfirmation emails a new confirmation link to an address that has
+// subscribed but not confirmed yet. Addresses that are unknown, confirmed or
+// were sent a link within ResendInterval get nothing, and that is not reported
+// to the caller so it can't tell them apart. Only database failures are
+// returned.
+func (s *SubscriptionService) ResendConfirmation(ctx context.Context, email string) error {
+	subscriber, err := s.subscriberRepo.FindByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Error finding subscriber %s: %v", email, err)
+		return fmt.Errorf("database error during confirmation")
+	}
+	if subscriber == nil {
+		log.Printf("Confirmation resend requested for unknown address %s.", email)
+		return nil
+	}
+	if subscriber.IsConfirmed {
+		log.Printf("Confirmation resend requested for confirmed address %s.", email)
+		return nil
+	}
+
+	subs, err := s.repo.ListBySubscriber(ctx, subscriber.ID)
+	if err != nil {
+		log.Printf("Error listing subscriptions of subscriber ID %s: %v", subscriber.ID, err)
+		return fmt.Errorf("database error during confirmation")
+	}
+	if len(subs) == 0 {
+		log.Printf("Confirmation resend requested for %s, which has no subscriptions.", email)
+		return nil
+	}
+	err = s.sendConfirmation(ctx, subscriber, subs[0])
+	if errors.Is(err, ErrConfirmationRateLimited) {
+		log.Printf("Confirmation resend for %s skipped: %v", email, err)
+		return nil
+	}
+	return err
+}
+
+// PurgeUnconfirmed deletes the subscribers, and their subscriptions, that were
+// not confirmed within UnconfirmedRetention of their last confirmation email.
+func (s *SubscriptionService) PurgeUnconfirmed(ctx context.Context) {
+	if s.confirmation.UnconfirmedRetention <= 0 {
+		return
+	}
+	before := time.Now().UTC().Add(-s.confirmation.UnconfirmedRetention)
+	purged, err := s.subscriberRepo.DeleteUnconfirmed(ctx, before)
+	if err != nil {
+		log.Printf("Scheduler: Error purging unconfirmed subscribers: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Scheduler: Purged %d unconfirmed subscribers last emailed before %s.", purged, before.Format(time.RFC3339))
+	}
+}
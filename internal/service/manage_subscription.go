@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"weather-app/internal/core"
+	"weather-app/internal/platform/database"
+
+	"github.com/google/uuid"
+)
+
+// subscriptionByToken finds the subscription a manage token belongs to. Every
+// email carries it in its manage link, and it also manages the rules.
+func (s *SubscriptionService) subscriptionByToken(ctx context.Context, token string) (*core.Subscription, error) {
+	if _, err := uuid.Parse(token); err != nil {
+		return nil, ErrInvalidToken
+	}
+	sub, err := s.repo.FindByManageToken(ctx, token)
+	if err != nil {
+		log.Printf("Error finding subscription by manage token %s: %v", token, err)
+		return nil, fmt.Errorf("database error during subscription lookup")
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+func (s *SubscriptionService) GetSubscription(ctx context.Context, token string) (*core.Subscription, error) {
+	return s.subscriptionByToken(ctx, token)
+}
+
+// UpdateSubscription applies the changes in req to the subscription the manage
+// token belongs to. A new schedule or resuming a paused subscription moves its
+// next update to the next delivery time from now.
+func (s *SubscriptionService) UpdateSubscription(ctx context.Context, token string, req core.SubscriptionUpdate) (*core.Subscription, error) {
+	sub, err := s.subscriptionByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	updated := *sub
+
+	if req.Frequency != nil || req.Weekday != nil {
+		frequency := sub.Frequency
+		if req.Frequency != nil {
+			frequency = *req.Frequency
+		}
+		weekday := ""
+		if req.Weekday != nil {
+			weekday = *req.Weekday
+		}
+		parsed, err := requestedFrequency(frequency, weekday)
+		if err != nil {
+			return nil, err
+		}
+		updated.Frequency = parsed.String()
+	}
+	if req.DeliveryTime != nil {
+		clock, err := core.ParseClockTime(*req.DeliveryTime)
+		if err != nil {
+			return nil, err
+		}
+		updated.DeliveryTime = clock.String()
+	}
+	if req.Units != nil {
+		units, err := core.ParseUnits(*req.Units)
+		if err != nil {
+			return nil, err
+		}
+		updated.Units = units.String()
+	}
+	if req.Paused != nil {
+		updated.IsPaused = *req.Paused
+	}
+
+	if req.City != nil {
+		if strings.TrimSpace(*req.City) == "" {
+			return nil, ErrCityNotFound
+		}
+		location, err := s.ResolveLocation(ctx, *req.City)
+		if err != nil {
+			return nil, err
+		}
+		if location.ID != sub.LocationID || location.Name != sub.City {
+			existing, err := s.repo.FindBySubscriberAndLocation(ctx, sub.SubscriberID, location.ID)
+			if err == nil && (existing == nil || existing.ID == sub.ID) {
+				existing, err = s.repo.FindBySubscriberAndCity(ctx, sub.SubscriberID, location.Name)
+			}
+			if err != nil {
+				log.Printf("Error checking for existing subscription: %v", err)
+				return nil, fmt.Errorf("could not update subscription")
+			}
+			if existing != nil && existing.ID != sub.ID {
+				return nil, ErrSubscriptionAlreadyExists
+			}
+			updated.City = location.Name
+			updated.LocationID = location.ID
+			updated.Country = location.Country
+			updated.Lat = location.Lat
+			updated.Lon = location.Lon
+			updated.Timezone = location.Timezone
+			// a delivery time kept in the old city's timezone moves with the city
+			if sub.DeliveryTimezone == sub.Timezone {
+				if _, err := core.LoadTimezone(location.Timezone); err == nil {
+					updated.DeliveryTimezone = location.Timezone
+				}
+			}
+		}
+	}
+
+	// next_due_at is left alone otherwise, as a scheduler run may have moved it since sub was read
+	reschedule := updated.Frequency != sub.Frequency || updated.DeliveryTime != sub.DeliveryTime ||
+		updated.DeliveryTimezone != sub.DeliveryTimezone || updated.IsPaused != sub.IsPaused
+	if reschedule {
+		updated.NextDueAt = nil
+		if updated.IsConfirmed && !updated.IsPaused {
+			updated.NextDueAt = updated.NextDeliveryAfter(time.Now().UTC())
+		}
+	}
+
+	// a new location clears the air quality alert state, and units are saved
+	// to the subscriber, in the same transaction
+	if err := s.repo.UpdateSettings(ctx, &updated, reschedule); err != nil {
+		if errors.Is(err, database.ErrSubscriptionNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		log.Printf("Error updating subscription ID %s: %v", sub.ID, err)
+		return nil, fmt.Errorf("could not update subscription")
+	}
+
+	log.Printf("Subscription ID %s updated: city %s, frequency %s at %s, units %s, paused %t.",
+		updated.ID, updated.City, updated.Frequency, updated.DeliveryTime, updated.Units, updated.IsPaused)
+	return &updated, nil
+}
+
+// DeleteSubscription deletes the subscription the manage token belongs to.
+func (s *SubscriptionService) DeleteSubscription(ctx context.Context, token string) error {
+	sub, err := s.subscriptionByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	return s.deleteSubscription(ctx, sub)
+}
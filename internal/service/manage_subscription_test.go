@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weather-app/internal/core"
+	"weather-app/internal/platform/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testManageToken = "0b5e1c42-7d3a-4f0e-8c6a-2a9f4d1e7b30"
+
+// londonSubscription is a confirmed daily subscription due tomorrow morning.
+func londonSubscription() *core.Subscription {
+	nextDueAt := time.Now().UTC().Add(20 * time.Hour)
+	return &core.Subscription{
+		ID: "sub-london", SubscriberID: "subscriber-1", Email: "test@example.com",
+		City: "London", LocationID: "test:london", Country: "United Kingdom", Timezone: "Europe/London",
+		Frequency: "daily", Units: "metric", Language: "en", DeliveryTime: "08:00", DeliveryTimezone: "Europe/London",
+		NextDueAt: &nextDueAt, AQIAlertActive: true, IsConfirmed: true, ManageToken: testManageToken,
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	tests := []struct {
+		name             string
+		update           core.SubscriptionUpdate
+		setupMocks       func(ts *testService, sub *core.Subscription)
+		expectReschedule bool
+		check            func(t *testing.T, sub, updated *core.Subscription)
+	}{
+		{
+			name:   "city change follows the new city's timezone",
+			update: core.SubscriptionUpdate{City: strPtr("tokyo")},
+			setupMocks: func(ts *testService, sub *core.Subscription) {
+				ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+				ts.subs.On("FindBySubscriberAndLocation", mock.Anything, sub.SubscriberID, tokyo.ID).Return(nil, nil).Once()
+				ts.subs.On("FindBySubscriberAndCity", mock.Anything, sub.SubscriberID, tokyo.Name).Return(nil, nil).Once()
+			},
+			expectReschedule: true,
+			check: func(t *testing.T, sub, updated *core.Subscription) {
+				assert.Equal(t, "Tokyo", updated.City)
+				assert.Equal(t, tokyo.ID, updated.LocationID)
+				assert.Equal(t, "Asia/Tokyo", updated.DeliveryTimezone)
+				assert.Equal(t, updated.NextDeliveryAfter(time.Now().UTC()).Truncate(time.Minute), updated.NextDueAt.Truncate(time.Minute))
+			},
+		},
+		{
+			name:   "city change keeps an explicit timezone",
+			update: core.SubscriptionUpdate{City: strPtr("tokyo")},
+			setupMocks: func(ts *testService, sub *core.Subscription) {
+				sub.DeliveryTimezone = "America/New_York"
+				ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+				ts.subs.On("FindBySubscriberAndLocation", mock.Anything, sub.SubscriberID, tokyo.ID).Return(nil, nil).Once()
+				ts.subs.On("FindBySubscriberAndCity", mock.Anything, sub.SubscriberID, tokyo.Name).Return(nil, nil).Once()
+			},
+			check: func(t *testing.T, sub, updated *core.Subscription) {
+				assert.Equal(t, "Tokyo", updated.City)
+				assert.Equal(t, "America/New_York", updated.DeliveryTimezone)
+			},
+		},
+		{
+			name:             "new frequency reschedules",
+			update:           core.SubscriptionUpdate{Frequency: strPtr("weekly"), Weekday: strPtr("friday")},
+			expectReschedule: true,
+			check: func(t *testing.T, sub, updated *core.Subscription) {
+				assert.Equal(t, "weekly_friday", updated.Frequency)
+				require.NotNil(t, updated.NextDueAt)
+				assert.Equal(t, time.Friday, updated.NextDueAt.In(updated.DeliveryLocation()).Weekday())
+			},
+		},
+		{
+			name:             "pausing clears the next update",
+			update:           core.SubscriptionUpdate{Paused: boolPtr(true)},
+			expectReschedule: true,
+			check: func(t *testing.T, sub, updated *core.Subscription) {
+				assert.True(t, updated.IsPaused)
+				assert.Nil(t, updated.NextDueAt)
+			},
+		},
+		{
+			name:   "units are saved with the settings",
+			update: core.SubscriptionUpdate{Units: strPtr("imperial")},
+			check: func(t *testing.T, sub, updated *core.Subscription) {
+				assert.Equal(t, "imperial", updated.Units)
+				assert.Equal(t, sub.NextDueAt, updated.NextDueAt)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			sub := londonSubscription()
+			if tc.setupMocks != nil {
+				tc.setupMocks(ts, sub)
+			}
+			ts.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+			ts.subs.On("UpdateSettings", mock.Anything, mock.AnythingOfType("*core.Subscription"), tc.expectReschedule).Return(nil).Once()
+
+			updated, err := ts.UpdateSubscription(context.Background(), testManageToken, tc.update)
+
+			require.NoError(t, err)
+			tc.check(t, sub, updated)
+			ts.assertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateSubscription_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		update        core.SubscriptionUpdate
+		setupMocks    func(ts *testService, sub *core.Subscription)
+		expectedError error
+	}{
+		{
+			name:          "malformed token",
+			token:         "not-a-uuid",
+			update:        core.SubscriptionUpdate{Paused: boolPtr(true)},
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name:   "unknown token",
+			token:  testManageToken,
+			update: core.SubscriptionUpdate{Paused: boolPtr(true)},
+			setupMocks: func(ts *testService, sub *core.Subscription) {
+				ts.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(nil, nil).Once()
+			},
+			expectedError: ErrSubscriptionNotFound,
+		},
+		{
+			name:   "invalid frequency",
+			token:  testManageToken,
+			update: core.SubscriptionUpdate{Frequency: strPtr("monthly")},
+			setupMocks: func(ts *testService, sub *core.Subscription) {
+				ts.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+			},
+			expectedError: core.ErrInvalidFrequency,
+		},
+		{
+			name:   "city already subscribed",
+			token:  testManageToken,
+			update: core.SubscriptionUpdate{City: strPtr("tokyo")},
+			setupMocks: func(ts *testService, sub *core.Subscription) {
+				ts.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+				ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+				ts.subs.On("FindBySubscriberAndLocation", mock.Anything, sub.SubscriberID, tokyo.ID).
+					Return(&core.Subscription{ID: "sub-tokyo", SubscriberID: sub.SubscriberID, City: "Tokyo"}, nil).Once()
+			},
+			expectedError: ErrSubscriptionAlreadyExists,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			sub := londonSubscription()
+			if tc.setupMocks != nil {
+				tc.setupMocks(ts, sub)
+			}
+
+			_, err := ts.UpdateSubscription(context.Background(), tc.token, tc.update)
+
+			require.ErrorIs(t, err, tc.expectedError)
+			ts.assertExpectations(t)
+			ts.subs.AssertNotCalled(t, "UpdateSettings", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUpdateSubscription_DeletedMeanwhile(t *testing.T) {
+	ts := newTestService()
+	sub := londonSubscription()
+	ts.subs.On("FindByManageToken", mock.Anything, testManageToken).Return(sub, nil).Once()
+	ts.subs.On("UpdateSettings", mock.Anything, mock.MatchedBy(func(updated *core.Subscription) bool {
+		return updated.Units == "imperial" && updated.IsPaused
+	}), true).Return(database.ErrSubscriptionNotFound).Once()
+
+	_, err := ts.UpdateSubscription(context.Background(), testManageToken,
+		core.SubscriptionUpdate{Units: strPtr("imperial"), Paused: boolPtr(true)})
+
+	require.ErrorIs(t, err, ErrSubscriptionNotFound)
+	ts.assertExpectations(t)
+}
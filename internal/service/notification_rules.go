@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"weather-app/internal/core"
+	"weather-app/internal/i18n"
+	"weather-app/internal/platform/weatherprovider"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRuleNotFound = errors.New("notification rule not found")
+	ErrTooManyRules = errors.New("too many notification rules")
+)
+
+// MaxRulesPerSubscription caps the rules evaluated for one subscription on
+// every scheduler run.
+const MaxRulesPerSubscription = 10
+
+// AddRule validates req and attaches it to the subscription as a new rule.
+// Its values are read in the subscription's units.
+func (s *SubscriptionService) AddRule(ctx context.Context, token string, req core.RuleRequest) (*core.NotificationRule, error) {
+	period, err := core.ParseRulePeriod(req.Period)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Expression.Validate(); err != nil {
+		return nil, err
+	}
+	cooldownHours := core.DefaultRuleCooldownHours
+	if req.CooldownHours != nil {
+		cooldownHours = *req.CooldownHours
+	}
+	if cooldownHours < core.MinRuleCooldownHours || cooldownHours > core.MaxRuleCooldownHours {
+		return nil, fmt.Errorf("%w: cooldown_hours must be between %d and %d",
+			core.ErrInvalidRule, core.MinRuleCooldownHours, core.MaxRuleCooldownHours)
+	}
+	if len([]rune(req.Name)) > 100 {
+		return nil, fmt.Errorf("%w: name can be at most 100 characters", core.ErrInvalidRule)
+	}
+
+	sub, err := s.subscriptionByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.ruleRepo.ListBySubscription(ctx, sub.ID)
+	if err != nil {
+		log.Printf("Error listing notification rules for subscription ID %s: %v", sub.ID, err)
+		return nil, fmt.Errorf("could not add notification rule")
+	}
+	if len(existing) >= MaxRulesPerSubscription {
+		return nil, ErrTooManyRules
+	}
+
+	rule := &core.NotificationRule{
+		ID:             uuid.NewString(),
+		SubscriptionID: sub.ID,
+		Name:           req.Name,
+		Period:         period,
+		Units:          sub.Units,
+		Expression:     req.Expression,
+		CooldownHours:  cooldownHours,
+	}
+	if err := s.ruleRepo.Create(ctx, rule); err != nil {
+		log.Printf("Error creating notification rule for subscription ID %s: %v", sub.ID, err)
+		return nil, fmt.Errorf("could not add notification rule")
+	}
+	log.Printf("Notification rule %s added to subscription ID %s: %s", rule.ID, sub.ID, rule.Expression)
+	return rule, nil
+}
+
+func (s *SubscriptionService) ListRules(ctx context.Context, token string) ([]core.NotificationRule, error) {
+	sub, err := s.subscriptionByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.ruleRepo.ListBySubscription(ctx, sub.ID)
+	if err != nil {
+		log.Printf("Error listing notification rules for subscription ID %s: %v", sub.ID, err)
+		return nil, fmt.Errorf("could not list notification rules")
+	}
+	return rules, nil
+}
+
+func (s *SubscriptionService) DeleteRule(ctx context.Context, token, ruleID string) error {
+	if _, err := uuid.Parse(ruleID); err != nil {
+		return ErrRuleNotFound
+	}
+	sub, err := s.subscriptionByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.ruleRepo.Delete(ctx, sub.ID, ruleID)
+	if err != nil {
+		log.Printf("Error deleting notification rule %s: %v", ruleID, err)
+		return fmt.Errorf("could not delete notification rule")
+	}
+	if !deleted {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// CheckNotificationRules evaluates the rules that aren't cooling down against
+// fresh weather and notifies subscribers whose rules match.
+func (s *SubscriptionService) CheckNotificationRules(ctx context.Context) {
+	ctx = weatherprovider.WithPriority(ctx, weatherprovider.PriorityScheduled)
+	now := time.Now().UTC()
+
+	rules, err := s.ruleRepo.GetEligible(ctx, now)
+	if err != nil {
+		log.Printf("Scheduler: Error fetching notification rules: %v", err)
+		return
+	}
+
+	// rules come ordered by subscription, so each subscription's weather is fetched once per run
+	for start := 0; start < len(rules); {
+		end := start + 1
+		for end < len(rules) && rules[end].SubscriptionID == rules[start].SubscriptionID {
+			end++
+		}
+		if ctx.Err() != nil {
+			log.Printf("Scheduler: CheckNotificationRules cancelled: %v", ctx.Err())
+			return
+		}
+		s.checkNotificationRules(ctx, rules[start:end], now)
+		start = end
+	}
+}
+
+func (s *SubscriptionService) checkNotificationRules(ctx context.Context, rules []core.NotificationRule, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	sub, err := s.repo.FindByID(ctx, rules[0].SubscriptionID)
+	if err != nil || sub == nil {
+		log.Printf("Scheduler: Failed to load subscription ID %s for notification rules: %v", rules[0].SubscriptionID, err)
+		return
+	}
+	units, err := core.ParseUnits(sub.Units)
+	if err != nil {
+		units = core.MetricUnits
+	}
+
+	var current *core.Weather
+	var forecast *core.Forecast
+	for _, rule := range rules {
+		ruleUnits, err := core.ParseUnits(rule.Units)
+		if err != nil {
+			log.Printf("Notification rule %s has invalid units %q, using metric: %v", rule.ID, rule.Units, err)
+			ruleUnits = core.MetricUnits
+		}
+
+		var input core.RuleInput
+		var details string
+		if rule.Period == core.RuleNow {
+			if current == nil {
+				if current, err = s.weatherProvider.FetchWeather(ctx, sub.WeatherQuery()); err != nil {
+					log.Printf("Scheduler: Failed to fetch weather for %s (subscriber %s): %v", sub.City, sub.Email, err)
+					return
+				}
+			}
+			input = core.RuleInputFromWeather(*current, ruleUnits)
+			details = formatCurrentWeather(*sub, units, current)
+		} else {
+			if forecast == nil {
+				if forecast, err = s.weatherProvider.FetchForecast(ctx, sub.WeatherQuery(), 2); err != nil {
+					log.Printf("Scheduler: Failed to fetch forecast for %s (subscriber %s): %v", sub.City, sub.Email, err)
+					return
+				}
+			}
+			day := 0
+			if rule.Period == core.RuleTomorrow {
+				day = 1
+			}
+			if day >= len(forecast.Days) {
+				log.Printf("Scheduler: Forecast for %s has no %s, skipping notification rule %s.", sub.City, rule.Period, rule.ID)
+				continue
+			}
+			input = core.RuleInputFromForecastDay(forecast.Days[day], ruleUnits)
+			details = formatRuleForecast(*sub, units, forecast.Days[day])
+		}
+
+		if !rule.Expression.Matches(input) {
+			continue
+		}
+		// claiming before sending means overlapping runs never send the same notification twice
+		claimed, err := s.ruleRepo.ClaimFired(ctx, rule.ID, rule.LastFiredAt, now)
+		if err != nil {
+			log.Printf("Scheduler: Failed to record notification rule %s: %v", rule.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		name := rule.Name
+		if name == "" {
+			name = rule.Expression.String()
+		}
+		info := i18n.T(sub.Language, i18n.MsgRuleMatched, name, i18n.T(sub.Language, i18n.RulePeriod(string(rule.Period))), sub.City) +
+			"\n\n" + details
+		unsubscribeLink := s.link("/api/unsubscribe/"+sub.UnsubscribeToken, sub.Language)
+		if err := s.emailer.SendRuleNotificationEmail(ctx, sub.Email, sub.Language, sub.City, name, info, s.manageLink(*sub), unsubscribeLink); err != nil {
+			log.Printf("Scheduler: Failed to send rule notification to %s for city %s: %v", sub.Email, sub.City, err)
+			continue
+		}
+		log.Printf("Scheduler: Sent rule notification %s to %s for city %s.", rule.ID, sub.Email, sub.City)
+	}
+}
+
+// formatRuleForecast describes the forecast day a rule matched.
+func formatRuleForecast(sub core.Subscription, units core.UnitSystem, day core.ForecastDay) string {
+	return i18n.T(sub.Language, i18n.MsgRuleForecast,
+		sub.City, day.Date, units.FormatTemperature(day.MaxTemperature), units.FormatTemperature(day.MinTemperature),
+		units.FormatPrecipitation(day.TotalPrecip), day.ChanceOfRain, units.FormatSpeed(day.MaxWindSpeed),
+		conditionText(sub.Language, day.Condition, day.Description),
+	)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weather-app/internal/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNotificationRules_FiresOncePerCooldown(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestService()
+
+	sub := &core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv", Units: "metric", Language: "en", UnsubscribeToken: "unsub-1"}
+	rule := core.NotificationRule{
+		ID:             "rule-1",
+		SubscriptionID: sub.ID,
+		Name:           "Heat",
+		Period:         core.RuleNow,
+		Units:          "metric",
+		Expression:     core.RuleExpr{Field: core.RuleTemperature, Op: core.RuleGreater, Value: 25.0},
+		CooldownHours:  24,
+	}
+	hot := &core.Weather{Temperature: 30, ObservedAt: time.Now().UTC()}
+
+	// first run: the rule matches, is claimed and the notification goes out
+	ts.rules.On("GetEligible", mock.Anything, mock.Anything).Return([]core.NotificationRule{rule}, nil).Once()
+	ts.subs.On("FindByID", mock.Anything, sub.ID).Return(sub, nil)
+	ts.provider.On("FetchWeather", mock.Anything, sub.WeatherQuery()).Return(hot, nil)
+	ts.rules.On("ClaimFired", mock.Anything, rule.ID, (*time.Time)(nil), mock.Anything).Return(true, nil).Once()
+	ts.emailer.On("SendRuleNotificationEmail", mock.Anything, sub.Email, "en", "Kyiv", "Heat", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil).Once()
+	ts.CheckNotificationRules(ctx)
+
+	// an overlapping run read the rule before it fired and loses the claim
+	ts.rules.On("GetEligible", mock.Anything, mock.Anything).Return([]core.NotificationRule{rule}, nil).Once()
+	ts.rules.On("ClaimFired", mock.Anything, rule.ID, (*time.Time)(nil), mock.Anything).Return(false, nil).Once()
+	ts.CheckNotificationRules(ctx)
+
+	// later runs don't see the rule until its cool-down is over
+	ts.rules.On("GetEligible", mock.Anything, mock.Anything).Return([]core.NotificationRule{}, nil).Once()
+	ts.CheckNotificationRules(ctx)
+
+	ts.assertExpectations(t)
+	ts.emailer.AssertNumberOfCalls(t, "SendRuleNotificationEmail", 1)
+	ts.provider.AssertNumberOfCalls(t, "FetchWeather", 2)
+}
+
+func TestCheckNotificationRules_NoMatch(t *testing.T) {
+	ts := newTestService()
+
+	sub := &core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv", Units: "metric", Language: "en"}
+	rule := core.NotificationRule{
+		ID:             "rule-1",
+		SubscriptionID: sub.ID,
+		Period:         core.RuleNow,
+		Units:          "metric",
+		Expression:     core.RuleExpr{Field: core.RuleTemperature, Op: core.RuleGreater, Value: 25.0},
+		CooldownHours:  24,
+	}
+	ts.rules.On("GetEligible", mock.Anything, mock.Anything).Return([]core.NotificationRule{rule}, nil).Once()
+	ts.subs.On("FindByID", mock.Anything, sub.ID).Return(sub, nil).Once()
+	ts.provider.On("FetchWeather", mock.Anything, sub.WeatherQuery()).Return(&core.Weather{Temperature: 18}, nil).Once()
+
+	ts.CheckNotificationRules(context.Background())
+
+	ts.assertExpectations(t)
+	ts.rules.AssertNotCalled(t, "ClaimFired", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddRule_Invalid(t *testing.T) {
+	ts := newTestService()
+
+	_, err := ts.AddRule(context.Background(), "0b5e1c42-7d3a-4f0e-8c6a-2a9f4d1e7b30", core.RuleRequest{
+		Period:     "now",
+		Expression: core.RuleExpr{Field: core.RuleTemperature, Op: core.RuleIs, Value: 25.0},
+	})
+
+	require.ErrorIs(t, err, core.ErrInvalidRule)
+	assert.Contains(t, err.Error(), "temperature needs one of lt, lte, gt or gte")
+	ts.assertExpectations(t)
+}
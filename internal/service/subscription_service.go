@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 	"weather-app/internal/core"
+	"weather-app/internal/i18n"
 	"weather-app/internal/platform/database"
 	"weather-app/internal/platform/email"
 	"weather-app/internal/platform/weatherprovider"
@@ -14,116 +18,326 @@ import (
 )
 
 var (
-	ErrSubscriptionAlreadyExists = errors.New("email already subscribed to this city")
-	ErrSubscriptionNotFound      = errors.New("subscription not found")
-	ErrInvalidToken              = errors.New("invalid or expired token")
-	ErrAlreadyConfirmed          = errors.New("subscription already confirmed")
+	ErrSubscriptionAlreadyExists  = errors.New("email already subscribed to this city")
+	ErrSubscriptionNotFound       = errors.New("subscription not found")
+	ErrInvalidToken               = errors.New("invalid or expired token")
+	ErrAlreadyConfirmed           = errors.New("subscription already confirmed")
+	ErrCityNotFound               = errors.New("city not found")
+	ErrLocationUnavailable        = errors.New("could not resolve city right now")
+	ErrInvalidAQIThreshold        = errors.New("aqi threshold must be between 1 and 6")
+	ErrSubscriberSettingsConflict = errors.New("subscriber already uses other units or language")
 )
 
+// SubscriberSettingsConflictError is returned when a subscription asks for
+// units or a language other than the ones its email already uses.
+type SubscriberSettingsConflictError struct {
+	Units    string
+	Language string
+}
+
+func (e *SubscriberSettingsConflictError) Error() string {
+	return fmt.Sprintf("%v: %s units, language %s", ErrSubscriberSettingsConflict, e.Units, e.Language)
+}
+
+func (e *SubscriberSettingsConflictError) Is(target error) bool {
+	return target == ErrSubscriberSettingsConflict
+}
+
+// deliveryTimeout bounds the fetch and send for a single subscriber during a
+// scheduler run so one slow upstream can't stall the whole batch.
+const deliveryTimeout = 30 * time.Second
+
+// releaseTimeout bounds putting back a claimed delivery whose email failed,
+// which happens even when the delivery itself ran out of time.
+const releaseTimeout = 5 * time.Second
+
 type SubscriptionService struct {
 	repo            database.SubscriptionRepository
+	subscriberRepo  database.SubscriberRepository
+	alertRepo       database.AlertRepository
+	ruleRepo        database.RuleRepository
 	emailer         email.Service
 	weatherProvider weatherprovider.WeatherProvider
 	appBaseURL      string
+	confirmation    ConfirmationPolicy
 }
 
 func NewSubscriptionService(
 	repo database.SubscriptionRepository,
+	subscriberRepo database.SubscriberRepository,
+	alertRepo database.AlertRepository,
+	ruleRepo database.RuleRepository,
 	emailer email.Service,
 	weatherProvider weatherprovider.WeatherProvider,
 	appBaseURL string,
+	confirmation ConfirmationPolicy,
 ) *SubscriptionService {
 	return &SubscriptionService{
 		repo:            repo,
+		subscriberRepo:  subscriberRepo,
+		alertRepo:       alertRepo,
+		ruleRepo:        ruleRepo,
 		emailer:         emailer,
 		weatherProvider: weatherProvider,
 		appBaseURL:      appBaseURL,
+		confirmation:    confirmation,
 	}
 }
 
-func (s *SubscriptionService) CreateSubscription(req core.SubscriptionRequest) error {
-	if req.Frequency != "hourly" && req.Frequency != "daily" {
-		return fmt.Errorf("invalid frequency: %s. Must be 'hourly' or 'daily'", req.Frequency)
+// CreateSubscription subscribes req.Email to the weather in req.City. The first
+// subscription for an email creates its subscriber, which takes the request's
+// units and language and has to be confirmed once; later subscriptions must
+// ask for the same units and language, and start as soon as the email is
+// confirmed. Each subscription's delivery time is in req.Timezone, or else its
+// city's timezone.
+func (s *SubscriptionService) CreateSubscription(ctx context.Context, req core.SubscriptionRequest) (*core.Subscription, error) {
+	frequency, err := requestedFrequency(req.Frequency, req.Weekday)
+	if err != nil {
+		return nil, err
 	}
 
-	existingSub, err := s.repo.FindByEmailAndCity(req.Email, req.City)
+	units, err := core.ParseUnits(req.Units)
+	if err != nil {
+		return nil, err
+	}
+	if req.AQIThreshold != nil && (*req.AQIThreshold < 1 || *req.AQIThreshold > 6) {
+		return nil, ErrInvalidAQIThreshold
+	}
+	lang, err := i18n.Parse(req.Language)
 	if err != nil {
-		log.Printf("Error checking for existing subscription: %v", err)
-		return fmt.Errorf("could not process subscription request")
+		return nil, err
 	}
-	if existingSub != nil {
-		if existingSub.IsConfirmed {
-			return ErrSubscriptionAlreadyExists
+	deliveryTime := core.DefaultDeliveryTime
+	if req.DeliveryTime != "" {
+		clock, err := core.ParseClockTime(req.DeliveryTime)
+		if err != nil {
+			return nil, err
+		}
+		deliveryTime = clock.String()
+	}
+	if req.Timezone != "" {
+		if _, err := core.LoadTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	location, err := s.ResolveLocation(ctx, req.City)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriber, err := s.subscriberRepo.FindByEmail(ctx, req.Email)
+	if err != nil {
+		log.Printf("Error finding subscriber %s: %v", req.Email, err)
+		return nil, fmt.Errorf("could not process subscription request")
+	}
+	if subscriber != nil {
+		if (req.Units != "" && units.String() != subscriber.Units) || (req.Language != "" && lang != subscriber.Language) {
+			return nil, &SubscriberSettingsConflictError{Units: subscriber.Units, Language: subscriber.Language}
+		}
+		existingSub, err := s.repo.FindBySubscriberAndLocation(ctx, subscriber.ID, location.ID)
+		if err == nil && existingSub == nil {
+			// rows from before location resolution are only keyed by city name
+			existingSub, err = s.repo.FindBySubscriberAndCity(ctx, subscriber.ID, location.Name)
+		}
+		if err != nil {
+			log.Printf("Error checking for existing subscription: %v", err)
+			return nil, fmt.Errorf("could not process subscription request")
+		}
+		if existingSub != nil {
+			if existingSub.IsConfirmed {
+				return nil, ErrSubscriptionAlreadyExists
+			}
+			if existingSub.PendingToken != nil {
+				s.resendPendingConfirmation(ctx, *existingSub)
+				return existingSub, nil
+			}
+			// subscribing again replaces a lost or expired confirmation link
+			log.Printf("Subscription exists for %s in %s but not confirmed. ID: %s", req.Email, location.Name, existingSub.ID)
+			if err := s.sendConfirmation(ctx, subscriber, *existingSub); err != nil {
+				return nil, err
+			}
+			return existingSub, nil
+		}
+	} else {
+		subscriber = &core.Subscriber{
+			ID:          uuid.NewString(),
+			Email:       req.Email,
+			Units:       units.String(),
+			Language:    lang,
+			IsConfirmed: false,
+		}
+		if err := s.subscriberRepo.Create(ctx, subscriber); err != nil {
+			log.Printf("Error creating subscriber in DB: %v", err)
+			return nil, fmt.Errorf("could not save subscription")
 		}
-		log.Printf("Subscription exists for %s in %s but not confirmed. ID: %s", req.Email, req.City, existingSub.ID)
-		return ErrSubscriptionAlreadyExists
 	}
 
-	confirmationToken := uuid.NewString()
+	deliveryTimezone := req.Timezone
+	if deliveryTimezone == "" {
+		deliveryTimezone = location.Timezone
+	}
+	if _, err := core.LoadTimezone(deliveryTimezone); err != nil {
+		// not every provider knows the city's timezone
+		deliveryTimezone = "UTC"
+	}
 	unsubscribeToken := uuid.NewString()
+	manageToken := uuid.NewString()
 
 	newSub := &core.Subscription{
-		ID:                uuid.NewString(),
-		Email:             req.Email,
-		City:              req.City,
-		Frequency:         req.Frequency,
-		ConfirmationToken: &confirmationToken,
-		IsConfirmed:       false,
-		UnsubscribeToken:  unsubscribeToken,
+		ID:               uuid.NewString(),
+		SubscriberID:     subscriber.ID,
+		Email:            subscriber.Email,
+		City:             location.Name,
+		LocationID:       location.ID,
+		Country:          location.Country,
+		Lat:              location.Lat,
+		Lon:              location.Lon,
+		Timezone:         location.Timezone,
+		Frequency:        frequency.String(),
+		Units:            subscriber.Units,
+		AQIThreshold:     req.AQIThreshold,
+		Language:         subscriber.Language,
+		IncludeAstronomy: req.IncludeAstronomy,
+		DeliveryTime:     deliveryTime,
+		DeliveryTimezone: deliveryTimezone,
+		IsConfirmed:      subscriber.IsConfirmed,
+		UnsubscribeToken: unsubscribeToken,
+		ManageToken:      manageToken,
+	}
+	if newSub.IsConfirmed {
+		newSub.NextDueAt = newSub.NextDeliveryAfter(time.Now().UTC())
 	}
 
-	if err := s.repo.Create(newSub); err != nil {
+	if err := s.repo.Create(ctx, newSub); err != nil {
 		log.Printf("Error creating subscription in DB: %v", err)
-		return fmt.Errorf("could not save subscription")
+		s.deleteSubscriberIfUnused(ctx, subscriber.ID)
+		return nil, fmt.Errorf("could not save subscription")
 	}
 
-	confirmationLink := fmt.Sprintf("%s/api/confirm/%s", s.appBaseURL, confirmationToken)
-	if err := s.emailer.SendConfirmationEmail(newSub.Email, newSub.City, confirmationLink); err != nil {
-		log.Printf("Failed to send confirmation email to %s: %v", newSub.Email, err)
+	if newSub.IsConfirmed {
+		log.Printf("Subscription created for confirmed subscriber %s, city %s. Unsubscribe token: %s. Manage token: %s.",
+			newSub.Email, newSub.City, unsubscribeToken, manageToken)
+		return newSub, nil
 	}
 
-	log.Printf("Subscription created for %s, city %s. Confirmation token: %s. Unsubscribe token: %s.",
-		newSub.Email, newSub.City, confirmationToken, unsubscribeToken)
-	return nil
+	// confirming once starts all of a subscriber's subscriptions, so a link
+	// that is still valid confirms this city too
+	if subscriber.ConfirmationToken != nil && !subscriber.ConfirmationExpired(time.Now().UTC()) {
+		log.Printf("Confirmation for %s already pending, city %s joins it.", newSub.Email, newSub.City)
+	} else if err := s.sendConfirmation(ctx, subscriber, *newSub); err != nil {
+		log.Printf("No confirmation email sent to %s for city %s: %v", newSub.Email, newSub.City, err)
+	}
+
+	log.Printf("Subscription created for %s, city %s. Unsubscribe token: %s. Manage token: %s.",
+		newSub.Email, newSub.City, unsubscribeToken, manageToken)
+	return newSub, nil
 }
 
-func (s *SubscriptionService) ConfirmSubscription(token string) error {
+// requestedFrequency parses a frequency from a form, where weekday picks the
+// day of a weekly one.
+func requestedFrequency(frequency, weekday string) (core.Frequency, error) {
+	weekly := string(core.FrequencyWeekly)
+	if weekday != "" && (frequency == weekly || strings.HasPrefix(frequency, weekly+"_")) {
+		frequency = weekly + "_" + weekday
+	}
+	return core.ParseFrequency(frequency)
+}
+
+// link builds an absolute link to path whose response is in lang.
+func (s *SubscriptionService) link(path, lang string) string {
+	if lang == "" || lang == i18n.Default {
+		return s.appBaseURL + path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return s.appBaseURL + path + separator + "lang=" + url.QueryEscape(lang)
+}
+
+// manageLink leads to the page where sub can be changed, paused or deleted.
+func (s *SubscriptionService) manageLink(sub core.Subscription) string {
+	return s.link("/manage.html?token="+url.QueryEscape(sub.ManageToken), sub.Language)
+}
+
+// ResolveLocation turns user input such as "kiev" into the provider's best
+// matching canonical location, including its timezone.
+func (s *SubscriptionService) ResolveLocation(ctx context.Context, city string) (*core.Location, error) {
+	locations, err := s.weatherProvider.SearchLocations(ctx, city)
+	if err != nil {
+		log.Printf("Error searching location %q: %v", city, err)
+		return nil, ErrLocationUnavailable
+	}
+	if len(locations) == 0 {
+		return nil, ErrCityNotFound
+	}
+
+	location := locations[0]
+	if location.Timezone == "" {
+		// not every search API returns timezones; the current weather lookup does
+		weather, err := s.weatherProvider.FetchWeather(ctx, core.CoordinatesQuery(location.Lat, location.Lon))
+		if err != nil {
+			log.Printf("Could not look up timezone for %s (%s): %v", location.Name, location.ID, err)
+		} else {
+			location.Timezone = weather.Location.Timezone
+		}
+	}
+	return &location, nil
+}
+
+// ConfirmSubscription confirms the subscriber a confirmation token was sent to
+// and schedules all of its subscriptions. A token no subscriber has may still
+// confirm a single pending subscription.
+func (s *SubscriptionService) ConfirmSubscription(ctx context.Context, token string) error {
 	if _, err := uuid.Parse(token); err != nil {
 		return ErrInvalidToken
 	}
 
-	sub, err := s.repo.FindByConfirmationToken(token)
+	subscriber, err := s.subscriberRepo.FindByConfirmationToken(ctx, token)
 	if err != nil {
-		log.Printf("Error finding subscription by confirmation token %s: %v", token, err)
+		log.Printf("Error finding subscriber by confirmation token %s: %v", token, err)
 		return fmt.Errorf("database error during confirmation")
 	}
 
-	if sub == nil {
-		return ErrSubscriptionNotFound
+	if subscriber == nil {
+		return s.confirmPending(ctx, token)
 	}
 
-	if sub.IsConfirmed {
+	if subscriber.IsConfirmed {
 		return ErrAlreadyConfirmed
 	}
+	if subscriber.ConfirmationExpired(time.Now().UTC()) {
+		return ErrConfirmationExpired
+	}
 
-	if err := s.repo.Confirm(sub.ID); err != nil {
-		if errors.Is(err, errors.New("subscription not found or already confirmed")) {
-			return ErrAlreadyConfirmed
-		}
-		log.Printf("Error confirming subscription ID %s: %v", sub.ID, err)
+	if err := s.subscriberRepo.Confirm(ctx, subscriber.ID); err != nil {
+		log.Printf("Error confirming subscriber ID %s: %v", subscriber.ID, err)
 		return fmt.Errorf("could not confirm subscription")
 	}
 
-	log.Printf("Subscription ID %s confirmed for email %s", sub.ID, sub.Email)
+	subs, err := s.repo.ListBySubscriber(ctx, subscriber.ID)
+	if err != nil {
+		// SendWeatherUpdates schedules whatever is left unscheduled here
+		log.Printf("Error listing subscriptions of subscriber ID %s: %v", subscriber.ID, err)
+	}
+	now := time.Now().UTC()
+	for _, sub := range subs {
+		if err := s.repo.SetNextDueAt(ctx, sub.ID, sub.NextDeliveryAfter(now)); err != nil {
+			log.Printf("Error scheduling subscription ID %s: %v", sub.ID, err)
+		}
+	}
+
+	log.Printf("Subscriber ID %s confirmed for email %s with %d subscriptions", subscriber.ID, subscriber.Email, len(subs))
 	return nil
 }
 
-func (s *SubscriptionService) Unsubscribe(token string) error {
+func (s *SubscriptionService) Unsubscribe(ctx context.Context, token string) error {
 	if _, err := uuid.Parse(token); err != nil {
 		return ErrInvalidToken
 	}
 
-	sub, err := s.repo.FindByUnsubscribeToken(token)
+	sub, err := s.repo.FindByUnsubscribeToken(ctx, token)
 	if err != nil {
 		log.Printf("Error finding subscription by unsubscribe token %s: %v", token, err)
 		return fmt.Errorf("database error during unsubscribe lookup")
@@ -133,75 +347,365 @@ func (s *SubscriptionService) Unsubscribe(token string) error {
 		return ErrSubscriptionNotFound
 	}
 
-	if err := s.repo.Delete(sub.ID); err != nil {
-		if err.Error() == "subscription not found for deletion" {
+	return s.deleteSubscription(ctx, sub)
+}
+
+func (s *SubscriptionService) deleteSubscription(ctx context.Context, sub *core.Subscription) error {
+	if err := s.repo.Delete(ctx, sub.ID); err != nil {
+		if errors.Is(err, database.ErrSubscriptionNotFound) {
 			return ErrSubscriptionNotFound
 		}
 		log.Printf("Error deleting subscription ID %s: %v", sub.ID, err)
 		return fmt.Errorf("could not process unsubscription")
 	}
+	s.deleteSubscriberIfUnused(ctx, sub.SubscriberID)
 
 	log.Printf("Subscription ID %s (email: %s, city: %s) unsubscribed successfully.", sub.ID, sub.Email, sub.City)
 	return nil
 }
-func (s *SubscriptionService) SendWeatherUpdates() {
+
+// deleteSubscriberIfUnused forgets a subscriber whose last subscription is gone.
+func (s *SubscriptionService) deleteSubscriberIfUnused(ctx context.Context, subscriberID string) {
+	if err := s.subscriberRepo.DeleteIfUnused(ctx, subscriberID); err != nil {
+		log.Printf("Error deleting subscriber ID %s: %v", subscriberID, err)
+	}
+}
+
+// scheduleUnscheduled gives confirmed subscriptions that have no next update
+// time one, e.g. when scheduling them on confirmation failed.
+func (s *SubscriptionService) scheduleUnscheduled(ctx context.Context, now time.Time) {
+	subs, err := s.repo.GetUnscheduled(ctx)
+	if err != nil {
+		log.Printf("Scheduler: Error fetching unscheduled subscriptions: %v", err)
+		return
+	}
+	for _, sub := range subs {
+		nextDueAt := sub.NextDeliveryAfter(now)
+		if nextDueAt == nil {
+			continue
+		}
+		if err := s.repo.SetNextDueAt(ctx, sub.ID, nextDueAt); err != nil {
+			log.Printf("Scheduler: Failed to schedule subscription ID %s: %v", sub.ID, err)
+			continue
+		}
+		log.Printf("Scheduler: Scheduled subscription ID %s for %s.", sub.ID, nextDueAt.Format(time.RFC3339))
+	}
+}
+
+func (s *SubscriptionService) SendWeatherUpdates(ctx context.Context) {
 	log.Println("Scheduler: Running SendWeatherUpdates job.")
+	ctx = weatherprovider.WithPriority(ctx, weatherprovider.PriorityScheduled)
 	now := time.Now().UTC()
+	s.scheduleUnscheduled(ctx, now)
 
-	confirmedSubs, err := s.repo.GetAllConfirmed()
+	dueSubs, err := s.repo.GetDue(ctx, now)
 	if err != nil {
-		log.Printf("Scheduler: Error fetching confirmed subscriptions: %v", err)
+		log.Printf("Scheduler: Error fetching due subscriptions: %v", err)
 		return
 	}
 
-	if len(confirmedSubs) == 0 {
-		log.Println("Scheduler: No confirmed subscriptions to process.")
+	if len(dueSubs) == 0 {
+		log.Println("Scheduler: No subscriptions due for an update.")
 		return
 	}
 
-	log.Printf("Scheduler: Processing %d confirmed subscriptions at %s.", len(confirmedSubs), now.Format(time.RFC3339))
+	log.Printf("Scheduler: Processing %d due subscriptions at %s.", len(dueSubs), now.Format(time.RFC3339))
 
-	for _, sub := range confirmedSubs {
-		isDue := false
-		switch sub.Frequency {
-		case "hourly":
-			if now.Minute() == 0 { // Start of the hour
-				isDue = true
-			}
+	for _, sub := range dueSubs {
+		if ctx.Err() != nil {
+			log.Printf("Scheduler: SendWeatherUpdates cancelled: %v", ctx.Err())
+			return
+		}
 
-		case "daily":
-			targetHourDaily := 8 // 8 AM UTC
-			if now.Hour() == targetHourDaily && now.Minute() == 0 {
-				isDue = true
-			}
-		default:
-			log.Printf("Scheduler: Unknown frequency '%s' for subscription ID %s. Skipping.", sub.Frequency, sub.ID)
+		if sub.NextDueAt == nil {
+			log.Printf("Scheduler: Subscription ID %s has no delivery scheduled. Skipping.", sub.ID)
 			continue
 		}
-
-		if !isDue {
+		// a subscription that missed several deliveries while the service was down
+		// gets one update and moves on to its next regular delivery time
+		nextDueAt := sub.NextDeliveryAfter(now)
+		if nextDueAt == nil {
+			log.Printf("Scheduler: Unknown frequency '%s' for subscription ID %s. Skipping.", sub.Frequency, sub.ID)
 			continue
 		}
 
-		log.Printf("Scheduler: Update DUE for %s (%s) in %s.", sub.Email, sub.Frequency, sub.City)
+		log.Printf("Scheduler: Update DUE for %s (%s) in %s, scheduled for %s.", sub.Email, sub.Frequency, sub.City, sub.NextDueAt.Format(time.RFC3339))
+		s.deliverWeatherUpdate(ctx, sub, *nextDueAt, now)
+	}
+	log.Println("Scheduler: Finished SendWeatherUpdates job run.")
+}
+
+// deliverWeatherUpdate sends the update for a due subscription and moves it on
+// to nextDueAt. When the weather can't be fetched or the email can't be sent
+// the subscription stays due, so the next run retries it.
+func (s *SubscriptionService) deliverWeatherUpdate(ctx context.Context, sub core.Subscription, nextDueAt, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	weatherInfo, err := s.buildWeatherInfo(ctx, sub)
+	if err != nil {
+		log.Printf("Scheduler: Failed to fetch weather for %s (subscriber %s): %v", sub.City, sub.Email, err)
+		return
+	}
+
+	// claiming before sending means overlapping runs never send the same update twice
+	claimed, err := s.repo.ClaimDue(ctx, sub.ID, *sub.NextDueAt, nextDueAt, now)
+	if err != nil {
+		log.Printf("Scheduler: Failed to reschedule subscription ID %s: %v", sub.ID, err)
+		return
+	}
+	if !claimed {
+		log.Printf("Scheduler: Update for subscription ID %s was already sent by another run. Skipping.", sub.ID)
+		return
+	}
+	unsubscribeLink := s.link("/api/unsubscribe/"+sub.UnsubscribeToken, sub.Language)
+
+	if err := s.emailer.SendWeatherUpdateEmail(ctx, sub.Email, sub.Language, sub.City, weatherInfo, s.manageLink(sub), unsubscribeLink); err != nil {
+		log.Printf("Scheduler: Failed to send weather update to %s for city %s: %v", sub.Email, sub.City, err)
+		s.releaseClaim(ctx, sub, nextDueAt, now)
+		return
+	}
+	log.Printf("Scheduler: Successfully sent weather update to %s for city %s.", sub.Email, sub.City)
+}
+
+// releaseClaim puts a delivery claimed at now back to its due time after its
+// email failed.
+func (s *SubscriptionService) releaseClaim(ctx context.Context, sub core.Subscription, nextDueAt, now time.Time) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
 
-		weatherData, err := s.weatherProvider.FetchWeather(sub.City)
+	released, err := s.repo.ReleaseClaim(ctx, sub.ID, *sub.NextDueAt, nextDueAt, now, sub.LastSentAt)
+	if err != nil {
+		log.Printf("Scheduler: Failed to put back the missed update of subscription ID %s: %v", sub.ID, err)
+		return
+	}
+	if !released {
+		log.Printf("Scheduler: Subscription ID %s changed since its update was claimed; not retrying it.", sub.ID)
+		return
+	}
+	log.Printf("Scheduler: Update for subscription ID %s will be retried on the next run.", sub.ID)
+}
+
+func (s *SubscriptionService) buildWeatherInfo(ctx context.Context, sub core.Subscription) (string, error) {
+	units, err := core.ParseUnits(sub.Units)
+	if err != nil {
+		log.Printf("Subscription ID %s has invalid units %q, using metric: %v", sub.ID, sub.Units, err)
+		units = core.MetricUnits
+	}
+
+	if frequency, _ := core.ParseFrequency(sub.Frequency); frequency.SendsForecast() {
+		forecast, err := s.weatherProvider.FetchForecast(ctx, sub.WeatherQuery(), 1)
 		if err != nil {
-			log.Printf("Scheduler: Failed to fetch weather for %s (subscriber %s): %v", sub.City, sub.Email, err)
-			continue
+			return "", err
+		}
+		if len(forecast.Days) == 0 {
+			return "", fmt.Errorf("empty forecast for %s", sub.City)
+		}
+		today := forecast.Days[0]
+		info := i18n.T(sub.Language, i18n.MsgDailyForecast,
+			sub.City, units.FormatTemperature(today.MaxTemperature), units.FormatTemperature(today.MinTemperature),
+			today.ChanceOfRain, units.FormatPrecipitation(today.TotalPrecip), conditionText(sub.Language, today.Condition, today.Description),
+		)
+		if sub.IncludeAstronomy {
+			// the sun and moon are an extra; the forecast goes out without them
+			astronomy, err := s.weatherProvider.FetchAstronomy(ctx, sub.WeatherQuery(), today.Date)
+			if err != nil {
+				log.Printf("Scheduler: Failed to fetch astronomy for %s (subscriber %s): %v", sub.City, sub.Email, err)
+			} else {
+				info += "\n\n" + formatAstronomy(sub.Language, astronomy)
+			}
+		}
+		return info, nil
+	}
+
+	weatherData, err := s.weatherProvider.FetchWeather(ctx, sub.WeatherQuery())
+	if err != nil {
+		return "", err
+	}
+
+	return formatCurrentWeather(sub, units, weatherData), nil
+}
+
+// formatCurrentWeather describes current conditions for a subscription's emails.
+func formatCurrentWeather(sub core.Subscription, units core.UnitSystem, weatherData *core.Weather) string {
+	return i18n.T(sub.Language, i18n.MsgCurrentWeather,
+		sub.City, units.FormatTemperature(weatherData.Temperature), units.FormatTemperature(weatherData.FeelsLike),
+		weatherData.Humidity, conditionText(sub.Language, weatherData.Condition, weatherData.Description),
+		units.FormatSpeed(weatherData.WindSpeed), weatherData.WindDirection, units.FormatSpeed(weatherData.WindGust),
+		units.FormatPressure(weatherData.Pressure), units.FormatPrecipitation(weatherData.Precipitation),
+		weatherData.CloudCover, weatherData.UVIndex, units.FormatDistance(weatherData.Visibility),
+		weatherData.ObservedAt.Format(time.RFC1123),
+	)
+}
+
+// conditionText names the condition in the subscriber's language, so emails
+// read the same whichever backend answered. The provider's own description is
+// kept for conditions it couldn't be mapped from.
+func conditionText(lang string, condition core.Condition, description string) string {
+	if condition == "" || condition == core.ConditionUnknown {
+		return description
+	}
+	return i18n.T(lang, i18n.Condition(string(condition)))
+}
+
+// formatAstronomy is the sun and moon section of a daily update. Times are
+// shown as the provider gave them, in the location's own timezone.
+func formatAstronomy(lang string, a *core.Astronomy) string {
+	sunrise, sunset := "—", "—"
+	if a.Sunrise != nil && a.Sunset != nil {
+		sunrise, sunset = a.Sunrise.Format("15:04"), a.Sunset.Format("15:04")
+	}
+	return i18n.T(lang, i18n.MsgAstronomy,
+		sunrise, sunset, a.DayLengthMinutes/60, a.DayLengthMinutes%60,
+		i18n.T(lang, i18n.MoonPhase(string(a.MoonPhase))), a.MoonIllumination,
+	)
+}
+
+// CheckAirQualityAlerts emails subscribers whose city's US EPA index has risen
+// to their threshold. An alert is sent once per crossing: it re-arms only after
+// the index drops back below the threshold.
+func (s *SubscriptionService) CheckAirQualityAlerts(ctx context.Context) {
+	ctx = weatherprovider.WithPriority(ctx, weatherprovider.PriorityScheduled)
+	subs, err := s.repo.GetConfirmedWithAQIThreshold(ctx)
+	if err != nil {
+		log.Printf("Scheduler: Error fetching subscriptions with air quality alerts: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			log.Printf("Scheduler: CheckAirQualityAlerts cancelled: %v", ctx.Err())
+			return
 		}
+		s.checkAirQuality(ctx, sub)
+	}
+}
+
+func (s *SubscriptionService) checkAirQuality(ctx context.Context, sub core.Subscription) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
 
-		weatherInfo := fmt.Sprintf(
-			"Current weather in %s:\nTemperature: %.1f°C\nHumidity: %.0f%%\nDescription: %s",
-			sub.City, weatherData.Temperature, weatherData.Humidity, weatherData.Description,
+	airQuality, err := s.weatherProvider.FetchAirQuality(ctx, sub.WeatherQuery())
+	if err != nil {
+		log.Printf("Scheduler: Failed to fetch air quality for %s (subscriber %s): %v", sub.City, sub.Email, err)
+		return
+	}
+
+	above := airQuality.USEPAIndex >= *sub.AQIThreshold
+	if above == sub.AQIAlertActive {
+		return
+	}
+
+	if above {
+		category := i18n.T(sub.Language, i18n.USEPACategory(airQuality.USEPAIndex))
+		alertInfo := i18n.T(sub.Language, i18n.MsgAirQualityAlert,
+			sub.City, airQuality.USEPAIndex, category, *sub.AQIThreshold,
+			airQuality.PM25, airQuality.PM10, airQuality.O3, airQuality.NO2, airQuality.DEFRAIndex,
 		)
-		unsubscribeLink := fmt.Sprintf("%s/api/unsubscribe/%s", s.appBaseURL, sub.UnsubscribeToken)
+		unsubscribeLink := s.link("/api/unsubscribe/"+sub.UnsubscribeToken, sub.Language)
+		if err := s.emailer.SendAirQualityAlertEmail(ctx, sub.Email, sub.Language, sub.City, alertInfo, s.manageLink(sub), unsubscribeLink); err != nil {
+			log.Printf("Scheduler: Failed to send air quality alert to %s for city %s: %v", sub.Email, sub.City, err)
+			return
+		}
+		log.Printf("Scheduler: Sent air quality alert to %s for city %s (index %d).", sub.Email, sub.City, airQuality.USEPAIndex)
+	}
 
-		if err := s.emailer.SendWeatherUpdateEmail(sub.Email, sub.City, weatherInfo, unsubscribeLink); err != nil {
-			log.Printf("Scheduler: Failed to send weather update to %s for city %s: %v", sub.Email, sub.City, err)
-		} else {
-			log.Printf("Scheduler: Successfully sent weather update to %s for city %s.", sub.Email, sub.City)
+	if err := s.repo.SetAQIAlertActive(ctx, sub.ID, above); err != nil {
+		log.Printf("Scheduler: Failed to update air quality alert state for subscription ID %s: %v", sub.ID, err)
+	}
+}
+
+// CheckWeatherAlerts notifies "alerts" subscribers about severe weather alerts
+// for their location. Each alert is sent once, and again only when the issuer
+// updates it.
+func (s *SubscriptionService) CheckWeatherAlerts(ctx context.Context) {
+	ctx = weatherprovider.WithPriority(ctx, weatherprovider.PriorityScheduled)
+	subs, err := s.repo.GetConfirmedByFrequency(ctx, string(core.FrequencyAlerts))
+	if err != nil {
+		log.Printf("Scheduler: Error fetching alert subscriptions: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			log.Printf("Scheduler: CheckWeatherAlerts cancelled: %v", ctx.Err())
+			return
 		}
+		s.checkWeatherAlerts(ctx, sub)
 	}
-	log.Println("Scheduler: Finished SendWeatherUpdates job run.")
+
+	if deleted, err := s.alertRepo.DeleteExpired(ctx, time.Now().UTC()); err != nil {
+		log.Printf("Scheduler: Failed to delete expired weather alerts: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Scheduler: Deleted %d expired weather alert notifications.", deleted)
+	}
+}
+
+func (s *SubscriptionService) checkWeatherAlerts(ctx context.Context, sub core.Subscription) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	alerts, err := s.weatherProvider.FetchAlerts(ctx, sub.WeatherQuery())
+	if err != nil {
+		log.Printf("Scheduler: Failed to fetch weather alerts for %s (subscriber %s): %v", sub.City, sub.Email, err)
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	notified, err := s.alertRepo.GetNotifiedFingerprints(ctx, sub.ID)
+	if err != nil {
+		log.Printf("Scheduler: Failed to load sent weather alerts for subscription ID %s: %v", sub.ID, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	unsubscribeLink := s.link("/api/unsubscribe/"+sub.UnsubscribeToken, sub.Language)
+	for _, alert := range alerts {
+		if !alert.Expires.IsZero() && alert.Expires.Before(now) {
+			continue
+		}
+		sentFingerprint, seen := notified[alert.ID]
+		if seen && sentFingerprint == alert.Fingerprint() {
+			continue
+		}
+
+		headline := alert.Headline
+		if headline == "" {
+			headline = alert.Event
+		}
+		if seen {
+			headline = i18n.T(sub.Language, i18n.MsgWeatherAlertUpdated, headline)
+		}
+		if err := s.emailer.SendWeatherAlertEmail(ctx, sub.Email, sub.Language, sub.City, headline, formatWeatherAlert(alert, sub.Language), s.manageLink(sub), unsubscribeLink); err != nil {
+			log.Printf("Scheduler: Failed to send weather alert to %s for city %s: %v", sub.Email, sub.City, err)
+			continue
+		}
+		if err := s.alertRepo.RecordNotification(ctx, sub.ID, alert); err != nil {
+			log.Printf("Scheduler: Failed to record weather alert %s for subscription ID %s: %v", alert.ID, sub.ID, err)
+			continue
+		}
+		log.Printf("Scheduler: Sent weather alert %q to %s for city %s.", alert.Event, sub.Email, sub.City)
+	}
+}
+
+// formatWeatherAlert renders an alert's details with labels in lang; the alert
+// text itself is as issued.
+func formatWeatherAlert(alert core.WeatherAlert, lang string) string {
+	info := i18n.T(lang, i18n.MsgWeatherAlertDetails,
+		alert.Event, alert.Severity, alert.Urgency, alert.Certainty, alert.Areas)
+	if !alert.Effective.IsZero() {
+		info += "\n" + i18n.T(lang, i18n.MsgWeatherAlertFrom, alert.Effective.Format(time.RFC1123))
+	}
+	if !alert.Expires.IsZero() {
+		info += "\n" + i18n.T(lang, i18n.MsgWeatherAlertUntil, alert.Expires.Format(time.RFC1123))
+	}
+	if alert.Description != "" {
+		info += "\n\n" + alert.Description
+	}
+	if alert.Instruction != "" {
+		info += "\n\n" + alert.Instruction
+	}
+	return info
 }
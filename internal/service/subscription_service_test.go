@@ -0,0 +1,540 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/core"
+	"weather-app/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testService wires a SubscriptionService to mocks of everything it uses.
+type testService struct {
+	*SubscriptionService
+	subs        *mocks.SubscriptionRepository
+	subscribers *mocks.SubscriberRepository
+	alerts      *mocks.AlertRepository
+	rules       *mocks.RuleRepository
+	emailer     *mocks.EmailService
+	provider    *mocks.WeatherProvider
+}
+
+func newTestService() *testService {
+	ts := &testService{
+		subs:        new(mocks.SubscriptionRepository),
+		subscribers: new(mocks.SubscriberRepository),
+		alerts:      new(mocks.AlertRepository),
+		rules:       new(mocks.RuleRepository),
+		emailer:     new(mocks.EmailService),
+		provider:    new(mocks.WeatherProvider),
+	}
+	ts.SubscriptionService = NewSubscriptionService(ts.subs, ts.subscribers, ts.alerts, ts.rules,
+		ts.emailer, ts.provider, "http://localhost:8080", DefaultConfirmationPolicy())
+	return ts
+}
+
+func (ts *testService) assertExpectations(t *testing.T) {
+	ts.subs.AssertExpectations(t)
+	ts.subscribers.AssertExpectations(t)
+	ts.alerts.AssertExpectations(t)
+	ts.rules.AssertExpectations(t)
+	ts.emailer.AssertExpectations(t)
+	ts.provider.AssertExpectations(t)
+}
+
+var tokyo = core.Location{ID: "test:tokyo", Name: "Tokyo", Country: "Japan", Lat: 35.69, Lon: 139.69, Timezone: "Asia/Tokyo"}
+
+func strPtr(s string) *string { return &s }
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestCreateSubscription_SecondCity(t *testing.T) {
+	req := core.SubscriptionRequest{Email: "test@example.com", City: "tokyo", Frequency: "daily", Units: "metric", Language: "en"}
+
+	tests := []struct {
+		name          string
+		subscriber    *core.Subscriber
+		expectEmail   bool
+		expectStarted bool
+	}{
+		{
+			name: "joins a pending confirmation",
+			subscriber: &core.Subscriber{ID: "subscriber-1", Email: req.Email, Units: "metric", Language: "en",
+				ConfirmationToken: strPtr("e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"), ConfirmationExpiresAt: timePtr(time.Now().Add(time.Hour))},
+		},
+		{
+			name: "replaces an expired confirmation link",
+			subscriber: &core.Subscriber{ID: "subscriber-1", Email: req.Email, Units: "metric", Language: "en",
+				ConfirmationToken: strPtr("e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"), ConfirmationExpiresAt: timePtr(time.Now().Add(-time.Hour))},
+			expectEmail: true,
+		},
+		{
+			name:          "starts right away for a confirmed email",
+			subscriber:    &core.Subscriber{ID: "subscriber-1", Email: req.Email, Units: "metric", Language: "en", IsConfirmed: true},
+			expectStarted: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+			ts.subscribers.On("FindByEmail", mock.Anything, req.Email).Return(tc.subscriber, nil).Once()
+			ts.subs.On("FindBySubscriberAndLocation", mock.Anything, tc.subscriber.ID, tokyo.ID).Return(nil, nil).Once()
+			ts.subs.On("FindBySubscriberAndCity", mock.Anything, tc.subscriber.ID, tokyo.Name).Return(nil, nil).Once()
+			ts.subs.On("Create", mock.Anything, mock.AnythingOfType("*core.Subscription")).Return(nil).Once()
+			if tc.expectEmail {
+				ts.subscribers.On("ReissueConfirmation", mock.Anything, tc.subscriber.ID, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(true, nil).Once()
+				ts.emailer.On("SendConfirmationEmail", mock.Anything, req.Email, "en", "Tokyo", mock.Anything, mock.Anything).Return(nil).Once()
+			}
+
+			sub, err := ts.CreateSubscription(context.Background(), req)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.subscriber.ID, sub.SubscriberID, "joins the existing subscriber")
+			assert.Equal(t, "Asia/Tokyo", sub.DeliveryTimezone, "delivery time is in the new city's timezone")
+			assert.Equal(t, tc.expectStarted, sub.IsConfirmed)
+			assert.Equal(t, tc.expectStarted, sub.NextDueAt != nil)
+			ts.assertExpectations(t)
+			if !tc.expectEmail {
+				ts.subscribers.AssertNotCalled(t, "ReissueConfirmation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				ts.emailer.AssertNotCalled(t, "SendConfirmationEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreateSubscription_NewSubscriber(t *testing.T) {
+	ts := newTestService()
+	req := core.SubscriptionRequest{Email: "test@example.com", City: "tokyo", Frequency: "daily", Units: "imperial", Language: "uk", Timezone: "Europe/London"}
+
+	ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+	ts.subscribers.On("FindByEmail", mock.Anything, req.Email).Return(nil, nil).Once()
+	ts.subscribers.On("Create", mock.Anything, mock.MatchedBy(func(b *core.Subscriber) bool {
+		return b.Email == req.Email && b.Units == "imperial" && b.Language == "uk" && !b.IsConfirmed
+	})).Return(nil).Once()
+	ts.subs.On("Create", mock.Anything, mock.AnythingOfType("*core.Subscription")).Return(nil).Once()
+	ts.subscribers.On("ReissueConfirmation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(true, nil).Once()
+	ts.emailer.On("SendConfirmationEmail", mock.Anything, req.Email, "uk", "Tokyo", mock.Anything, mock.Anything).Return(nil).Once()
+
+	sub, err := ts.CreateSubscription(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/London", sub.DeliveryTimezone, "an explicit timezone wins over the city's")
+	assert.False(t, sub.IsConfirmed)
+	assert.Nil(t, sub.NextDueAt)
+	ts.assertExpectations(t)
+}
+
+func TestCreateSubscription_SettingsConflict(t *testing.T) {
+	subscriber := &core.Subscriber{ID: "subscriber-1", Email: "test@example.com", Units: "imperial", Language: "en", IsConfirmed: true}
+
+	tests := []struct {
+		name  string
+		units string
+		lang  string
+	}{
+		{name: "other units", units: "metric", lang: "en"},
+		{name: "other language", units: "imperial", lang: "uk"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+			ts.subscribers.On("FindByEmail", mock.Anything, subscriber.Email).Return(subscriber, nil).Once()
+
+			_, err := ts.CreateSubscription(context.Background(), core.SubscriptionRequest{
+				Email: subscriber.Email, City: "tokyo", Frequency: "daily", Units: tc.units, Language: tc.lang,
+			})
+
+			require.ErrorIs(t, err, ErrSubscriberSettingsConflict)
+			var conflictErr *SubscriberSettingsConflictError
+			require.ErrorAs(t, err, &conflictErr)
+			assert.Equal(t, "imperial", conflictErr.Units)
+			assert.Equal(t, "en", conflictErr.Language)
+			ts.assertExpectations(t)
+			ts.subs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestConfirmSubscription_SchedulesEverySubscription(t *testing.T) {
+	ts := newTestService()
+	token := "e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"
+	subscriber := &core.Subscriber{ID: "subscriber-1", Email: "test@example.com", ConfirmationToken: &token,
+		ConfirmationExpiresAt: timePtr(time.Now().Add(time.Hour))}
+	subs := []core.Subscription{
+		{ID: "sub-london", SubscriberID: subscriber.ID, City: "London", Frequency: "daily", DeliveryTime: "08:00", DeliveryTimezone: "Europe/London"},
+		{ID: "sub-tokyo", SubscriberID: subscriber.ID, City: "Tokyo", Frequency: "hourly", DeliveryTimezone: "Asia/Tokyo"},
+	}
+
+	ts.subscribers.On("FindByConfirmationToken", mock.Anything, token).Return(subscriber, nil).Once()
+	ts.subscribers.On("Confirm", mock.Anything, subscriber.ID).Return(nil).Once()
+	ts.subs.On("ListBySubscriber", mock.Anything, subscriber.ID).Return(subs, nil).Once()
+	for _, sub := range subs {
+		ts.subs.On("SetNextDueAt", mock.Anything, sub.ID, mock.MatchedBy(func(next *time.Time) bool {
+			return next != nil && next.After(time.Now())
+		})).Return(nil).Once()
+	}
+
+	require.NoError(t, ts.ConfirmSubscription(context.Background(), token))
+	ts.assertExpectations(t)
+}
+
+func TestConfirmSubscription_PendingSubscription(t *testing.T) {
+	ts := newTestService()
+	token := "e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"
+	sub := &core.Subscription{ID: "sub-tokyo", SubscriberID: "subscriber-1", Email: "test@example.com", City: "Tokyo",
+		Frequency: "hourly", IsConfirmed: true}
+
+	ts.subscribers.On("FindByConfirmationToken", mock.Anything, token).Return(nil, nil).Once()
+	ts.subs.On("ConfirmPending", mock.Anything, token).Return(sub, nil).Once()
+	ts.subs.On("SetNextDueAt", mock.Anything, sub.ID, mock.MatchedBy(func(next *time.Time) bool {
+		return next != nil && next.After(time.Now())
+	})).Return(nil).Once()
+
+	require.NoError(t, ts.ConfirmSubscription(context.Background(), token))
+	ts.assertExpectations(t)
+	ts.subscribers.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything)
+}
+
+func TestConfirmSubscription_UnknownToken(t *testing.T) {
+	ts := newTestService()
+	token := "e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"
+	ts.subscribers.On("FindByConfirmationToken", mock.Anything, token).Return(nil, nil).Once()
+	ts.subs.On("ConfirmPending", mock.Anything, token).Return(nil, nil).Once()
+
+	require.ErrorIs(t, ts.ConfirmSubscription(context.Background(), token), ErrSubscriptionNotFound)
+	ts.assertExpectations(t)
+}
+
+func TestCreateSubscription_PendingCityOfConfirmedEmail(t *testing.T) {
+	ts := newTestService()
+	req := core.SubscriptionRequest{Email: "test@example.com", City: "tokyo", Frequency: "daily"}
+	subscriber := &core.Subscriber{ID: "subscriber-1", Email: req.Email, Units: "metric", Language: "en", IsConfirmed: true}
+	pending := &core.Subscription{ID: "sub-tokyo", SubscriberID: subscriber.ID, Email: req.Email, City: "Tokyo", Language: "en",
+		PendingToken: strPtr("e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"), ManageToken: "0b5e1c42-7d3a-4f0e-8c6a-2a9f4d1e7b30"}
+
+	ts.provider.On("SearchLocations", mock.Anything, "tokyo").Return([]core.Location{tokyo}, nil).Once()
+	ts.subscribers.On("FindByEmail", mock.Anything, req.Email).Return(subscriber, nil).Once()
+	ts.subs.On("FindBySubscriberAndLocation", mock.Anything, subscriber.ID, tokyo.ID).Return(pending, nil).Once()
+	// the city's own link goes out again; the confirmed subscriber gets no new token
+	ts.emailer.On("SendConfirmationEmail", mock.Anything, req.Email, "en", "Tokyo",
+		"http://localhost:8080/api/confirm/e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11", mock.Anything).Return(nil).Once()
+
+	sub, err := ts.CreateSubscription(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, pending.ID, sub.ID)
+	ts.assertExpectations(t)
+	ts.subscribers.AssertNotCalled(t, "ReissueConfirmation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	ts.subs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestConfirmSubscription_Expired(t *testing.T) {
+	ts := newTestService()
+	token := "e3c1f3f4-3c7e-4d8e-9a0e-5b9b7b0f8a11"
+	subscriber := &core.Subscriber{ID: "subscriber-1", Email: "test@example.com", ConfirmationToken: &token,
+		ConfirmationExpiresAt: timePtr(time.Now().Add(-time.Minute))}
+	ts.subscribers.On("FindByConfirmationToken", mock.Anything, token).Return(subscriber, nil).Once()
+
+	err := ts.ConfirmSubscription(context.Background(), token)
+
+	require.ErrorIs(t, err, ErrConfirmationExpired)
+	ts.assertExpectations(t)
+	ts.subscribers.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything)
+}
+
+func TestResendConfirmation(t *testing.T) {
+	email := "test@example.com"
+	sub := core.Subscription{ID: "sub-1", SubscriberID: "subscriber-1", Email: email, City: "Tokyo", ManageToken: "0b5e1c42-7d3a-4f0e-8c6a-2a9f4d1e7b30"}
+	sentAt := time.Now().UTC().Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		subscriber  *core.Subscriber
+		subs        []core.Subscription
+		claimed     bool
+		expectEmail bool
+	}{
+		{name: "unknown email"},
+		{name: "confirmed email", subscriber: &core.Subscriber{ID: "subscriber-1", Email: email, IsConfirmed: true}},
+		{name: "no subscriptions", subscriber: &core.Subscriber{ID: "subscriber-1", Email: email}, subs: []core.Subscription{}},
+		{
+			name:       "sent too recently",
+			subscriber: &core.Subscriber{ID: "subscriber-1", Email: email, Language: "en", ConfirmationSentAt: &sentAt},
+			subs:       []core.Subscription{sub},
+		},
+		{
+			name:        "sent",
+			subscriber:  &core.Subscriber{ID: "subscriber-1", Email: email, Language: "en", ConfirmationSentAt: timePtr(time.Now().Add(-time.Hour))},
+			subs:        []core.Subscription{sub},
+			claimed:     true,
+			expectEmail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			ts.subscribers.On("FindByEmail", mock.Anything, email).Return(tc.subscriber, nil).Once()
+			if tc.subs != nil {
+				ts.subs.On("ListBySubscriber", mock.Anything, tc.subscriber.ID).Return(tc.subs, nil).Once()
+			}
+			if len(tc.subs) > 0 {
+				ts.subscribers.On("ReissueConfirmation", mock.Anything, tc.subscriber.ID, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(tc.claimed, nil).Once()
+			}
+			if tc.expectEmail {
+				ts.emailer.On("SendConfirmationEmail", mock.Anything, email, "en", "Tokyo", mock.Anything, mock.Anything).Return(nil).Once()
+			}
+
+			// callers can't tell any of these cases apart
+			require.NoError(t, ts.ResendConfirmation(context.Background(), email))
+			ts.assertExpectations(t)
+			if !tc.expectEmail {
+				ts.emailer.AssertNotCalled(t, "SendConfirmationEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestResendConfirmation_DatabaseError(t *testing.T) {
+	ts := newTestService()
+	ts.subscribers.On("FindByEmail", mock.Anything, "test@example.com").Return(nil, errors.New("db down")).Once()
+
+	require.Error(t, ts.ResendConfirmation(context.Background(), "test@example.com"))
+	ts.assertExpectations(t)
+}
+
+func TestPurgeUnconfirmed(t *testing.T) {
+	ts := newTestService()
+	retention := ts.confirmation.UnconfirmedRetention
+	before := time.Now().UTC()
+	ts.subscribers.On("DeleteUnconfirmed", mock.Anything, mock.MatchedBy(func(cutoff time.Time) bool {
+		// only subscribers last emailed more than the retention period ago
+		return !cutoff.Before(before.Add(-retention)) && !cutoff.After(time.Now().UTC().Add(-retention))
+	})).Return(int64(2), nil).Once()
+
+	ts.PurgeUnconfirmed(context.Background())
+
+	ts.assertExpectations(t)
+}
+
+func TestPurgeUnconfirmed_RetentionDisabled(t *testing.T) {
+	ts := newTestService()
+	ts.confirmation.UnconfirmedRetention = 0
+
+	ts.PurgeUnconfirmed(context.Background())
+
+	ts.subscribers.AssertNotCalled(t, "DeleteUnconfirmed", mock.Anything, mock.Anything)
+}
+
+func TestSendWeatherUpdates(t *testing.T) {
+	// the service was down for five hourly deliveries
+	dueAt := time.Now().UTC().Truncate(time.Hour).Add(-5 * time.Hour)
+	lastSentAt := dueAt.Add(-time.Hour)
+	sub := core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv", Frequency: "hourly", Units: "metric", Language: "en",
+		IsConfirmed: true, NextDueAt: &dueAt, LastSentAt: &lastSentAt, UnsubscribeToken: "unsub-1", ManageToken: testManageToken}
+	// only the next regular slot, not the missed ones
+	nextSlot := mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now()) && !next.After(time.Now().Add(time.Hour)) && next.Equal(next.Truncate(time.Hour))
+	})
+
+	tests := []struct {
+		name          string
+		claimed       bool
+		sendErr       error
+		expectSend    bool
+		expectRelease bool
+	}{
+		{name: "catches up with one update", claimed: true, expectSend: true},
+		{name: "claimed by another run", claimed: false},
+		{name: "send failure puts the delivery back", claimed: true, sendErr: errors.New("smtp down"), expectSend: true, expectRelease: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			ts.subs.On("GetUnscheduled", mock.Anything).Return([]core.Subscription{}, nil).Once()
+			ts.subs.On("GetDue", mock.Anything, mock.Anything).Return([]core.Subscription{sub}, nil).Once()
+			ts.provider.On("FetchWeather", mock.Anything, mock.Anything).Return(&core.Weather{Temperature: 18, Description: "Sunny"}, nil).Once()
+			ts.subs.On("ClaimDue", mock.Anything, sub.ID, dueAt, nextSlot, mock.Anything).Return(tc.claimed, nil).Once()
+			if tc.expectSend {
+				ts.emailer.On("SendWeatherUpdateEmail", mock.Anything, sub.Email, "en", "Kyiv", mock.Anything, mock.Anything, mock.Anything).
+					Return(tc.sendErr).Once()
+			}
+			if tc.expectRelease {
+				ts.subs.On("ReleaseClaim", mock.Anything, sub.ID, dueAt, nextSlot, mock.Anything, &lastSentAt).Return(true, nil).Once()
+			}
+
+			ts.SendWeatherUpdates(context.Background())
+
+			ts.assertExpectations(t)
+			if !tc.expectSend {
+				ts.emailer.AssertNotCalled(t, "SendWeatherUpdateEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if !tc.expectRelease {
+				ts.subs.AssertNotCalled(t, "ReleaseClaim", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCheckAirQualityAlerts(t *testing.T) {
+	threshold := 150
+
+	tests := []struct {
+		name         string
+		alertActive  bool
+		index        int
+		sendErr      error
+		expectSend   bool
+		expectActive *bool
+	}{
+		{name: "crossing the threshold sends an alert", index: 160, expectSend: true, expectActive: boolPtr(true)},
+		{name: "reaching the threshold sends an alert", index: 150, expectSend: true, expectActive: boolPtr(true)},
+		{name: "staying above is not sent again", alertActive: true, index: 170},
+		{name: "dropping below re-arms the alert", alertActive: true, index: 80, expectActive: boolPtr(false)},
+		{name: "staying below sends nothing", index: 80},
+		{name: "send failure stays armed", index: 160, sendErr: errors.New("smtp down"), expectSend: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sub := core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv", Language: "en", IsConfirmed: true,
+				AQIThreshold: &threshold, AQIAlertActive: tc.alertActive, UnsubscribeToken: "unsub-1", ManageToken: testManageToken}
+			ts := newTestService()
+			ts.subs.On("GetConfirmedWithAQIThreshold", mock.Anything).Return([]core.Subscription{sub}, nil).Once()
+			ts.provider.On("FetchAirQuality", mock.Anything, core.CityQuery("Kyiv")).
+				Return(&core.AirQuality{USEPAIndex: tc.index, PM25: 55.5}, nil).Once()
+			if tc.expectSend {
+				alertInfo := mock.MatchedBy(func(info string) bool { return strings.Contains(info, strconv.Itoa(tc.index)) })
+				ts.emailer.On("SendAirQualityAlertEmail", mock.Anything, sub.Email, "en", "Kyiv", alertInfo,
+					"http://localhost:8080/manage.html?token="+testManageToken, "http://localhost:8080/api/unsubscribe/unsub-1").
+					Return(tc.sendErr).Once()
+			}
+			if tc.expectActive != nil {
+				ts.subs.On("SetAQIAlertActive", mock.Anything, sub.ID, *tc.expectActive).Return(nil).Once()
+			}
+
+			ts.CheckAirQualityAlerts(context.Background())
+
+			ts.assertExpectations(t)
+			if !tc.expectSend {
+				ts.emailer.AssertNotCalled(t, "SendAirQualityAlertEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tc.expectActive == nil {
+				ts.subs.AssertNotCalled(t, "SetAQIAlertActive", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCheckWeatherAlerts(t *testing.T) {
+	sub := core.Subscription{ID: "sub-1", Email: "test@example.com", City: "Kyiv", Frequency: "alerts", Language: "en",
+		IsConfirmed: true, UnsubscribeToken: "unsub-1", ManageToken: testManageToken}
+	storm := core.WeatherAlert{ID: "alert-1", Event: "Storm", Headline: "Storm warning", Severity: "Severe",
+		Expires: time.Now().UTC().Add(6 * time.Hour)}
+	updatedStorm := storm
+	updatedStorm.Severity = "Extreme"
+	expired := core.WeatherAlert{ID: "alert-2", Event: "Fog", Expires: time.Now().UTC().Add(-time.Hour)}
+
+	tests := []struct {
+		name           string
+		alerts         []core.WeatherAlert
+		notified       map[string]string
+		expectHeadline string
+	}{
+		{name: "new alert is sent", alerts: []core.WeatherAlert{storm}, notified: map[string]string{}, expectHeadline: "Storm warning"},
+		{name: "alert already sent is skipped", alerts: []core.WeatherAlert{storm}, notified: map[string]string{storm.ID: storm.Fingerprint()}},
+		{name: "updated alert is sent again", alerts: []core.WeatherAlert{updatedStorm}, notified: map[string]string{storm.ID: storm.Fingerprint()},
+			expectHeadline: "Updated: Storm warning"},
+		{name: "expired alert is skipped", alerts: []core.WeatherAlert{expired}, notified: map[string]string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			ts.subs.On("GetConfirmedByFrequency", mock.Anything, "alerts").Return([]core.Subscription{sub}, nil).Once()
+			ts.provider.On("FetchAlerts", mock.Anything, core.CityQuery("Kyiv")).Return(tc.alerts, nil).Once()
+			ts.alerts.On("GetNotifiedFingerprints", mock.Anything, sub.ID).Return(tc.notified, nil).Once()
+			ts.alerts.On("DeleteExpired", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+			if tc.expectHeadline != "" {
+				ts.emailer.On("SendWeatherAlertEmail", mock.Anything, sub.Email, "en", "Kyiv", tc.expectHeadline, mock.Anything,
+					"http://localhost:8080/manage.html?token="+testManageToken, "http://localhost:8080/api/unsubscribe/unsub-1").
+					Return(nil).Once()
+				ts.alerts.On("RecordNotification", mock.Anything, sub.ID, tc.alerts[0]).Return(nil).Once()
+			}
+
+			ts.CheckWeatherAlerts(context.Background())
+
+			ts.assertExpectations(t)
+			if tc.expectHeadline == "" {
+				ts.emailer.AssertNotCalled(t, "SendWeatherAlertEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				ts.alerts.AssertNotCalled(t, "RecordNotification", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	kyiv := core.Location{ID: "test:kyiv", Name: "Kyiv", Country: "Ukraine", Lat: 50.45, Lon: 30.52}
+
+	tests := []struct {
+		name             string
+		results          []core.Location
+		searchErr        error
+		expectWeather    bool
+		weatherErr       error
+		expectedTimezone string
+		expectedErr      error
+	}{
+		{name: "first match with its timezone", results: []core.Location{tokyo, kyiv}, expectedTimezone: "Asia/Tokyo"},
+		{name: "no match", results: []core.Location{}, expectedErr: ErrCityNotFound},
+		{name: "search error", searchErr: errors.New("upstream down"), expectedErr: ErrLocationUnavailable},
+		{name: "timezone from the current weather", results: []core.Location{kyiv}, expectWeather: true, expectedTimezone: "Europe/Kyiv"},
+		{name: "timezone lookup failure keeps the location", results: []core.Location{kyiv}, expectWeather: true, weatherErr: errors.New("upstream down")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestService()
+			ts.provider.On("SearchLocations", mock.Anything, "kyiv").Return(tc.results, tc.searchErr).Once()
+			if tc.expectWeather {
+				var weather interface{}
+				if tc.weatherErr == nil {
+					weather = &core.Weather{Location: core.Location{Name: "Kyiv", Timezone: "Europe/Kyiv"}}
+				}
+				ts.provider.On("FetchWeather", mock.Anything, core.CoordinatesQuery(kyiv.Lat, kyiv.Lon)).Return(weather, tc.weatherErr).Once()
+			}
+
+			location, err := ts.ResolveLocation(context.Background(), "kyiv")
+
+			ts.assertExpectations(t)
+			if !tc.expectWeather {
+				ts.provider.AssertNotCalled(t, "FetchWeather", mock.Anything, mock.Anything)
+			}
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, location)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.results[0].ID, location.ID)
+			assert.Equal(t, tc.expectedTimezone, location.Timezone)
+		})
+	}
+}
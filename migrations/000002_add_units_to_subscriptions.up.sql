@@ -0,0 +1 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS units VARCHAR(100) NOT NULL DEFAULT 'metric';
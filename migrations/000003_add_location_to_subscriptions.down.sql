@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_subscriptions_email_location;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS lon,
+    DROP COLUMN IF EXISTS lat,
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS location_id;
@@ -0,0 +1,10 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS location_id VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS country VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS lat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS lon DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Subscriptions created before location resolution keep location_id = '' and stay keyed by (email, city)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscriptions_email_location
+    ON subscriptions (email, location_id) WHERE location_id <> '';
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS aqi_alert_active,
+    DROP COLUMN IF EXISTS aqi_threshold;
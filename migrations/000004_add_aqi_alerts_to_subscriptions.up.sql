@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS aqi_threshold SMALLINT CHECK (aqi_threshold BETWEEN 1 AND 6),
+    ADD COLUMN IF NOT EXISTS aqi_alert_active BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS weather_alert_notifications;
+
+DELETE FROM subscriptions WHERE frequency = 'alerts';
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_frequency_check;
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_frequency_check CHECK (frequency IN ('hourly', 'daily'));
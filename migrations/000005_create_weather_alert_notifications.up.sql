@@ -0,0 +1,15 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_frequency_check;
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_frequency_check CHECK (frequency IN ('hourly', 'daily', 'alerts'));
+
+-- One row per alert already sent to a subscription; fingerprint detects updates to the same alert
+CREATE TABLE IF NOT EXISTS weather_alert_notifications (
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    alert_id VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ,
+    notified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (subscription_id, alert_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_weather_alert_notifications_expires_at ON weather_alert_notifications (expires_at);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS weather_observations (
+    id BIGSERIAL PRIMARY KEY,
+    location_key VARCHAR(100) NOT NULL, -- lower-cased location name
+    location_name VARCHAR(100) NOT NULL,
+    country VARCHAR(100) NOT NULL DEFAULT '',
+    lat DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lon DOUBLE PRECISION NOT NULL DEFAULT 0,
+    observed_at TIMESTAMPTZ NOT NULL,
+    temperature DOUBLE PRECISION NOT NULL,
+    feels_like DOUBLE PRECISION NOT NULL,
+    humidity DOUBLE PRECISION NOT NULL,
+    wind_speed DOUBLE PRECISION NOT NULL,
+    pressure DOUBLE PRECISION NOT NULL,
+    precipitation DOUBLE PRECISION NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The same observation is usually fetched many times (cache, API and scheduler); keep one copy
+CREATE UNIQUE INDEX IF NOT EXISTS idx_weather_observations_location_observed_at
+    ON weather_observations (location_key, observed_at);
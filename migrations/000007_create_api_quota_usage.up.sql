@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS api_quota_usage (
+    provider VARCHAR(50) NOT NULL,
+    month DATE NOT NULL, -- first day of the month, UTC
+    calls INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, month)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS include_astronomy BOOLEAN NOT NULL DEFAULT FALSE;
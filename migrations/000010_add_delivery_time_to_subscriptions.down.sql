@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS delivery_timezone,
+    DROP COLUMN IF EXISTS delivery_time;
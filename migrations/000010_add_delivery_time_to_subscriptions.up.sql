@@ -0,0 +1,4 @@
+-- existing subscriptions keep getting daily updates at 08:00 UTC; new ones default to the city's timezone
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS delivery_time VARCHAR(5) NOT NULL DEFAULT '08:00',
+    ADD COLUMN IF NOT EXISTS delivery_timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscriptions_next_due_at;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS last_sent_at,
+    DROP COLUMN IF EXISTS next_due_at;
@@ -0,0 +1,18 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS next_due_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS last_sent_at TIMESTAMPTZ NULL;
+
+-- confirmed subscriptions pick up from their next regular delivery
+UPDATE subscriptions
+SET next_due_at = date_trunc('hour', NOW()) + INTERVAL '1 hour'
+WHERE is_confirmed = TRUE AND frequency = 'hourly';
+
+UPDATE subscriptions
+SET next_due_at = CASE
+    WHEN (date_trunc('day', NOW() AT TIME ZONE delivery_timezone) + delivery_time::time) AT TIME ZONE delivery_timezone > NOW()
+        THEN (date_trunc('day', NOW() AT TIME ZONE delivery_timezone) + delivery_time::time) AT TIME ZONE delivery_timezone
+    ELSE (date_trunc('day', NOW() AT TIME ZONE delivery_timezone) + INTERVAL '1 day' + delivery_time::time) AT TIME ZONE delivery_timezone
+END
+WHERE is_confirmed = TRUE AND frequency = 'daily';
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_next_due_at ON subscriptions (next_due_at) WHERE is_confirmed = TRUE;
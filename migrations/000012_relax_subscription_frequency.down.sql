@@ -0,0 +1,8 @@
+-- the old constraint only knows these frequencies
+DELETE FROM subscriptions WHERE frequency NOT IN ('hourly', 'daily', 'alerts');
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_frequency_check;
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_frequency_check CHECK (frequency IN ('hourly', 'daily', 'alerts'));
+
+ALTER TABLE subscriptions ALTER COLUMN frequency TYPE VARCHAR(10);
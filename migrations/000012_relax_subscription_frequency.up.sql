@@ -0,0 +1,9 @@
+ALTER TABLE subscriptions ALTER COLUMN frequency TYPE VARCHAR(20);
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_frequency_check;
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_frequency_check CHECK (
+        frequency IN ('hourly', 'twice_daily', 'daily', 'weekdays', 'alerts')
+        OR frequency ~ '^every_(2|3|4|6|8|12)h$'
+        OR frequency ~ '^weekly_(monday|tuesday|wednesday|thursday|friday|saturday|sunday)$'
+    );
@@ -0,0 +1,14 @@
+-- Conditional notifications; expression is a core.RuleExpr tree of comparisons joined by all/any
+CREATE TABLE IF NOT EXISTS notification_rules (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    period VARCHAR(10) NOT NULL CHECK (period IN ('now', 'today', 'tomorrow')),
+    units VARCHAR(100) NOT NULL DEFAULT 'metric',
+    expression JSONB NOT NULL,
+    cooldown_hours INTEGER NOT NULL DEFAULT 24 CHECK (cooldown_hours BETWEEN 1 AND 168),
+    last_fired_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_rules_subscription_id ON notification_rules (subscription_id);
@@ -0,0 +1,38 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS email VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS units VARCHAR(100) NOT NULL DEFAULT 'metric',
+    ADD COLUMN IF NOT EXISTS language VARCHAR(8) NOT NULL DEFAULT 'en',
+    ADD COLUMN IF NOT EXISTS confirmation_token VARCHAR(36) UNIQUE,
+    ADD COLUMN IF NOT EXISTS is_confirmed BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE subscriptions s
+SET email = b.email, units = b.units, language = b.language,
+    is_confirmed = b.is_confirmed AND s.pending_confirmation_token IS NULL
+FROM subscribers b
+WHERE b.id = s.subscriber_id;
+
+-- a pending confirmation link keeps working for the subscriber's oldest subscription
+UPDATE subscriptions s
+SET confirmation_token = b.confirmation_token
+FROM subscribers b
+WHERE b.id = s.subscriber_id
+  AND b.confirmation_token IS NOT NULL
+  AND s.id = (SELECT id FROM subscriptions WHERE subscriber_id = b.id ORDER BY created_at LIMIT 1);
+
+-- pending cities get their own link back
+UPDATE subscriptions SET confirmation_token = pending_confirmation_token WHERE pending_confirmation_token IS NOT NULL;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS pending_confirmation_token;
+
+ALTER TABLE subscriptions ALTER COLUMN email SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_subscriptions_next_due_at;
+DROP INDEX IF EXISTS idx_subscriptions_subscriber_location;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_subscriber_city_key;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS subscriber_id;
+DROP TABLE IF EXISTS subscribers;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_email_city_key UNIQUE (email, city);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_confirmation_token ON subscriptions (confirmation_token);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscriptions_email_location
+    ON subscriptions (email, location_id) WHERE location_id <> '';
+CREATE INDEX IF NOT EXISTS idx_subscriptions_next_due_at ON subscriptions (next_due_at) WHERE is_confirmed = TRUE;
@@ -0,0 +1,62 @@
+-- One row per email address, owning its subscriptions and the preferences they share
+CREATE TABLE IF NOT EXISTS subscribers (
+    id UUID PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    units VARCHAR(100) NOT NULL DEFAULT 'metric',
+    language VARCHAR(8) NOT NULL DEFAULT 'en',
+    confirmation_token VARCHAR(36) UNIQUE,
+    is_confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- preferences come from the email's most recently changed subscription, a confirmed one if it has any
+INSERT INTO subscribers (id, email, units, language, created_at, updated_at)
+SELECT DISTINCT ON (email) gen_random_uuid(), email, units, language, created_at, updated_at
+FROM subscriptions
+ORDER BY email, is_confirmed DESC, updated_at DESC;
+
+-- an email confirmed for any city is confirmed; otherwise its newest confirmation link stays valid
+UPDATE subscribers b
+SET is_confirmed = agg.confirmed,
+    confirmation_token = CASE WHEN agg.confirmed THEN NULL ELSE agg.token END,
+    created_at = agg.created_at
+FROM (
+    SELECT email,
+           bool_or(is_confirmed) AS confirmed,
+           min(created_at) AS created_at,
+           (array_agg(confirmation_token ORDER BY created_at DESC) FILTER (WHERE confirmation_token IS NOT NULL))[1] AS token
+    FROM subscriptions
+    GROUP BY email
+) agg
+WHERE b.email = agg.email;
+
+-- cities a confirmed email asked for but never confirmed stay pending on their
+-- own link rather than being confirmed on the email's behalf
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS pending_confirmation_token VARCHAR(36) UNIQUE;
+UPDATE subscriptions s
+SET pending_confirmation_token = COALESCE(s.confirmation_token, gen_random_uuid()::text)
+FROM subscribers b
+WHERE b.email = s.email AND b.is_confirmed = TRUE AND s.is_confirmed = FALSE;
+
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS subscriber_id UUID REFERENCES subscribers (id) ON DELETE CASCADE;
+UPDATE subscriptions s SET subscriber_id = b.id FROM subscribers b WHERE b.email = s.email;
+ALTER TABLE subscriptions ALTER COLUMN subscriber_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_subscriptions_email_location;
+DROP INDEX IF EXISTS idx_subscriptions_confirmation_token;
+DROP INDEX IF EXISTS idx_subscriptions_next_due_at;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_email_city_key;
+-- delivery_timezone stays with each subscription: it defaults to the city's
+-- timezone, so every city is delivered at its own local time
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS units,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS confirmation_token,
+    DROP COLUMN IF EXISTS is_confirmed;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_subscriber_city_key UNIQUE (subscriber_id, city);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscriptions_subscriber_location
+    ON subscriptions (subscriber_id, location_id) WHERE location_id <> '';
+CREATE INDEX IF NOT EXISTS idx_subscriptions_next_due_at ON subscriptions (next_due_at);
@@ -0,0 +1,4 @@
+-- paused subscriptions resume on the scheduler's next run
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS manage_token,
+    DROP COLUMN IF EXISTS is_paused;
@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS manage_token VARCHAR(36) UNIQUE,
+    ADD COLUMN IF NOT EXISTS is_paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE subscriptions SET manage_token = gen_random_uuid()::text WHERE manage_token IS NULL;
+ALTER TABLE subscriptions ALTER COLUMN manage_token SET NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE subscribers
+    DROP COLUMN IF EXISTS confirmation_expires_at,
+    DROP COLUMN IF EXISTS confirmation_sent_at;
@@ -0,0 +1,8 @@
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS confirmation_expires_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS confirmation_sent_at TIMESTAMPTZ NULL;
+
+-- links sent before they could expire keep working for two more days
+UPDATE subscribers
+SET confirmation_expires_at = NOW() + INTERVAL '48 hours', confirmation_sent_at = created_at
+WHERE is_confirmed = FALSE AND confirmation_token IS NOT NULL;
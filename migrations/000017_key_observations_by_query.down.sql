@@ -0,0 +1,7 @@
+-- Only city keys map back to a name; observations recorded by coordinates,
+-- postcode or airport have no name to go back to and are dropped.
+DELETE FROM weather_observations WHERE location_key NOT LIKE 'city:%';
+
+UPDATE weather_observations SET location_key = SUBSTRING(location_key FROM 6);
+
+ALTER TABLE weather_observations ALTER COLUMN location_key TYPE VARCHAR(100);
@@ -0,0 +1,7 @@
+-- Observations are keyed by the normalized query they were fetched for
+-- ("city:kyiv", "coordinates:50.4500,30.5233") instead of the provider's name
+-- for the place. Rows recorded so far were looked up by city name, so they
+-- keep working for that name.
+ALTER TABLE weather_observations ALTER COLUMN location_key TYPE VARCHAR(150);
+
+UPDATE weather_observations SET location_key = 'city:' || location_key;